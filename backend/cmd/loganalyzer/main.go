@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/logs"
+)
+
+// Exit codes, so CI can tell a failed analysis from a breached threshold
+const (
+	exitOK            = 0
+	exitFailed        = 1
+	exitUsage         = 2
+	exitOverThreshold = 3
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	output := flag.String("output", "text", "output format: text or json")
+	failOver := flag.Float64("fail-over", 0, "exit with status 3 when the error rate exceeds this ratio (0 disables)")
+	levelMap := flag.String("level-map", "", "extra JSON log level mappings, e.g. verbose:DEBUG,notice:INFO")
+	timeLayout := flag.String("time-layout", "", "Go reference layout for timestamps, e.g. 2006-01-02T15:04:05 (default: auto-detect)")
+	levels := flag.String("levels", "", "comma-separated levels to analyze, e.g. WARN,ERROR; the totals then cover only these (default: all)")
+	continuationPattern := flag.String("continuation", "", "regexp for lines that continue the previous entry, such as stack trace frames, or none (default: common stack trace lines)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <log file>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "invalid --output %q (expected text or json)\n", *output)
+		return exitUsage
+	}
+	if *failOver < 0 || *failOver >= 1 {
+		fmt.Fprintln(os.Stderr, "--fail-over must be a ratio between 0 and 1")
+		return exitUsage
+	}
+	if *levels != "" && *failOver > 0 {
+		fmt.Fprintln(os.Stderr, "--fail-over cannot be combined with --levels, which changes the error rate")
+		return exitUsage
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return exitUsage
+	}
+
+	analyzer := logs.NewLogAnalyzer()
+	if *levelMap != "" {
+		mapping, err := logs.ParseLevelMapping(*levelMap)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		analyzer = analyzer.WithLevelMapping(mapping)
+	}
+	if *timeLayout != "" {
+		if err := logs.ValidateTimeLayout(*timeLayout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		analyzer = analyzer.WithTimeLayout(*timeLayout)
+	}
+	if *continuationPattern != "" {
+		continuation, err := logs.ParseContinuationPattern(*continuationPattern)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		analyzer = analyzer.WithContinuationPattern(continuation)
+	}
+	if *levels != "" {
+		selected, err := logs.ParseLevels(*levels)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		analyzer = analyzer.WithLevels(selected)
+	}
+
+	var stats *logs.LogStats
+	var err error
+	if flag.NArg() == 1 {
+		stats, err = analyzer.ParseLogFile(flag.Arg(0))
+	} else {
+		stats, err = analyzer.ParseLogFiles(flag.Args())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Log analysis failed: %v\n", err)
+		return exitFailed
+	}
+
+	if *output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(stats); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write results: %v\n", err)
+			return exitFailed
+		}
+	} else {
+		analyzer.PrintStats(stats)
+	}
+
+	if *failOver > 0 && stats.ErrorRate() > *failOver {
+		fmt.Fprintf(os.Stderr, "Error rate %.4f exceeds --fail-over %.4f\n", stats.ErrorRate(), *failOver)
+		return exitOverThreshold
+	}
+	return exitOK
+}
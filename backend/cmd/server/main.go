@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,21 +15,93 @@ import (
 
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/alerts"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/api"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/audit"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/auth"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/idempotency"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/logging"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/logs"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/notify"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/storage"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/utils"
 )
 
 func main() {
+	// Gin's mode must be read before it is switched to release mode below
+	debug := gin.Mode() == gin.DebugMode
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	logFormat, err := logging.ParseFormat(cfg.Logging.Format, debug)
+	if err != nil {
+		log.Fatalf("Invalid LOG_FORMAT: %v", err)
+	}
+	logColor, err := logging.ParseColorMode(cfg.Logging.Color)
+	if err != nil {
+		log.Fatalf("Invalid LOG_COLOR: %v", err)
+	}
+	logging.Setup(os.Stderr, logFormat, logColor)
+
+	// Validate alert message templates before starting anything
+	messageTemplates, err := alerts.ParseMessageTemplates(cfg.Alerts.MessageTemplate, cfg.Alerts.MessageTemplates)
+	if err != nil {
+		log.Fatalf("Invalid alert message template: %v", err)
+	}
+	ingestMode, err := metrics.ParseIngestMode(cfg.Metrics.IngestMode)
+	if err != nil {
+		log.Fatalf("Invalid METRICS_INGEST_MODE: %v", err)
+	}
+	outOfRange, err := metrics.ParseOutOfRangePolicy(cfg.Metrics.OutOfRange)
+	if err != nil {
+		log.Fatalf("Invalid METRICS_OUT_OF_RANGE: %v", err)
+	}
+	metricLabels, err := metrics.ParseLabels(cfg.Metrics.Labels)
+	if err != nil {
+		log.Fatalf("Invalid METRICS_LABELS: %v", err)
+	}
+	cgroupMode, err := metrics.ParseCgroupMode(cfg.Metrics.CgroupMode)
+	if err != nil {
+		log.Fatalf("Invalid METRICS_CGROUP_MODE: %v", err)
+	}
+	quietHours, err := notify.ParseQuietHours(cfg.Notify.QuietHours, cfg.Notify.QuietHoursTimezone, cfg.Notify.QuietMinSeverity)
+	if err != nil {
+		log.Fatalf("Invalid NOTIFY_QUIET_HOURS: %v", err)
+	}
+	fallbackSeverity := cfg.Notify.FallbackMinSeverity
+	if fallbackSeverity != "" {
+		if fallbackSeverity, err = notify.ParseSeverity(fallbackSeverity); err != nil {
+			log.Fatalf("Invalid NOTIFY_FALLBACK_MIN_SEVERITY: %v", err)
+		}
+	}
+	routing, err := notify.ParseRoutingTable(cfg.Notify.Routes)
+	if err != nil {
+		log.Fatalf("Invalid NOTIFY_ROUTES: %v", err)
+	}
+	queueOverflow, err := notify.ParseOverflowPolicy(cfg.Notify.QueueOverflow)
+	if err != nil {
+		log.Fatalf("Invalid NOTIFY_QUEUE_OVERFLOW: %v", err)
+	}
+	// Configured metric types must be registered before type names are checked
+	typeMetadata, err := metrics.ParseTypeMetadata(cfg.Metrics.TypeMetadata)
+	if err != nil {
+		log.Fatalf("Invalid METRIC_TYPE_ metadata: %v", err)
+	}
+	if err := metrics.ApplyTypeMetadata(typeMetadata); err != nil {
+		log.Fatalf("Invalid METRIC_TYPE_ metadata: %v", err)
+	}
+	var stalenessTypes []metrics.MetricType
+	for _, name := range cfg.Alerts.StalenessTypes {
+		if _, ok := metrics.LookupType(metrics.MetricType(name)); !ok {
+			log.Fatalf("Invalid ALERT_STALENESS_TYPES: unknown metric type %q", name)
+		}
+		stalenessTypes = append(stalenessTypes, metrics.MetricType(name))
+	}
+
 	// Initialize JWT utilities with config
 	utils.InitConfig(cfg)
 
@@ -45,9 +119,92 @@ func main() {
 
 	// Initialize services
 	authService := auth.NewService(db.GetDB())
-	logAnalyzer := logs.NewLogAnalyzer()
+	authService.SetPasswordPolicy(auth.PasswordPolicy{
+		MinLength:     cfg.Auth.PasswordMinLength,
+		RequireUpper:  cfg.Auth.PasswordRequireUpper,
+		RequireLower:  cfg.Auth.PasswordRequireLower,
+		RequireDigit:  cfg.Auth.PasswordRequireDigit,
+		RequireSymbol: cfg.Auth.PasswordRequireSymbol,
+	})
+	if err := authService.SetBcryptCost(cfg.Auth.BcryptCost); err != nil {
+		log.Fatalf("Invalid bcrypt cost: %v", err)
+	}
+	if err := authService.SetAdminUsernames(cfg.Auth.AdminUsernames); err != nil {
+		log.Fatalf("Failed to set up admin accounts: %v", err)
+	}
+	emailNotifier := notify.NewEmailNotifier(cfg.SMTP)
+	if emailNotifier.Enabled() {
+		authService.SetMailer(emailNotifier)
+	}
+	authService.SetVerificationConfig(auth.VerificationConfig{
+		Required: cfg.Auth.RequireEmailVerification,
+		TokenTTL: cfg.Auth.VerificationTokenTTL,
+		BaseURL:  cfg.Server.PublicURL,
+	})
+	levelMapping, err := logs.ParseLevelMapping(cfg.Logs.LevelMapping)
+	if err != nil {
+		log.Fatalf("Invalid LOG_LEVEL_MAPPING: %v", err)
+	}
+	continuation, err := logs.ParseContinuationPattern(cfg.Logs.ContinuationPattern)
+	if err != nil {
+		log.Fatalf("Invalid LOG_CONTINUATION_PATTERN: %v", err)
+	}
+	logAnalyzer := logs.NewLogAnalyzer().WithLevelMapping(levelMapping).WithContinuationPattern(continuation)
 	metricsCollector := metrics.NewCollector(db.GetDB(), cfg.Metrics.CollectionInterval)
+	if err := metricsCollector.SetCollectionJitter(cfg.Metrics.CollectionJitter); err != nil {
+		log.Fatalf("Invalid METRICS_COLLECTION_JITTER: %v", err)
+	}
+	metricsCollector.SetStartDelay(cfg.Metrics.StartDelay)
+	metricsCollector.SetCPUSampleInterval(cfg.Metrics.CPUSampleInterval)
+	if cfg.Metrics.CPUModes {
+		metricsCollector.EnableCPUModes()
+	}
+	metricsCollector.SetCgroupMode(cgroupMode)
+	metricsCollector.SetSourceTimeout(cfg.Metrics.CollectorTimeout)
+	metricsCollector.SetRecentBufferSize(cfg.Metrics.RecentBufferSize)
+	metricsCollector.SetDiskMounts(cfg.Metrics.DiskMounts)
+	metricsCollector.SetNetworkInterfaces(cfg.Metrics.NetworkInterfaces, cfg.Metrics.NetworkIncludeLoopback)
+	if cfg.Metrics.PersistCounters {
+		metricsCollector.SetCounterPersistence(cfg.Metrics.CounterMaxGap)
+	}
+	metricsCollector.SetIngestPolicy(ingestMode, cfg.Metrics.IngestMaxBatch)
+	metricsCollector.SetOutOfRangePolicy(outOfRange)
+	metricsCollector.SetLabels(metricLabels)
+	metricsCollector.SetRetentionPolicy(metrics.RetentionPolicy{
+		RawRetention:    cfg.Metrics.RawRetention,
+		HourlyRetention: cfg.Metrics.HourlyRetention,
+	})
 	alertService := alerts.NewService(db.GetDB())
+	alertService.SetMessageTemplates(messageTemplates)
+	alertService.SetMountThresholds(cfg.Metrics.DiskThresholds)
+	alertService.SetComparisonEpsilon(cfg.Alerts.ComparisonEpsilon)
+	alertService.SetIncidentWindow(cfg.Alerts.IncidentWindow)
+	alertService.SetWarmup(cfg.Alerts.WarmupPeriod)
+	alertService.SetFlapDetection(cfg.Alerts.FlapWindow, cfg.Alerts.FlapTransitions)
+	alertService.SetRealertCooldown(cfg.Alerts.RealertCooldown)
+	alertService.SetMaxAlertLimit(cfg.Alerts.MaxQueryLimit)
+	alertService.SetCollectionPaused(metricsCollector.Paused)
+	notifier := notify.NewDispatcher(notify.ChannelsFromConfig(cfg.Notify, emailNotifier)...)
+	notifier.SetQuietHours(quietHours)
+	notifier.SetSubscribers(alertService, emailNotifier)
+	notifier.SetFallbackMinSeverity(fallbackSeverity)
+	if err := notifier.SetRouting(routing); err != nil {
+		log.Fatalf("Invalid NOTIFY_ROUTES: %v", err)
+	}
+	notifier.SetWorkerPool(cfg.Notify.Workers, cfg.Notify.QueueSize, queueOverflow)
+	alertService.SetNotifier(notifier)
+	if channels := notifier.Channels(); len(channels) > 0 {
+		log.Printf("Alert notifications enabled: %v", channels)
+	}
+	if quietHours != nil {
+		log.Printf("Notification quiet hours enabled: %s", cfg.Notify.QuietHours)
+	}
+	if routing != nil {
+		log.Printf("Notification routing enabled: %s", cfg.Notify.Routes)
+	}
+	if cfg.Alerts.WarmupPeriod > 0 {
+		log.Printf("Threshold alerts held for a %v startup warmup", cfg.Alerts.WarmupPeriod)
+	}
 
 	// Initialize metric thresholds
 	if err := metricsCollector.InitializeThresholds(); err != nil {
@@ -55,7 +212,23 @@ func main() {
 	}
 
 	// Initialize API handlers
-	handlers := api.NewHandlers(authService, logAnalyzer, metricsCollector, alertService)
+	auditService := audit.NewService(db.GetDB())
+	handlers := api.NewHandlers(authService, logAnalyzer, metricsCollector, alertService, auditService)
+	handlers.SetAllowAdminReset(cfg.Server.AllowAdminReset)
+	idempotencyStore := idempotency.NewStore(db.GetDB(), cfg.Server.IdempotencyKeyTTL)
+	handlers.SetIdempotencyStore(idempotencyStore)
+	handlers.SetSummaryCacheTTL(cfg.Server.SummaryCacheTTL)
+	handlers.SetMaxStreamClients(cfg.Server.MaxStreamClients)
+	handlers.SetNotifier(notifier)
+	if cfg.Server.AllowAdminReset {
+		slog.Warn("Admin reset endpoint enabled (ALLOW_ADMIN_RESET=true)")
+	}
+	handlers.SetIntegrityChecker(db)
+	handlers.SetDiagnosticsConfig(cfg)
+	if cfg.Server.AllowAdminMigrate {
+		handlers.SetMigrator(db)
+		log.Println("Admin migrate endpoint enabled (ALLOW_ADMIN_MIGRATE=true)")
+	}
 
 	// Setup Gin router
 	if gin.Mode() == gin.DebugMode {
@@ -63,39 +236,92 @@ func main() {
 	}
 
 	router := gin.New()
-	api.SetupRoutes(router, handlers, authService)
+	// Forwarded client IP headers are only believed from trusted proxies;
+	// TRUSTED_PROXIES=none uses the connecting address
+	trustedProxies := cfg.Server.TrustedProxies
+	if len(trustedProxies) == 1 && strings.EqualFold(trustedProxies[0], "none") {
+		trustedProxies = nil
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	api.SetupRoutes(router, handlers, authService, api.SecurityOptions{
+		AllowedOrigins:        cfg.Server.CORSAllowedOrigins,
+		ContentSecurityPolicy: cfg.Server.ContentSecurityPolicy,
+		HSTS:                  cfg.Server.HSTS,
+		MaxBodyBytes:          cfg.Server.MaxBodyBytes,
+		MaxIngestBodyBytes:    cfg.Server.MaxIngestBodyBytes,
+	})
+	if cfg.Server.EnablePprof {
+		api.SetupPprofRoutes(router, authService)
+		slog.Warn("pprof enabled at /debug/pprof (admin only)")
+	}
 
 	// Start metrics collection in background
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Check alert thresholds either after each collection cycle or on their
+	// own timer. The hook must be registered before collection starts.
+	checkThresholds := func(currentMetrics *metrics.SystemMetrics) {
+		if err := alertService.CheckThresholds(currentMetrics); err != nil {
+			log.Printf("Failed to check alert thresholds: %v", err)
+		}
+	}
+	if cfg.Metrics.AlertCheckOnCollect {
+		metricsCollector.SetCollectHook(checkThresholds)
+	}
+
 	go func() {
 		log.Println("Starting metrics collection...")
 		metricsCollector.Start(ctx)
 	}()
 
+	// Compact old metrics into rollups (no-op unless METRICS_RAW_RETENTION is set)
+	go metricsCollector.StartCompaction(ctx, cfg.Metrics.CompactionInterval)
+
+	// Permanently delete soft-deleted users (no-op unless USER_PURGE_AFTER_DAYS is set)
+	go authService.StartUserPurge(ctx, time.Duration(cfg.Auth.UserPurgeAfterDays)*24*time.Hour)
+
+	// Look for invalid rows (no-op if INTEGRITY_CHECK_INTERVAL is negative)
+	go db.StartIntegrityCheck(ctx, cfg.Database.IntegrityCheckInterval, cfg.Database.IntegrityCheckFix)
+
+	// Forget idempotency keys once their window has passed
+	go idempotencyStore.StartPurge(ctx)
+
+	// Delete old resolved alerts (no-op if ALERT_RETENTION_PERIOD is negative)
+	go alertService.StartAlertRetention(ctx, cfg.Alerts.RetentionPeriod)
+
+	// Alert when collection stalls (no-op if ALERT_STALENESS_WINDOW is negative)
+	go alertService.StartStalenessWatch(ctx, stalenessTypes, cfg.Alerts.StalenessWindow)
+
 	// Start alert monitoring
-	go func() {
-		ticker := time.NewTicker(30 * time.Second) // Check every 30 seconds
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				currentMetrics, err := metricsCollector.GetCurrentMetrics()
-				if err != nil {
-					log.Printf("Failed to get current metrics for alert checking: %v", err)
-					continue
-				}
+	if cfg.Metrics.AlertCheckOnCollect {
+		log.Println("Checking alert thresholds after each collection cycle")
+	} else {
+		go func() {
+			ticker := time.NewTicker(cfg.Metrics.AlertCheckInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if metricsCollector.Paused() {
+						continue
+					}
+					currentMetrics, err := metricsCollector.GetCurrentMetrics()
+					if err != nil {
+						log.Printf("Failed to get current metrics for alert checking: %v", err)
+						continue
+					}
 
-				if err := alertService.CheckThresholds(currentMetrics); err != nil {
-					log.Printf("Failed to check alert thresholds: %v", err)
+					checkThresholds(currentMetrics)
 				}
 			}
-		}
-	}()
+		}()
+	}
 
 	// Setup HTTP server
 	server := &http.Server{
@@ -107,11 +333,13 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("🚀 CodeXray Observability Service starting on port %s", cfg.Server.Port)
-		log.Printf("📊 Metrics collection interval: %v", cfg.Metrics.CollectionInterval)
-		log.Printf("🔥 CPU threshold: %.1f%%", cfg.Metrics.CPUThreshold)
-		log.Printf("💾 Memory threshold: %.1f%%", cfg.Metrics.MemoryThreshold)
-		log.Printf("📁 Database: %s", cfg.GetDatabaseDSN())
+		slog.Info("CodeXray Observability Service starting",
+			"port", cfg.Server.Port,
+			"collection_interval", cfg.Metrics.CollectionInterval,
+			"cpu_threshold", cfg.Metrics.CPUThreshold,
+			"memory_threshold", cfg.Metrics.MemoryThreshold,
+			"database", cfg.Redacted().GetDatabaseDSN(),
+		)
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
@@ -123,7 +351,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("🛑 Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Cancel background processes
 	cancel()
@@ -136,5 +364,9 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
-	log.Println("✅ Server exited")
+	// Flush in-flight notifications within the same timeout
+	drained, dropped := notifier.Drain(ctx)
+	log.Printf("Notifications drained: %d, dropped: %d", drained, dropped)
+
+	slog.Info("Server exited")
 }
@@ -0,0 +1,51 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+)
+
+// Breach is a metric currently past its threshold
+type Breach struct {
+	Type      metrics.MetricType         `json:"type"`
+	Mount     string                     `json:"mount,omitempty"`
+	Value     float64                    `json:"value"`
+	Threshold float64                    `json:"threshold"`
+	Direction metrics.ThresholdDirection `json:"direction"`
+	Severity  AlertSeverity              `json:"severity"`
+}
+
+// CurrentBreaches compares currentMetrics against the enabled thresholds the
+// same way CheckThresholds does, without creating or resolving alerts
+func (s *Service) CurrentBreaches(currentMetrics *metrics.SystemMetrics) ([]Breach, error) {
+	var thresholds []metrics.MetricThreshold
+	if err := s.db.Where("enabled = ?", true).Find(&thresholds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get thresholds: %w", err)
+	}
+
+	breaches := make([]Breach, 0)
+	for _, check := range s.thresholdChecks(thresholds, currentMetrics) {
+		if !s.breached(check.direction, check.value, check.threshold) {
+			continue
+		}
+		breaches = append(breaches, Breach{
+			Type:      check.metricType,
+			Mount:     check.mount,
+			Value:     check.value,
+			Threshold: check.threshold,
+			Direction: check.direction,
+			Severity:  s.calculateSeverity(check.value, check.threshold, check.direction),
+		})
+	}
+
+	sort.Slice(breaches, func(i, j int) bool {
+		if breaches[i].Type != breaches[j].Type {
+			return breaches[i].Type < breaches[j].Type
+		}
+		return breaches[i].Mount < breaches[j].Mount
+	})
+
+	return breaches, nil
+}
@@ -0,0 +1,38 @@
+package alerts
+
+import (
+	"log"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+)
+
+// SetRealertCooldown holds back a new threshold alert for cooldown after the
+// last alert for the same metric type and mount resolved, so a value
+// oscillating around its threshold does not raise an alert every other
+// check. A non-positive cooldown disables it.
+func (s *Service) SetRealertCooldown(cooldown time.Duration) {
+	if cooldown < 0 {
+		cooldown = 0
+	}
+	s.realertCooldown = cooldown
+}
+
+// inRealertCooldown reports whether an alert for metricType and mount
+// resolved within the cooldown before at
+func (s *Service) inRealertCooldown(metricType metrics.MetricType, mount string, at time.Time) bool {
+	if s.realertCooldown <= 0 {
+		return false
+	}
+
+	var count int64
+	err := s.db.Model(&Alert{}).
+		Where("metric_type = ? AND mount = ? AND status = ? AND resolved_at > ?", metricType, mount, AlertResolved, at.Add(-s.realertCooldown)).
+		Count(&count).Error
+	if err != nil {
+		// Alerting matters more than the cooldown
+		log.Printf("Failed to check re-alert cooldown for %s%s: %v", metricType, mountSuffix(mount), err)
+		return false
+	}
+	return count > 0
+}
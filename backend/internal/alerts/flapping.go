@@ -0,0 +1,132 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"gorm.io/gorm"
+)
+
+// FlappingAlertType is the alert type raised when a metric type's alert
+// keeps triggering and resolving; the affected type is recorded in the
+// alert's FlapType and Mount
+const FlappingAlertType metrics.MetricType = "alert_flapping"
+
+// SetFlapDetection raises a flapping alert when a metric type's alert
+// triggers or resolves more than maxTransitions times within window. A
+// non-positive window or count disables detection.
+func (s *Service) SetFlapDetection(window time.Duration, maxTransitions int) {
+	if window <= 0 || maxTransitions <= 0 {
+		s.flapWindow, s.flapMaxTransitions = 0, 0
+		return
+	}
+	s.flapWindow, s.flapMaxTransitions = window, maxTransitions
+}
+
+// countTransitions counts how many times alerts for metricType and mount
+// triggered or resolved within the flap window before now. The alerts table
+// is the transition history: every alert contributes its trigger and, once
+// resolved, its resolution.
+func (s *Service) countTransitions(metricType metrics.MetricType, mount string, now time.Time) (int64, error) {
+	since := now.Add(-s.flapWindow)
+	alerts := s.db.Model(&Alert{}).
+		Where("metric_type = ? AND mount = ? AND status IN ?", metricType, mount, []AlertStatus{AlertActive, AlertResolved})
+
+	var triggered, resolved int64
+	if err := alerts.Session(&gorm.Session{}).Where("triggered_at >= ?", since).Count(&triggered).Error; err != nil {
+		return 0, fmt.Errorf("failed to count alert transitions: %w", err)
+	}
+	if err := alerts.Session(&gorm.Session{}).Where("resolved_at >= ?", since).Count(&resolved).Error; err != nil {
+		return 0, fmt.Errorf("failed to count alert transitions: %w", err)
+	}
+	return triggered + resolved, nil
+}
+
+// checkFlapping raises a flapping alert for metricType and mount if their
+// alert has changed state too often within the flap window
+func (s *Service) checkFlapping(metricType metrics.MetricType, mount string, threshold float64, now time.Time) {
+	if s.flapWindow <= 0 {
+		return
+	}
+
+	transitions, err := s.countTransitions(metricType, mount, now)
+	if err != nil {
+		log.Printf("Failed to check %s%s for flapping: %v", metricType, mountSuffix(mount), err)
+		return
+	}
+	if transitions <= int64(s.flapMaxTransitions) {
+		return
+	}
+
+	var existing Alert
+	err = s.db.Where("metric_type = ? AND flap_type = ? AND mount = ? AND status = ?", FlappingAlertType, metricType, mount, AlertActive).
+		First(&existing).Error
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Failed to check flapping alerts: %v", err)
+		return
+	}
+
+	rate := float64(transitions) / s.flapWindow.Hours()
+	alert := Alert{
+		Type:      FlappingAlertType,
+		FlapType:  metricType,
+		Mount:     mount,
+		Value:     float64(transitions),
+		Threshold: float64(s.flapMaxTransitions),
+		Direction: metrics.DirectionAbove,
+		Severity:  SeverityMedium,
+		Message: fmt.Sprintf("%s%s alert is flapping: %d state changes in %s (%.1f per hour); threshold %s may be too close to normal values",
+			metricType, mountSuffix(mount), transitions, s.flapWindow, rate, metrics.FormatValue(metricType, threshold)),
+		Status:      AlertActive,
+		TriggeredAt: now,
+	}
+
+	if err := s.db.Create(&alert).Error; err != nil {
+		log.Printf("Failed to create flapping alert: %v", err)
+		return
+	}
+	s.markChanged()
+
+	log.Printf("Alert created: %s%s flapping, %d state changes in %s", metricType, mountSuffix(mount), transitions, s.flapWindow)
+	s.notifyTriggered(&alert)
+}
+
+// resolveSettledFlapping resolves flapping alerts whose metric type has
+// changed state no more than the allowed number of times within the window
+func (s *Service) resolveSettledFlapping(now time.Time) {
+	if s.flapWindow <= 0 {
+		return
+	}
+
+	var flapping []Alert
+	if err := s.db.Where("metric_type = ? AND status = ?", FlappingAlertType, AlertActive).Find(&flapping).Error; err != nil {
+		log.Printf("Failed to get flapping alerts: %v", err)
+		return
+	}
+
+	for _, alert := range flapping {
+		transitions, err := s.countTransitions(alert.FlapType, alert.Mount, now)
+		if err != nil {
+			log.Printf("Failed to check %s%s for flapping: %v", alert.FlapType, mountSuffix(alert.Mount), err)
+			continue
+		}
+		if transitions > int64(s.flapMaxTransitions) {
+			continue
+		}
+
+		resolved, err := resolveAlerts(s.db, now, "id = ?", alert.ID)
+		if err != nil {
+			log.Printf("Failed to resolve flapping alert %d: %v", alert.ID, err)
+		} else if len(resolved) > 0 {
+			s.markChanged()
+			log.Printf("%s%s alert has settled; flapping alert resolved", alert.FlapType, mountSuffix(alert.Mount))
+			s.notifyResolved(resolved)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultIncidentWindow is how close together alerts must fire to share an incident
+const DefaultIncidentWindow = 5 * time.Minute
+
+// IncidentStatus represents the status of an incident
+type IncidentStatus string
+
+const (
+	IncidentOpen   IncidentStatus = "open"
+	IncidentClosed IncidentStatus = "closed"
+)
+
+// Incident groups related alerts that fired close together, so that for
+// example CPU and memory alerts from one overload page once
+type Incident struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	// Severity is the highest severity among the incident's alerts
+	Severity    AlertSeverity  `json:"severity" gorm:"not null"`
+	Status      IncidentStatus `json:"status" gorm:"not null;index"`
+	OpenedAt    time.Time      `json:"opened_at" gorm:"not null"`
+	LastAlertAt time.Time      `json:"last_alert_at" gorm:"not null"`
+	ClosedAt    *time.Time     `json:"closed_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+
+	Alerts []Alert `json:"alerts" gorm:"foreignKey:IncidentID"`
+}
+
+// severityRank orders severities from least to most severe
+var severityRank = map[AlertSeverity]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// SetIncidentWindow sets how soon after an incident's latest alert a new
+// alert still joins it rather than opening a new incident
+func (s *Service) SetIncidentWindow(window time.Duration) {
+	if window <= 0 {
+		window = DefaultIncidentWindow
+	}
+	s.incidentWindow = window
+}
+
+// attachToIncident adds alert to the open incident whose latest alert fired
+// within the incident window, or opens a new incident for it. The incident
+// takes on the alert's severity if it is higher.
+func (s *Service) attachToIncident(tx *gorm.DB, alert *Alert) error {
+	var incident Incident
+	err := tx.Where("status = ? AND last_alert_at >= ?", IncidentOpen, alert.TriggeredAt.Add(-s.incidentWindow)).
+		Order("last_alert_at DESC").
+		First(&incident).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		incident = Incident{
+			Severity:    alert.Severity,
+			Status:      IncidentOpen,
+			OpenedAt:    alert.TriggeredAt,
+			LastAlertAt: alert.TriggeredAt,
+		}
+		if err := tx.Create(&incident).Error; err != nil {
+			return fmt.Errorf("failed to open incident: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to find open incident: %w", err)
+	default:
+		updates := map[string]interface{}{"last_alert_at": alert.TriggeredAt}
+		if severityRank[alert.Severity] > severityRank[incident.Severity] {
+			updates["severity"] = alert.Severity
+		}
+		if err := tx.Model(&incident).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update incident: %w", err)
+		}
+	}
+
+	alert.IncidentID = &incident.ID
+	return tx.Model(alert).Update("incident_id", incident.ID).Error
+}
+
+// closeResolvedIncidents closes open incidents that no longer have an active
+// alert, as of now
+func closeResolvedIncidents(db *gorm.DB, now time.Time) error {
+	result := db.Model(&Incident{}).
+		Where("status = ?", IncidentOpen).
+		Where("NOT EXISTS (SELECT 1 FROM alerts WHERE alerts.incident_id = incidents.id AND alerts.status = ?)", AlertActive).
+		Updates(map[string]interface{}{
+			"status":    IncidentClosed,
+			"closed_at": &now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to close incidents: %w", result.Error)
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Closed %d incidents with no active alerts", result.RowsAffected)
+	}
+	return nil
+}
+
+// GetIncidents returns incidents newest first with their member alerts
+func (s *Service) GetIncidents(status IncidentStatus, limit int) ([]Incident, error) {
+	var incidents []Incident
+
+	query := s.db.Order("opened_at DESC").
+		Preload("Alerts", func(db *gorm.DB) *gorm.DB {
+			return db.Order("triggered_at ASC")
+		})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&incidents).Error; err != nil {
+		return nil, fmt.Errorf("failed to get incidents: %w", err)
+	}
+
+	now := s.clock.Now()
+	for i := range incidents {
+		for j := range incidents[i].Alerts {
+			incidents[i].Alerts[j].populateDuration(now)
+		}
+	}
+
+	return incidents, nil
+}
@@ -0,0 +1,160 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"gorm.io/gorm"
+)
+
+// ErrMaintenanceNotFound is returned when a maintenance window does not exist or is already over
+var ErrMaintenanceNotFound = errors.New("maintenance window not found or already ended")
+
+// MaintenanceWindow suppresses all alerting between StartsAt and EndsAt
+type MaintenanceWindow struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	StartsAt    time.Time  `json:"starts_at" gorm:"not null;index"`
+	EndsAt      time.Time  `json:"ends_at" gorm:"not null;index"`
+	Reason      string     `json:"reason" gorm:"not null"`
+	CreatedBy   uint       `json:"created_by"`
+	Cancelled   bool       `json:"cancelled" gorm:"not null;default:false"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ScheduleMaintenanceRequest represents a request to schedule a maintenance window
+type ScheduleMaintenanceRequest struct {
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   time.Time  `json:"ends_at" binding:"required"`
+	Reason   string     `json:"reason" binding:"required"`
+}
+
+// ScheduleMaintenance creates a maintenance window, starting now if no start is given
+func (s *Service) ScheduleMaintenance(req *ScheduleMaintenanceRequest, createdBy uint) (*MaintenanceWindow, error) {
+	now := s.clock.Now()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+
+	if !req.EndsAt.After(startsAt) {
+		return nil, fmt.Errorf("ends_at must be after starts_at")
+	}
+	if !req.EndsAt.After(now) {
+		return nil, fmt.Errorf("ends_at must be in the future")
+	}
+
+	window := MaintenanceWindow{
+		StartsAt:  startsAt,
+		EndsAt:    req.EndsAt,
+		Reason:    req.Reason,
+		CreatedBy: createdBy,
+	}
+
+	if err := s.db.Create(&window).Error; err != nil {
+		return nil, fmt.Errorf("failed to schedule maintenance window: %w", err)
+	}
+	s.markChanged()
+
+	log.Printf("Maintenance window scheduled: %s to %s (%s)",
+		window.StartsAt.Format(time.RFC3339), window.EndsAt.Format(time.RFC3339), window.Reason)
+
+	return &window, nil
+}
+
+// ListMaintenanceWindows returns maintenance windows, newest first. Unless
+// includePast is set, only current and upcoming windows are returned.
+func (s *Service) ListMaintenanceWindows(includePast bool) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+
+	query := s.db.Order("starts_at DESC")
+	if !includePast {
+		query = query.Where("ends_at > ? AND cancelled = ?", s.clock.Now(), false)
+	}
+
+	if err := query.Find(&windows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list maintenance windows: %w", err)
+	}
+
+	return windows, nil
+}
+
+// CancelMaintenance cancels a current or upcoming maintenance window
+func (s *Service) CancelMaintenance(windowID uint) error {
+	now := s.clock.Now()
+	result := s.db.Model(&MaintenanceWindow{}).
+		Where("id = ? AND cancelled = ? AND ends_at > ?", windowID, false, now).
+		Updates(map[string]interface{}{
+			"cancelled":    true,
+			"cancelled_at": &now,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to cancel maintenance window: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrMaintenanceNotFound
+	}
+	s.markChanged()
+
+	return nil
+}
+
+// ActiveMaintenanceWindow returns the window covering now, or nil if none is active
+func (s *Service) ActiveMaintenanceWindow(now time.Time) (*MaintenanceWindow, error) {
+	var window MaintenanceWindow
+	err := s.db.Where("starts_at <= ? AND ends_at > ? AND cancelled = ?", now, now, false).
+		Order("ends_at DESC").
+		First(&window).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check maintenance windows: %w", err)
+	}
+
+	return &window, nil
+}
+
+// recordSuppressedBreach stores a breach that occurred during maintenance so it
+// can be reviewed later. At most one suppressed alert is kept per metric type
+// and mount per window.
+func (s *Service) recordSuppressedBreach(window *MaintenanceWindow, metricType metrics.MetricType, mount string, value, threshold float64, direction metrics.ThresholdDirection, at time.Time) {
+	var count int64
+	if err := s.db.Model(&Alert{}).
+		Where("metric_type = ? AND mount = ? AND status = ? AND triggered_at >= ?", metricType, mount, AlertSuppressed, window.StartsAt).
+		Count(&count).Error; err != nil {
+		log.Printf("Failed to check suppressed alerts: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	alert := Alert{
+		Type:        metricType,
+		Mount:       mount,
+		Value:       value,
+		Threshold:   threshold,
+		Direction:   direction,
+		Severity:    s.calculateSeverity(value, threshold, direction),
+		Status:      AlertSuppressed,
+		TriggeredAt: at,
+	}
+	alert.Message = fmt.Sprintf("[suppressed: %s] %s", window.Reason, s.generateAlertMessage(&alert))
+
+	if err := s.db.Create(&alert).Error; err != nil {
+		log.Printf("Failed to record suppressed alert: %v", err)
+		return
+	}
+	s.markChanged()
+
+	log.Printf("Alert suppressed by %s: %s%s - %s %s %s",
+		window.suppressedBy(), metricType, mountSuffix(mount), metrics.FormatValue(metricType, value),
+		comparison(direction), metrics.FormatValue(metricType, threshold))
+}
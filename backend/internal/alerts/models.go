@@ -12,6 +12,9 @@ type AlertStatus string
 const (
 	AlertActive   AlertStatus = "active"
 	AlertResolved AlertStatus = "resolved"
+
+	// AlertSuppressed records a breach that occurred during a maintenance window
+	AlertSuppressed AlertStatus = "suppressed"
 )
 
 // AlertSeverity represents the severity level of an alert
@@ -26,17 +29,40 @@ const (
 
 // Alert represents a system alert
 type Alert struct {
-	ID          uint               `json:"id" gorm:"primaryKey"`
-	Type        metrics.MetricType `json:"type" gorm:"column:metric_type"`
-	Message     string             `json:"message" gorm:"not null"`
-	Value       float64            `json:"value" gorm:"not null"`
-	Threshold   float64            `json:"threshold" gorm:"not null"`
-	Severity    AlertSeverity      `json:"severity" gorm:"not null"`
-	Status      AlertStatus        `json:"status" gorm:"default:'active'"`
-	TriggeredAt time.Time          `json:"triggered_at" gorm:"not null"`
-	ResolvedAt  *time.Time         `json:"resolved_at,omitempty"`
-	CreatedAt   time.Time          `json:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at"`
+	ID          uint                       `json:"id" gorm:"primaryKey"`
+	Type        metrics.MetricType         `json:"type" gorm:"column:metric_type"`
+	Mount       string                     `json:"mount,omitempty" gorm:"not null;default:''"`
+	StaleType   metrics.MetricType         `json:"stale_type,omitempty" gorm:"not null;default:''"`
+	FlapType    metrics.MetricType         `json:"flap_type,omitempty" gorm:"not null;default:''"`
+	Source      string                     `json:"source,omitempty" gorm:"not null;default:''"`
+	Message     string                     `json:"message" gorm:"not null"`
+	Value       float64                    `json:"value" gorm:"not null"`
+	Threshold   float64                    `json:"threshold" gorm:"not null"`
+	Direction   metrics.ThresholdDirection `json:"direction" gorm:"not null;default:'above'"`
+	Severity    AlertSeverity              `json:"severity" gorm:"not null"`
+	Status      AlertStatus                `json:"status" gorm:"default:'active'"`
+	TriggeredAt time.Time                  `json:"triggered_at" gorm:"not null"`
+	ResolvedAt  *time.Time                 `json:"resolved_at,omitempty"`
+	IncidentID  *uint                      `json:"incident_id,omitempty" gorm:"index"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+
+	// DurationSeconds is computed on read and never stored
+	DurationSeconds int64 `json:"duration_seconds" gorm:"-"`
+}
+
+// populateDuration sets DurationSeconds from TriggeredAt to ResolvedAt, or to now for active alerts
+func (a *Alert) populateDuration(now time.Time) {
+	end := now
+	if a.ResolvedAt != nil {
+		end = *a.ResolvedAt
+	}
+
+	duration := end.Sub(a.TriggeredAt)
+	if duration < 0 {
+		duration = 0
+	}
+	a.DurationSeconds = int64(duration / time.Second)
 }
 
 // AlertSummary represents aggregated alert statistics
@@ -47,6 +73,20 @@ type AlertSummary struct {
 	AlertsByType     map[metrics.MetricType]int64 `json:"alerts_by_type"`
 	AlertsBySeverity map[AlertSeverity]int64      `json:"alerts_by_severity"`
 	RecentAlerts     []Alert                      `json:"recent_alerts"`
+	// RecentAlertsTotal counts all alerts RecentAlerts was drawn from;
+	// RecentAlertsHasMore is set when RecentAlerts is a truncated page of them
+	RecentAlertsTotal   int64 `json:"recent_alerts_total"`
+	RecentAlertsHasMore bool  `json:"recent_alerts_has_more"`
+
+	// ActiveMaintenance is the maintenance window in effect, if any
+	ActiveMaintenance *MaintenanceWindow `json:"active_maintenance,omitempty"`
+}
+
+// AlertTrendBucket holds alert counts for one triggered-at time bucket
+type AlertTrendBucket struct {
+	Start      time.Time               `json:"start"`
+	Total      int64                   `json:"total"`
+	BySeverity map[AlertSeverity]int64 `json:"by_severity"`
 }
 
 // CreateAlertRequest represents a request to create an alert
@@ -55,3 +95,25 @@ type CreateAlertRequest struct {
 	Value     float64            `json:"value" binding:"required"`
 	Threshold float64            `json:"threshold" binding:"required"`
 }
+
+// SeverityFilter selects alerts for severity recalculation; zero values are ignored
+type SeverityFilter struct {
+	Status AlertStatus
+	Type   metrics.MetricType
+	From   time.Time
+	To     time.Time
+}
+
+// SeverityChange counts alerts moved from one severity to another
+type SeverityChange struct {
+	From  AlertSeverity `json:"from"`
+	To    AlertSeverity `json:"to"`
+	Count int64         `json:"count"`
+}
+
+// SeverityRecalculation reports the outcome of a severity recalculation
+type SeverityRecalculation struct {
+	Examined int64            `json:"examined"`
+	Updated  int64            `json:"updated"`
+	Changes  []SeverityChange `json:"changes"`
+}
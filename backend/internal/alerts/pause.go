@@ -0,0 +1,13 @@
+package alerts
+
+// SetCollectionPaused lets the service ask whether metric collection is
+// paused. While it is, thresholds are not checked and no staleness alerts
+// are raised, since the only data there is predates the pause.
+func (s *Service) SetCollectionPaused(paused func() bool) {
+	s.collectionPaused = paused
+}
+
+// paused reports whether metric collection is paused
+func (s *Service) paused() bool {
+	return s.collectionPaused != nil && s.collectionPaused()
+}
@@ -0,0 +1,114 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxAlertLimit caps how many alerts one request can return
+const DefaultMaxAlertLimit = 500
+
+// AlertSortField is a field alerts can be sorted by
+type AlertSortField string
+
+const (
+	SortTriggeredAt AlertSortField = "triggered_at"
+	SortSeverity    AlertSortField = "severity"
+	SortResolvedAt  AlertSortField = "resolved_at"
+)
+
+// AlertSort orders a list of alerts
+type AlertSort struct {
+	Field AlertSortField
+	Desc  bool
+}
+
+// DefaultAlertSort lists the most recently triggered alerts first
+var DefaultAlertSort = AlertSort{Field: SortTriggeredAt, Desc: true}
+
+// ParseAlertSort validates a sort field and an order of "asc" or "desc".
+// An empty field means triggered_at and an empty order means desc.
+func ParseAlertSort(field, order string) (AlertSort, error) {
+	result := DefaultAlertSort
+
+	switch f := AlertSortField(strings.ToLower(field)); f {
+	case "":
+	case SortTriggeredAt, SortSeverity, SortResolvedAt:
+		result.Field = f
+	default:
+		return AlertSort{}, fmt.Errorf("invalid sort field %q (expected triggered_at, severity or resolved_at)", field)
+	}
+
+	switch strings.ToLower(order) {
+	case "", "desc":
+	case "asc":
+		result.Desc = false
+	default:
+		return AlertSort{}, fmt.Errorf("invalid order %q (expected asc or desc)", order)
+	}
+
+	return result, nil
+}
+
+// orderClause returns the ORDER BY clause for the sort. Severity is ordered
+// by rank rather than name, alerts without a resolved_at come last either
+// way, and ties fall back to the newest alert first.
+func (a AlertSort) orderClause() string {
+	direction := "ASC"
+	if a.Desc {
+		direction = "DESC"
+	}
+
+	var clause string
+	switch a.Field {
+	case SortSeverity:
+		clause = severityRankExpr() + " " + direction
+	case SortResolvedAt:
+		clause = "CASE WHEN resolved_at IS NULL THEN 1 ELSE 0 END, resolved_at " + direction
+	default:
+		return "triggered_at " + direction + ", id " + direction
+	}
+	return clause + ", triggered_at DESC, id DESC"
+}
+
+// severityRankExpr is a SQL expression ranking the severity column by severityRank
+func severityRankExpr() string {
+	severities := make([]AlertSeverity, 0, len(severityRank))
+	for severity := range severityRank {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		return severityRank[severities[i]] < severityRank[severities[j]]
+	})
+
+	var expr strings.Builder
+	expr.WriteString("CASE severity")
+	for _, severity := range severities {
+		fmt.Fprintf(&expr, " WHEN '%s' THEN %d", severity, severityRank[severity])
+	}
+	expr.WriteString(" ELSE 0 END")
+	return expr.String()
+}
+
+// SetMaxAlertLimit sets how many alerts one request can return; a
+// non-positive max uses DefaultMaxAlertLimit
+func (s *Service) SetMaxAlertLimit(max int) {
+	if max <= 0 {
+		max = DefaultMaxAlertLimit
+	}
+	s.maxAlertLimit = max
+}
+
+// AlertLimit returns the number of alerts a request for limit returns:
+// limit itself, or the maximum when limit exceeds it or is not positive
+func (s *Service) AlertLimit(limit int) int {
+	max := s.maxAlertLimit
+	if max <= 0 {
+		max = DefaultMaxAlertLimit
+	}
+	if limit <= 0 || limit > max {
+		return max
+	}
+	return limit
+}
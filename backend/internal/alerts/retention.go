@@ -0,0 +1,107 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// alertRetentionInterval is how often resolved alerts are checked for deletion
+const alertRetentionInterval = time.Hour
+
+// RetentionStatus describes the resolved alert retention job
+type RetentionStatus struct {
+	Enabled bool `json:"enabled"`
+	// Retention is how long resolved alerts are kept, e.g. "2160h0m0s"
+	Retention    string     `json:"retention,omitempty"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastDeleted  int64      `json:"last_deleted"`
+	TotalDeleted int64      `json:"total_deleted"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// retentionState records what the retention job has done
+type retentionState struct {
+	mu     sync.Mutex
+	status RetentionStatus
+}
+
+// PurgeResolvedAlerts permanently deletes alerts resolved more than olderThan
+// ago. Active and suppressed alerts are never deleted.
+func (s *Service) PurgeResolvedAlerts(olderThan time.Duration) (int64, error) {
+	cutoff := s.clock.Now().Add(-olderThan)
+
+	result := s.db.Where("status = ? AND resolved_at IS NOT NULL AND resolved_at < ?", AlertResolved, cutoff).
+		Delete(&Alert{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge resolved alerts: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		s.markChanged()
+	}
+	return result.RowsAffected, nil
+}
+
+// StartAlertRetention deletes alerts resolved more than retention ago, once
+// at startup and then periodically until ctx is done. A non-positive
+// retention keeps resolved alerts forever.
+func (s *Service) StartAlertRetention(ctx context.Context, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	s.retention.mu.Lock()
+	s.retention.status.Enabled = true
+	s.retention.status.Retention = retention.String()
+	s.retention.mu.Unlock()
+
+	ticker := time.NewTicker(alertRetentionInterval)
+	defer ticker.Stop()
+
+	log.Printf("Deleting resolved alerts after %v", retention)
+
+	s.runAlertRetention(retention)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runAlertRetention(retention)
+		}
+	}
+}
+
+// runAlertRetention purges old resolved alerts and records the outcome
+func (s *Service) runAlertRetention(retention time.Duration) {
+	deleted, err := s.PurgeResolvedAlerts(retention)
+	now := s.clock.Now()
+
+	s.retention.mu.Lock()
+	defer s.retention.mu.Unlock()
+
+	s.retention.status.LastRun = &now
+	s.retention.status.LastDeleted = deleted
+	s.retention.status.TotalDeleted += deleted
+	s.retention.status.LastError = ""
+	if err != nil {
+		log.Printf("Error purging resolved alerts: %v", err)
+		s.retention.status.LastError = err.Error()
+	} else if deleted > 0 {
+		log.Printf("Deleted %d resolved alerts older than %v", deleted, retention)
+	}
+}
+
+// RetentionStatus reports what the resolved alert retention job has done
+func (s *Service) RetentionStatus() RetentionStatus {
+	s.retention.mu.Lock()
+	defer s.retention.mu.Unlock()
+
+	status := s.retention.status
+	if status.LastRun != nil {
+		lastRun := *status.LastRun
+		status.LastRun = &lastRun
+	}
+	return status
+}
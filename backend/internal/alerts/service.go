@@ -1,111 +1,435 @@
 package alerts
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"sync/atomic"
 	"time"
 
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/clock"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/notify"
 	"gorm.io/gorm"
 )
 
+// ErrAlertNotFound is returned when an alert does not exist
+var ErrAlertNotFound = errors.New("alert not found")
+
+// DefaultComparisonEpsilon is how far past its threshold a value must be to
+// breach it
+const DefaultComparisonEpsilon = 0.01
+
+// Notifier sends alert notifications; satisfied by notify.Dispatcher
+type Notifier interface {
+	Dispatch(event notify.Event)
+}
+
 // Service handles alert operations
 type Service struct {
-	db *gorm.DB
+	db        *gorm.DB
+	notifier  Notifier
+	templates *MessageTemplates
+
+	// mountThresholds overrides the disk_usage threshold per mount point
+	mountThresholds map[string]float64
+
+	// epsilon is how far past its threshold a value must be to breach it
+	epsilon float64
+
+	// incidentWindow groups threshold alerts firing this close together
+	incidentWindow time.Duration
+
+	// maxAlertLimit caps how many alerts GetAlerts returns
+	maxAlertLimit int
+
+	// realertCooldown holds back a new threshold alert for this long after
+	// the last one for the same metric type and mount resolved
+	realertCooldown time.Duration
+
+	// Threshold alerts are suppressed from warmupStart until warmupEnd
+	warmupStart, warmupEnd time.Time
+
+	// A flapping alert is raised when an alert changes state more than
+	// flapMaxTransitions times within flapWindow
+	flapWindow         time.Duration
+	flapMaxTransitions int
+
+	// collectionPaused reports whether metric collection is paused
+	collectionPaused func() bool
+
+	// retention records the resolved alert retention job's progress
+	retention retentionState
+
+	// clock tells the time for alert timestamps, durations and windows
+	clock clock.Clock
+
+	// revision counts changes to stored alerts and maintenance windows, so
+	// callers caching alert-derived data can tell when it is stale
+	revision atomic.Uint64
 }
 
 // NewService creates a new alert service
 func NewService(db *gorm.DB) *Service {
-	return &Service{db: db}
+	return &Service{
+		db:             db,
+		clock:          clock.Real{},
+		epsilon:        DefaultComparisonEpsilon,
+		incidentWindow: DefaultIncidentWindow,
+		maxAlertLimit:  DefaultMaxAlertLimit,
+	}
+}
+
+// SetClock sets the clock used for alert timestamps, durations, cooldowns and
+// windows, so tests can control time. Tickers still run on real time.
+func (s *Service) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetMountThresholds sets per-mount disk usage thresholds; mounts without an
+// entry use the disk_usage threshold
+func (s *Service) SetMountThresholds(thresholds map[string]float64) {
+	s.mountThresholds = thresholds
+}
+
+// SetComparisonEpsilon sets how far past its threshold a value must be to
+// breach it, so floating point noise such as 80.0000001 against a threshold
+// of 80 neither triggers nor keeps an alert. Zero compares exactly; negative
+// values are treated as zero.
+func (s *Service) SetComparisonEpsilon(epsilon float64) {
+	if epsilon < 0 {
+		epsilon = 0
+	}
+	s.epsilon = epsilon
+}
+
+// breached reports whether value breaches threshold in direction, allowing
+// for the comparison epsilon
+func (s *Service) breached(direction metrics.ThresholdDirection, value, threshold float64) bool {
+	return direction.BreachedBy(value, threshold, s.epsilon)
+}
+
+// Revision returns a counter that increases whenever alerts are created,
+// resolved, deleted or rescored, or maintenance windows change
+func (s *Service) Revision() uint64 {
+	return s.revision.Load()
+}
+
+// markChanged records a change to stored alerts
+func (s *Service) markChanged() {
+	s.revision.Add(1)
+}
+
+// SetNotifier sets the notifier told about triggered and resolved alerts
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// alertEvent describes alert as a notification event of kind
+func alertEvent(kind string, alert *Alert) notify.Event {
+	return notify.Event{
+		Kind:        kind,
+		AlertID:     alert.ID,
+		MetricType:  string(alert.Type),
+		Mount:       alert.Mount,
+		Severity:    string(alert.Severity),
+		Message:     alert.Message,
+		Value:       alert.Value,
+		Threshold:   alert.Threshold,
+		TriggeredAt: alert.TriggeredAt,
+	}
+}
+
+// notifyTriggered sends a notification for a newly created alert
+func (s *Service) notifyTriggered(alert *Alert) {
+	if s.notifier == nil {
+		return
+	}
+	s.notifier.Dispatch(alertEvent(notify.EventTriggered, alert))
+}
+
+// notifyResolved sends a notification for each resolved alert, with how long
+// it was active
+func (s *Service) notifyResolved(resolved []Alert) {
+	if s.notifier == nil {
+		return
+	}
+	for i := range resolved {
+		alert := &resolved[i]
+		event := alertEvent(notify.EventResolved, alert)
+		event.ResolvedAt = alert.ResolvedAt
+		event.DurationSeconds = alert.DurationSeconds
+		s.notifier.Dispatch(event)
+	}
+}
+
+// resolveAlerts resolves the active alerts matching query and args at now
+// and returns them as resolved, so they can be notified about
+func resolveAlerts(db *gorm.DB, now time.Time, query interface{}, args ...interface{}) ([]Alert, error) {
+	var active []Alert
+	if err := db.Where(query, args...).Where("status = ?", AlertActive).Find(&active).Error; err != nil {
+		return nil, err
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(active))
+	for i, alert := range active {
+		ids[i] = alert.ID
+	}
+	if err := db.Model(&Alert{}).
+		Where("id IN ? AND status = ?", ids, AlertActive).
+		Updates(map[string]interface{}{
+			"status":      AlertResolved,
+			"resolved_at": &now,
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range active {
+		active[i].Status = AlertResolved
+		active[i].ResolvedAt = &now
+		active[i].populateDuration(now)
+	}
+	return active, nil
 }
 
 // CheckThresholds checks if current metrics exceed thresholds and creates alerts
 func (s *Service) CheckThresholds(currentMetrics *metrics.SystemMetrics) error {
+	// Metrics from before a pause are stale, so nothing may fire on them
+	if s.paused() {
+		return nil
+	}
+
 	// Get all enabled thresholds
 	var thresholds []metrics.MetricThreshold
 	if err := s.db.Where("enabled = ?", true).Find(&thresholds).Error; err != nil {
 		return fmt.Errorf("failed to get thresholds: %w", err)
 	}
 
-	for _, threshold := range thresholds {
-		var currentValue float64
+	// During maintenance or the startup warmup nothing is created or
+	// resolved; breaches are only recorded
+	window, err := s.ActiveMaintenanceWindow(currentMetrics.Timestamp)
+	if err != nil {
+		return err
+	}
+	if window == nil {
+		window = s.warmupWindow(currentMetrics.Timestamp)
+	}
+
+	for _, check := range s.thresholdChecks(thresholds, currentMetrics) {
+		s.evaluateThreshold(window, check.metricType, check.mount, check.value, check.threshold, check.resolveMargin, check.direction, currentMetrics.Timestamp)
+	}
+	if window == nil {
+		s.resolveSettledFlapping(currentMetrics.Timestamp)
+	}
+
+	return nil
+}
+
+// thresholdCheck is one current value to compare against its threshold
+type thresholdCheck struct {
+	metricType    metrics.MetricType
+	mount         string
+	value         float64
+	threshold     float64
+	resolveMargin float64
+	direction     metrics.ThresholdDirection
+}
 
+// thresholdChecks pairs each enabled threshold with the current values it
+// applies to. Each disk mount is checked against its own threshold, falling
+// back to the disk_usage default; mounts that disappeared are skipped, as are
+// types that were not collected this cycle.
+func (s *Service) thresholdChecks(thresholds []metrics.MetricThreshold, currentMetrics *metrics.SystemMetrics) []thresholdCheck {
+	var checks []thresholdCheck
+	for _, threshold := range thresholds {
 		switch threshold.Type {
 		case metrics.CPUUsage:
-			currentValue = currentMetrics.CPUUsage
+			if !currentMetrics.Reported(threshold.Type) {
+				continue
+			}
+			checks = append(checks, thresholdCheck{threshold.Type, "", currentMetrics.CPUUsage, threshold.Threshold, threshold.ResolveMargin, threshold.Direction})
 		case metrics.MemoryUsage:
-			currentValue = currentMetrics.MemoryUsage
-		default:
-			continue
+			if !currentMetrics.Reported(threshold.Type) {
+				continue
+			}
+			checks = append(checks, thresholdCheck{threshold.Type, "", currentMetrics.MemoryUsage, threshold.Threshold, threshold.ResolveMargin, threshold.Direction})
+		case metrics.MemoryAvailable:
+			// Not every platform reports available memory
+			if currentMetrics.MemoryAvailable != nil {
+				checks = append(checks, thresholdCheck{threshold.Type, "", *currentMetrics.MemoryAvailable, threshold.Threshold, threshold.ResolveMargin, threshold.Direction})
+			}
+		case metrics.DiskUsage:
+			for mount, usage := range currentMetrics.DiskUsage {
+				limit := threshold.Threshold
+				if override, ok := s.mountThresholds[mount]; ok {
+					limit = override
+				}
+				checks = append(checks, thresholdCheck{threshold.Type, mount, usage, limit, threshold.ResolveMargin, threshold.Direction})
+			}
 		}
+	}
+	return checks
+}
 
-		// Check if threshold is breached
-		if currentValue > threshold.Threshold {
-			// Check if there's already an active alert for this type
-			var existingAlert Alert
-			err := s.db.Where("metric_type = ? AND status = ?", threshold.Type, AlertActive).
-				First(&existingAlert).Error
-
-			if err == gorm.ErrRecordNotFound {
-				// Create new alert
-				alert := Alert{
-					Type:        threshold.Type,
-					Message:     s.generateAlertMessage(threshold.Type, currentValue, threshold.Threshold),
-					Value:       currentValue,
-					Threshold:   threshold.Threshold,
-					Severity:    s.calculateSeverity(currentValue, threshold.Threshold),
-					Status:      AlertActive,
-					TriggeredAt: currentMetrics.Timestamp,
-				}
+// evaluateThreshold creates or resolves the alert for one metric type and
+// mount. An active alert only resolves once the value is resolveMargin back
+// past the threshold. During a maintenance window breaches are only recorded
+// as suppressed.
+func (s *Service) evaluateThreshold(window *MaintenanceWindow, metricType metrics.MetricType, mount string, currentValue, threshold, resolveMargin float64, direction metrics.ThresholdDirection, at time.Time) {
+	if window != nil {
+		if s.breached(direction, currentValue, threshold) {
+			s.recordSuppressedBreach(window, metricType, mount, currentValue, threshold, direction, at)
+		}
+		return
+	}
+
+	// Check if threshold is breached
+	if s.breached(direction, currentValue, threshold) {
+		// Check if there's already an active alert for this type
+		var existingAlert Alert
+		err := s.db.Where("metric_type = ? AND mount = ? AND status = ?", metricType, mount, AlertActive).
+			First(&existingAlert).Error
+
+		if err == gorm.ErrRecordNotFound && !s.inRealertCooldown(metricType, mount, at) {
+			// Create new alert
+			alert := Alert{
+				Type:        metricType,
+				Mount:       mount,
+				Value:       currentValue,
+				Threshold:   threshold,
+				Direction:   direction,
+				Severity:    s.calculateSeverity(currentValue, threshold, direction),
+				Status:      AlertActive,
+				TriggeredAt: at,
+			}
+			alert.Message = s.generateAlertMessage(&alert)
 
-				if err := s.db.Create(&alert).Error; err != nil {
-					log.Printf("Failed to create alert: %v", err)
-				} else {
-					log.Printf("Alert created: %s - %.2f%% > %.2f%%",
-						threshold.Type, currentValue, threshold.Threshold)
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Create(&alert).Error; err != nil {
+					return err
 				}
+				return s.attachToIncident(tx, &alert)
+			})
+			if err != nil {
+				log.Printf("Failed to create alert: %v", err)
+			} else {
+				s.markChanged()
+				log.Printf("Alert created: %s%s - %s %s %s",
+					metricType, mountSuffix(mount), metrics.FormatValue(metricType, currentValue),
+					comparison(direction), metrics.FormatValue(metricType, threshold))
+				s.notifyTriggered(&alert)
+				s.checkFlapping(metricType, mount, threshold, at)
 			}
-		} else {
-			// Resolve any active alerts for this type
-			s.resolveActiveAlerts(threshold.Type)
+		}
+	} else if direction.RecoveredBy(currentValue, threshold, resolveMargin, s.epsilon) {
+		// Resolve any active alerts for this type
+		if s.resolveActiveAlerts(metricType, mount) {
+			s.checkFlapping(metricType, mount, threshold, at)
 		}
 	}
+}
 
-	return nil
+// mountSuffix formats a mount for log lines, or returns "" when there is none
+func mountSuffix(mount string) string {
+	if mount == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", mount)
 }
 
 // resolveActiveAlerts resolves all active alerts for a specific metric type
-func (s *Service) resolveActiveAlerts(metricType metrics.MetricType) {
-	now := time.Now()
-	result := s.db.Model(&Alert{}).
-		Where("metric_type = ? AND status = ?", metricType, AlertActive).
-		Updates(map[string]interface{}{
-			"status":      AlertResolved,
-			"resolved_at": &now,
-		})
+// and mount, reporting whether any were resolved
+func (s *Service) resolveActiveAlerts(metricType metrics.MetricType, mount string) bool {
+	now := s.clock.Now()
+	resolved, err := resolveAlerts(s.db, now, "metric_type = ? AND mount = ?", metricType, mount)
+	if err != nil {
+		log.Printf("Failed to resolve alerts for %s%s: %v", metricType, mountSuffix(mount), err)
+		return false
+	}
+	if len(resolved) == 0 {
+		return false
+	}
+
+	s.markChanged()
+	log.Printf("Resolved %d alerts for %s%s", len(resolved), metricType, mountSuffix(mount))
+	if err := closeResolvedIncidents(s.db, now); err != nil {
+		log.Printf("%v", err)
+	}
+	s.notifyResolved(resolved)
+	return true
+}
+
+// generateAlertMessage creates a descriptive alert message, using the
+// configured template for the alert's metric type when there is one
+func (s *Service) generateAlertMessage(alert *Alert) string {
+	if message, ok := s.renderAlertMessage(alert); ok {
+		return message
+	}
 
-	if result.Error != nil {
-		log.Printf("Failed to resolve alerts for %s: %v", metricType, result.Error)
-	} else if result.RowsAffected > 0 {
-		log.Printf("Resolved %d alerts for %s", result.RowsAffected, metricType)
+	metricType := alert.Type
+	value, threshold := formatAlertValues(alert)
+	if alert.Direction == metrics.DirectionBelow {
+		return lowValueMessage(alert)
+	}
+	switch metricType {
+	case metrics.CPUUsage:
+		return fmt.Sprintf("High CPU usage detected: %s (threshold: %s)", value, threshold)
+	case metrics.MemoryUsage:
+		return fmt.Sprintf("High memory usage detected: %s (threshold: %s)", value, threshold)
+	case metrics.LogErrorRate:
+		return fmt.Sprintf("High log error rate detected: %s (threshold: %s)", value, threshold)
+	case metrics.DiskUsage:
+		return fmt.Sprintf("High disk usage detected on %s: %s (threshold: %s)", alert.Mount, value, threshold)
+	default:
+		return fmt.Sprintf("Threshold breached for %s: %s (threshold: %s)", metrics.DisplayName(metricType), value, threshold)
 	}
 }
 
-// generateAlertMessage creates a descriptive alert message
-func (s *Service) generateAlertMessage(metricType metrics.MetricType, value, threshold float64) string {
+// lowValueMessage describes an alert on a value that fell below its threshold
+func lowValueMessage(alert *Alert) string {
+	metricType := alert.Type
+	value, threshold := formatAlertValues(alert)
 	switch metricType {
 	case metrics.CPUUsage:
-		return fmt.Sprintf("High CPU usage detected: %.2f%% (threshold: %.2f%%)", value, threshold)
+		return fmt.Sprintf("Low CPU usage detected: %s (threshold: %s)", value, threshold)
 	case metrics.MemoryUsage:
-		return fmt.Sprintf("High memory usage detected: %.2f%% (threshold: %.2f%%)", value, threshold)
+		return fmt.Sprintf("Low memory usage detected: %s (threshold: %s)", value, threshold)
+	case metrics.MemoryAvailable:
+		return fmt.Sprintf("Low available memory detected: %s (threshold: %s)", value, threshold)
+	case metrics.LogErrorRate:
+		return fmt.Sprintf("Low log error rate detected: %s (threshold: %s)", value, threshold)
+	case metrics.DiskUsage:
+		return fmt.Sprintf("Low disk usage detected on %s: %s (threshold: %s)", alert.Mount, value, threshold)
 	default:
-		return fmt.Sprintf("Threshold breached for %s: %.2f%% (threshold: %.2f%%)", metricType, value, threshold)
+		return fmt.Sprintf("Value below threshold for %s: %s (threshold: %s)", metrics.DisplayName(metricType), value, threshold)
 	}
 }
 
-// calculateSeverity determines alert severity based on how much the threshold is exceeded
-func (s *Service) calculateSeverity(value, threshold float64) AlertSeverity {
+// formatAlertValues formats an alert's value and threshold in the unit of
+// its metric type
+func formatAlertValues(alert *Alert) (string, string) {
+	return metrics.FormatValue(alert.Type, alert.Value), metrics.FormatValue(alert.Type, alert.Threshold)
+}
+
+// comparison returns the operator a breach in direction satisfies, for logs
+func comparison(direction metrics.ThresholdDirection) string {
+	if direction == metrics.DirectionBelow {
+		return "<"
+	}
+	return ">"
+}
+
+// calculateSeverity determines alert severity based on how far past the
+// threshold the value is, in the threshold's direction
+func (s *Service) calculateSeverity(value, threshold float64, direction metrics.ThresholdDirection) AlertSeverity {
 	exceedPercentage := ((value - threshold) / threshold) * 100
+	if direction == metrics.DirectionBelow {
+		exceedPercentage = -exceedPercentage
+	}
 
 	switch {
 	case exceedPercentage >= 50: // 50% above threshold
@@ -119,27 +443,44 @@ func (s *Service) calculateSeverity(value, threshold float64) AlertSeverity {
 	}
 }
 
-// GetAlerts returns alerts with optional filtering
-func (s *Service) GetAlerts(status AlertStatus, limit int) ([]Alert, error) {
+// GetAlerts returns alerts with optional filtering, in the given order. The
+// limit is clamped to the maximum set by SetMaxAlertLimit.
+func (s *Service) GetAlerts(status AlertStatus, limit int, order AlertSort) ([]Alert, error) {
 	var alerts []Alert
 
-	query := s.db.Order("triggered_at DESC")
+	query := s.db.Order(order.orderClause()).Limit(s.AlertLimit(limit))
 
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
 
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-
 	if err := query.Find(&alerts).Error; err != nil {
 		return nil, fmt.Errorf("failed to get alerts: %w", err)
 	}
 
+	now := s.clock.Now()
+	for i := range alerts {
+		alerts[i].populateDuration(now)
+	}
+
 	return alerts, nil
 }
 
+// GetAlertByID returns a single alert by its ID
+func (s *Service) GetAlertByID(alertID uint) (*Alert, error) {
+	var alert Alert
+	if err := s.db.First(&alert, alertID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAlertNotFound
+		}
+		return nil, fmt.Errorf("failed to get alert: %w", err)
+	}
+
+	alert.populateDuration(s.clock.Now())
+
+	return &alert, nil
+}
+
 // GetAlertSummary returns comprehensive alert statistics
 func (s *Service) GetAlertSummary(limit int) (*AlertSummary, error) {
 	summary := &AlertSummary{
@@ -197,51 +538,297 @@ func (s *Service) GetAlertSummary(limit int) (*AlertSummary, error) {
 	}
 
 	// Get recent alerts
-	recentAlerts, err := s.GetAlerts("", limit)
+	recentAlerts, err := s.GetAlerts("", limit, DefaultAlertSort)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent alerts: %w", err)
 	}
 	summary.RecentAlerts = recentAlerts
+	summary.RecentAlertsTotal = summary.TotalAlerts
+	summary.RecentAlertsHasMore = int64(len(recentAlerts)) < summary.TotalAlerts
+
+	window, err := s.ActiveMaintenanceWindow(s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	summary.ActiveMaintenance = window
 
 	return summary, nil
 }
 
+// GetActiveAlertCountsBySeverity counts active alerts per severity with one
+// grouped query. Every severity is present, with zero when none are active.
+func (s *Service) GetActiveAlertCountsBySeverity() (map[AlertSeverity]int64, error) {
+	counts := map[AlertSeverity]int64{
+		SeverityLow:      0,
+		SeverityMedium:   0,
+		SeverityHigh:     0,
+		SeverityCritical: 0,
+	}
+
+	var results []struct {
+		Severity AlertSeverity
+		Count    int64
+	}
+	if err := s.db.Model(&Alert{}).
+		Select("severity, COUNT(*) as count").
+		Where("status = ?", AlertActive).
+		Group("severity").
+		Scan(&results).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active alerts by severity: %w", err)
+	}
+
+	for _, result := range results {
+		counts[result.Severity] = result.Count
+	}
+	return counts, nil
+}
+
+// maxTrendBuckets bounds the size of a trend response
+const maxTrendBuckets = 1000
+
+// GetAlertTrend counts alerts per triggered-at bucket between from and to,
+// broken down by severity. Buckets with no alerts are included with zero counts.
+func (s *Service) GetAlertTrend(from, to time.Time, bucket time.Duration) ([]AlertTrendBucket, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("bucket must be positive")
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	start := from.Truncate(bucket)
+	count := int(to.Sub(start)/bucket) + 1
+	if count > maxTrendBuckets {
+		return nil, fmt.Errorf("range spans %d buckets, maximum is %d", count, maxTrendBuckets)
+	}
+
+	trend := make([]AlertTrendBucket, count)
+	for i := range trend {
+		trend[i] = AlertTrendBucket{
+			Start: start.Add(time.Duration(i) * bucket),
+			BySeverity: map[AlertSeverity]int64{
+				SeverityLow:      0,
+				SeverityMedium:   0,
+				SeverityHigh:     0,
+				SeverityCritical: 0,
+			},
+		}
+	}
+
+	var rows []struct {
+		TriggeredAt time.Time
+		Severity    AlertSeverity
+	}
+	if err := s.db.Model(&Alert{}).
+		Select("triggered_at, severity").
+		Where("triggered_at >= ? AND triggered_at <= ?", start, to).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get alert trend: %w", err)
+	}
+
+	for _, row := range rows {
+		i := int(row.TriggeredAt.Sub(start) / bucket)
+		if i < 0 || i >= count {
+			continue
+		}
+		trend[i].Total++
+		trend[i].BySeverity[row.Severity]++
+	}
+
+	return trend, nil
+}
+
 // CreateAlert manually creates an alert (for testing purposes)
 func (s *Service) CreateAlert(req *CreateAlertRequest) (*Alert, error) {
 	alert := Alert{
 		Type:        req.Type,
-		Message:     s.generateAlertMessage(req.Type, req.Value, req.Threshold),
 		Value:       req.Value,
 		Threshold:   req.Threshold,
-		Severity:    s.calculateSeverity(req.Value, req.Threshold),
+		Direction:   metrics.DirectionAbove,
+		Severity:    s.calculateSeverity(req.Value, req.Threshold, metrics.DirectionAbove),
 		Status:      AlertActive,
-		TriggeredAt: time.Now(),
+		TriggeredAt: s.clock.Now(),
 	}
+	alert.Message = s.generateAlertMessage(&alert)
 
 	if err := s.db.Create(&alert).Error; err != nil {
 		return nil, fmt.Errorf("failed to create alert: %w", err)
 	}
+	s.markChanged()
+	s.notifyTriggered(&alert)
+
+	return &alert, nil
+}
+
+// CheckLogErrorRate alerts on a log file whose error ratio exceeded the given
+// threshold ratio, or resolves the file's alert once its ratio is back under
+// it. Each source has at most one active alert, which is returned while the
+// ratio stays over the threshold; nil is returned once it is under. Ratios
+// are stored as percentages like other metrics.
+func (s *Service) CheckLogErrorRate(source string, errorRate, thresholdRatio float64) (*Alert, error) {
+	value := errorRate * 100
+	threshold := thresholdRatio * 100
+
+	if !s.breached(metrics.DirectionAbove, value, threshold) {
+		resolved, err := resolveAlerts(s.db, s.clock.Now(), "metric_type = ? AND source = ?", metrics.LogErrorRate, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve log error rate alert: %w", err)
+		}
+		if len(resolved) > 0 {
+			s.markChanged()
+			log.Printf("Alert resolved: %s back to %s in %s", metrics.LogErrorRate,
+				metrics.FormatValue(metrics.LogErrorRate, value), source)
+			s.notifyResolved(resolved)
+		}
+		return nil, nil
+	}
+
+	var existing Alert
+	err := s.db.Where("metric_type = ? AND source = ? AND status = ?", metrics.LogErrorRate, source, AlertActive).
+		First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check log error rate alerts: %w", err)
+	}
+
+	alert := Alert{
+		Type:        metrics.LogErrorRate,
+		Source:      source,
+		Value:       value,
+		Threshold:   threshold,
+		Direction:   metrics.DirectionAbove,
+		Severity:    s.calculateSeverity(value, threshold, metrics.DirectionAbove),
+		Status:      AlertActive,
+		TriggeredAt: s.clock.Now(),
+	}
+	alert.Message = fmt.Sprintf("%s in %s", s.generateAlertMessage(&alert), source)
+
+	if err := s.db.Create(&alert).Error; err != nil {
+		return nil, fmt.Errorf("failed to create log error rate alert: %w", err)
+	}
+	s.markChanged()
+
+	log.Printf("Alert created: %s - %s > %s in %s", metrics.LogErrorRate,
+		metrics.FormatValue(metrics.LogErrorRate, value), metrics.FormatValue(metrics.LogErrorRate, threshold), source)
+	s.notifyTriggered(&alert)
 
 	return &alert, nil
 }
 
 // ResolveAlert manually resolves an alert
 func (s *Service) ResolveAlert(alertID uint) error {
-	now := time.Now()
-	result := s.db.Model(&Alert{}).
-		Where("id = ? AND status = ?", alertID, AlertActive).
-		Updates(map[string]interface{}{
-			"status":      AlertResolved,
-			"resolved_at": &now,
-		})
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to resolve alert: %w", result.Error)
+	now := s.clock.Now()
+	resolved, err := resolveAlerts(s.db, now, "id = ?", alertID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve alert: %w", err)
 	}
 
-	if result.RowsAffected == 0 {
+	if len(resolved) == 0 {
 		return fmt.Errorf("alert not found or already resolved")
 	}
+	s.markChanged()
+
+	if err := closeResolvedIncidents(s.db, now); err != nil {
+		log.Printf("%v", err)
+	}
+	s.notifyResolved(resolved)
 
 	return nil
 }
+
+// recalculateBatchSize is the number of alerts loaded at a time when recalculating severity
+const recalculateBatchSize = 500
+
+// ResetAlerts deletes every alert and incident, returning the rows deleted
+// per table. It backs the admin reset endpoint used in development and tests.
+func (s *Service) ResetAlerts() (map[string]int64, error) {
+	deleted := make(map[string]int64)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("1 = 1").Delete(&Alert{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["alerts"] = result.RowsAffected
+
+		result = tx.Where("1 = 1").Delete(&Incident{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["incidents"] = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset alerts: %w", err)
+	}
+	s.markChanged()
+	return deleted, nil
+}
+
+// RecalculateSeverity recomputes the severity of alerts matching filter with
+// the current calculateSeverity rules, updating those that changed in a
+// single transaction
+func (s *Service) RecalculateSeverity(filter SeverityFilter) (*SeverityRecalculation, error) {
+	result := &SeverityRecalculation{Changes: []SeverityChange{}}
+	counts := make(map[[2]AlertSeverity]int64)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Staleness and flapping alerts have a fixed severity
+		query := tx.Model(&Alert{}).
+			Where("metric_type NOT IN ?", []metrics.MetricType{StalenessAlertType, FlappingAlertType}).
+			Order("id")
+		if filter.Status != "" {
+			query = query.Where("status = ?", filter.Status)
+		}
+		if filter.Type != "" {
+			query = query.Where("metric_type = ?", filter.Type)
+		}
+		if !filter.From.IsZero() {
+			query = query.Where("triggered_at >= ?", filter.From)
+		}
+		if !filter.To.IsZero() {
+			query = query.Where("triggered_at <= ?", filter.To)
+		}
+
+		var batch []Alert
+		return query.FindInBatches(&batch, recalculateBatchSize, func(_ *gorm.DB, _ int) error {
+			for _, alert := range batch {
+				result.Examined++
+
+				severity := s.calculateSeverity(alert.Value, alert.Threshold, alert.Direction)
+				if severity == alert.Severity {
+					continue
+				}
+
+				if err := tx.Model(&Alert{}).Where("id = ?", alert.ID).
+					Update("severity", severity).Error; err != nil {
+					return fmt.Errorf("failed to update alert %d: %w", alert.ID, err)
+				}
+				result.Updated++
+				counts[[2]AlertSeverity{alert.Severity, severity}]++
+			}
+			return nil
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to recalculate severity: %w", err)
+	}
+	if result.Updated > 0 {
+		s.markChanged()
+	}
+
+	for change, count := range counts {
+		result.Changes = append(result.Changes, SeverityChange{From: change[0], To: change[1], Count: count})
+	}
+	sort.Slice(result.Changes, func(i, j int) bool {
+		if result.Changes[i].From != result.Changes[j].From {
+			return result.Changes[i].From < result.Changes[j].From
+		}
+		return result.Changes[i].To < result.Changes[j].To
+	})
+
+	log.Printf("Recalculated severity: %d alerts examined, %d updated", result.Examined, result.Updated)
+
+	return result, nil
+}
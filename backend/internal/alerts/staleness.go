@@ -0,0 +1,141 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"gorm.io/gorm"
+)
+
+// StalenessAlertType is the alert type raised when a metric type stops
+// receiving data; the affected type is recorded in the alert's StaleType
+const StalenessAlertType metrics.MetricType = "metric_staleness"
+
+// maxStalenessCheckInterval bounds how long a stall can go unnoticed past the window
+const maxStalenessCheckInterval = time.Minute
+
+// CheckStaleness raises an alert for each of types whose newest metric is
+// older than window, and resolves the alerts of types receiving data again.
+// Types with no data at all are measured from since, when watching began.
+// During maintenance no alerts are created.
+func (s *Service) CheckStaleness(types []metrics.MetricType, window time.Duration, since, now time.Time) error {
+	maintenance, err := s.ActiveMaintenanceWindow(now)
+	if err != nil {
+		return err
+	}
+
+	for _, metricType := range types {
+		var newest metrics.Metric
+		err := s.db.Select("timestamp").
+			Where("metric_type = ?", metricType).
+			Order("timestamp DESC").
+			First(&newest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to find newest %s metric: %w", metricType, err)
+		}
+
+		lastSeen := since
+		if err == nil && newest.Timestamp.After(since) {
+			lastSeen = newest.Timestamp
+		}
+		age := now.Sub(lastSeen)
+
+		if age <= window {
+			s.resolveStaleness(metricType)
+			continue
+		}
+		if maintenance == nil {
+			s.raiseStaleness(metricType, age, window, now)
+		}
+	}
+
+	return nil
+}
+
+// raiseStaleness creates the staleness alert for metricType unless one is active
+func (s *Service) raiseStaleness(metricType metrics.MetricType, age, window time.Duration, now time.Time) {
+	var existing Alert
+	err := s.db.Where("metric_type = ? AND stale_type = ? AND status = ?", StalenessAlertType, metricType, AlertActive).
+		First(&existing).Error
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Failed to check staleness alerts: %v", err)
+		return
+	}
+
+	age = age.Round(time.Second)
+	alert := Alert{
+		Type:        StalenessAlertType,
+		StaleType:   metricType,
+		Message:     fmt.Sprintf("No %s data for %s (staleness window: %s)", metricType, age, window),
+		Value:       age.Seconds(),
+		Threshold:   window.Seconds(),
+		Direction:   metrics.DirectionAbove,
+		Severity:    SeverityHigh,
+		Status:      AlertActive,
+		TriggeredAt: now,
+	}
+
+	if err := s.db.Create(&alert).Error; err != nil {
+		log.Printf("Failed to create staleness alert: %v", err)
+		return
+	}
+	s.markChanged()
+
+	log.Printf("Alert created: no %s data for %s", metricType, age)
+	s.notifyTriggered(&alert)
+}
+
+// resolveStaleness resolves the active staleness alert for metricType, if any
+func (s *Service) resolveStaleness(metricType metrics.MetricType) {
+	resolved, err := resolveAlerts(s.db, s.clock.Now(), "metric_type = ? AND stale_type = ?", StalenessAlertType, metricType)
+	if err != nil {
+		log.Printf("Failed to resolve staleness alert for %s: %v", metricType, err)
+	} else if len(resolved) > 0 {
+		s.markChanged()
+		log.Printf("%s data is flowing again; staleness alert resolved", metricType)
+		s.notifyResolved(resolved)
+	}
+}
+
+// StartStalenessWatch checks types for stalled data until ctx is done. A
+// non-positive window disables the watch.
+func (s *Service) StartStalenessWatch(ctx context.Context, types []metrics.MetricType, window time.Duration) {
+	if window <= 0 || len(types) == 0 {
+		return
+	}
+
+	interval := window / 5
+	if interval > maxStalenessCheckInterval {
+		interval = maxStalenessCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	since := s.clock.Now()
+	log.Printf("Alerting when %v receive no data for %v", types, window)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := s.clock.Now()
+			// No data arrives while collection is paused; measure the
+			// window from when it resumes
+			if s.paused() {
+				since = now
+				continue
+			}
+			if err := s.CheckStaleness(types, window, since, now); err != nil {
+				log.Printf("Failed to check metric staleness: %v", err)
+			}
+		}
+	}
+}
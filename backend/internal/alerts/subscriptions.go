@@ -0,0 +1,112 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/notify"
+)
+
+// ErrSubscriptionNotFound is returned when a subscription does not exist or
+// belongs to another user
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// AlertSubscription sends a user's chosen channel the alerts for one metric
+// type, or every type when MetricType is empty, at or above MinSeverity
+type AlertSubscription struct {
+	ID          uint               `json:"id" gorm:"primaryKey"`
+	UserID      uint               `json:"user_id" gorm:"not null;index"`
+	MetricType  metrics.MetricType `json:"metric_type,omitempty" gorm:"column:metric_type;not null;default:''"`
+	MinSeverity AlertSeverity      `json:"min_severity" gorm:"not null"`
+	Channel     string             `json:"channel" gorm:"not null"`
+	Target      string             `json:"target" gorm:"not null"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// CreateSubscriptionRequest represents a request to subscribe to alerts
+type CreateSubscriptionRequest struct {
+	MetricType  metrics.MetricType `json:"metric_type"`
+	MinSeverity AlertSeverity      `json:"min_severity"`
+	Channel     string             `json:"channel" binding:"required"`
+	Target      string             `json:"target"`
+}
+
+// CreateSubscription subscribes a user to alerts. MinSeverity defaults to low.
+func (s *Service) CreateSubscription(userID uint, req *CreateSubscriptionRequest) (*AlertSubscription, error) {
+	if req.MetricType != "" {
+		if _, ok := metrics.LookupType(req.MetricType); !ok {
+			return nil, fmt.Errorf("unknown metric type %q", req.MetricType)
+		}
+	}
+
+	minSeverity := SeverityLow
+	if req.MinSeverity != "" {
+		parsed, err := notify.ParseSeverity(string(req.MinSeverity))
+		if err != nil {
+			return nil, err
+		}
+		minSeverity = AlertSeverity(parsed)
+	}
+
+	if err := notify.ValidateRecipient(req.Channel, req.Target); err != nil {
+		return nil, err
+	}
+
+	subscription := AlertSubscription{
+		UserID:      userID,
+		MetricType:  req.MetricType,
+		MinSeverity: minSeverity,
+		Channel:     req.Channel,
+		Target:      req.Target,
+	}
+
+	if err := s.db.Create(&subscription).Error; err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return &subscription, nil
+}
+
+// ListSubscriptions returns a user's subscriptions, oldest first
+func (s *Service) ListSubscriptions(userID uint) ([]AlertSubscription, error) {
+	var subscriptions []AlertSubscription
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// DeleteSubscription removes one of a user's subscriptions
+func (s *Service) DeleteSubscription(userID, subscriptionID uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", subscriptionID, userID).Delete(&AlertSubscription{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete subscription: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// Recipients returns the channels subscribed to event, satisfying
+// notify.Subscribers. Subscriptions of deleted users are ignored.
+func (s *Service) Recipients(event notify.Event) ([]notify.Recipient, error) {
+	var subscriptions []AlertSubscription
+	if err := s.db.
+		Where("metric_type = ? OR metric_type = ''", event.MetricType).
+		Where("user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)").
+		Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to find subscriptions: %w", err)
+	}
+
+	var recipients []notify.Recipient
+	for _, subscription := range subscriptions {
+		if notify.MeetsSeverity(event.Severity, string(subscription.MinSeverity)) {
+			recipients = append(recipients, notify.Recipient{Channel: subscription.Channel, Target: subscription.Target})
+		}
+	}
+	return recipients, nil
+}
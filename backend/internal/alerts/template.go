@@ -0,0 +1,147 @@
+package alerts
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+)
+
+// MessageData is the data available to alert message templates
+type MessageData struct {
+	Type metrics.MetricType
+	// DisplayName is the metric type's name for people, e.g. "CPU Usage"
+	DisplayName string
+	Mount       string
+	Value       float64
+	Threshold   float64
+	Direction   metrics.ThresholdDirection
+	Severity    AlertSeverity
+	Host        string
+	Time        time.Time
+
+	// FormattedValue and FormattedThreshold are Value and Threshold in the
+	// metric type's unit, e.g. "91.50%" or "12.30 MB/s"
+	FormattedValue     string
+	FormattedThreshold string
+}
+
+// MessageTemplates renders alert messages from text/template templates, per
+// metric type or globally
+type MessageTemplates struct {
+	global  *template.Template
+	perType map[metrics.MetricType]*template.Template
+}
+
+// ParseMessageTemplates parses and test-renders the global and per-type
+// templates so mistakes are reported at startup rather than when an alert
+// fires. Empty templates are ignored.
+func ParseMessageTemplates(global string, perType map[string]string) (*MessageTemplates, error) {
+	templates := &MessageTemplates{perType: make(map[metrics.MetricType]*template.Template)}
+
+	var err error
+	if global != "" {
+		if templates.global, err = parseMessageTemplate("global", global); err != nil {
+			return nil, err
+		}
+	}
+
+	for metricType, text := range perType {
+		if text == "" {
+			continue
+		}
+		tmpl, err := parseMessageTemplate(metricType, text)
+		if err != nil {
+			return nil, err
+		}
+		templates.perType[metrics.MetricType(metricType)] = tmpl
+	}
+
+	return templates, nil
+}
+
+// parseMessageTemplate parses one template and renders it against sample data
+func parseMessageTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s alert message template: %w", name, err)
+	}
+
+	sample := MessageData{
+		Type:        metrics.CPUUsage,
+		DisplayName: metrics.DisplayName(metrics.CPUUsage),
+		Value:       90,
+		Threshold:   80,
+		Direction:   metrics.DirectionAbove,
+		Severity:    SeverityMedium,
+		Host:        "localhost",
+		Time:        time.Now(),
+
+		FormattedValue:     metrics.FormatValue(metrics.CPUUsage, 90),
+		FormattedThreshold: metrics.FormatValue(metrics.CPUUsage, 80),
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, fmt.Errorf("invalid %s alert message template: %w", name, err)
+	}
+
+	return tmpl, nil
+}
+
+// lookup returns the template for metricType, falling back to the global one
+func (t *MessageTemplates) lookup(metricType metrics.MetricType) *template.Template {
+	if t == nil {
+		return nil
+	}
+	if tmpl, ok := t.perType[metricType]; ok {
+		return tmpl
+	}
+	return t.global
+}
+
+// SetMessageTemplates sets the templates used for new alert messages
+func (s *Service) SetMessageTemplates(templates *MessageTemplates) {
+	s.templates = templates
+}
+
+// renderAlertMessage renders the configured template for alert, returning
+// false when no template applies or rendering fails
+func (s *Service) renderAlertMessage(alert *Alert) (string, bool) {
+	tmpl := s.templates.lookup(alert.Type)
+	if tmpl == nil {
+		return "", false
+	}
+
+	data := MessageData{
+		Type:        alert.Type,
+		DisplayName: metrics.DisplayName(alert.Type),
+		Mount:       alert.Mount,
+		Value:       alert.Value,
+		Threshold:   alert.Threshold,
+		Direction:   alert.Direction,
+		Severity:    alert.Severity,
+		Host:        hostname(),
+		Time:        alert.TriggeredAt,
+	}
+	data.FormattedValue, data.FormattedThreshold = formatAlertValues(alert)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Printf("Failed to render alert message template for %s: %v", alert.Type, err)
+		return "", false
+	}
+
+	return buf.String(), true
+}
+
+// hostname returns the machine's hostname, or "unknown" if it cannot be read
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
@@ -0,0 +1,110 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"gorm.io/gorm"
+)
+
+// ErrThresholdNotFound is returned when no threshold exists for a metric type
+var ErrThresholdNotFound = errors.New("threshold not found")
+
+// UpdateThresholdRequest represents a request to enable or disable a
+// threshold or change its direction or resolve margin; at least one field
+// must be set
+type UpdateThresholdRequest struct {
+	Enabled       *bool    `json:"enabled"`
+	Direction     *string  `json:"direction"`
+	ResolveMargin *float64 `json:"resolve_margin"`
+}
+
+// ThresholdUpdate is the result of updating a threshold
+type ThresholdUpdate struct {
+	Threshold      metrics.MetricThreshold `json:"threshold"`
+	ResolvedAlerts int64                   `json:"resolved_alerts"`
+}
+
+// ListThresholds returns all metric thresholds, enabled or not
+func (s *Service) ListThresholds() ([]metrics.MetricThreshold, error) {
+	var thresholds []metrics.MetricThreshold
+	if err := s.db.Order("metric_type").Find(&thresholds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get thresholds: %w", err)
+	}
+	return thresholds, nil
+}
+
+// UpdateThreshold enables or disables the threshold for a metric type without
+// deleting it, or changes whether values above or below it breach. Disabling
+// or changing direction also resolves the type's active alerts, on every
+// mount, since they no longer describe a breach that checks would resolve.
+func (s *Service) UpdateThreshold(metricType metrics.MetricType, req *UpdateThresholdRequest) (*ThresholdUpdate, error) {
+	if req.Enabled == nil && req.Direction == nil && req.ResolveMargin == nil {
+		return nil, fmt.Errorf("enabled, direction or resolve_margin is required")
+	}
+
+	updates := make(map[string]interface{})
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if req.Direction != nil {
+		direction, err := metrics.ParseThresholdDirection(*req.Direction)
+		if err != nil {
+			return nil, err
+		}
+		updates["direction"] = direction
+	}
+	if req.ResolveMargin != nil {
+		if *req.ResolveMargin < 0 {
+			return nil, fmt.Errorf("resolve_margin must not be negative")
+		}
+		updates["resolve_margin"] = *req.ResolveMargin
+	}
+
+	update := &ThresholdUpdate{}
+	var resolved []Alert
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("metric_type = ?", metricType).First(&update.Threshold).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrThresholdNotFound
+			}
+			return err
+		}
+		previous := update.Threshold
+
+		if err := tx.Model(&update.Threshold).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		disabled := previous.Enabled && !update.Threshold.Enabled
+		if !disabled && previous.Direction == update.Threshold.Direction {
+			return nil
+		}
+
+		var err error
+		now := s.clock.Now()
+		resolved, err = resolveAlerts(tx, now, "metric_type = ?", metricType)
+		if err != nil {
+			return err
+		}
+		update.ResolvedAlerts = int64(len(resolved))
+		return closeResolvedIncidents(tx, now)
+	})
+	if err != nil {
+		if errors.Is(err, ErrThresholdNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update threshold: %w", err)
+	}
+
+	if update.ResolvedAlerts > 0 {
+		s.markChanged()
+		log.Printf("Resolved %d alerts for updated %s threshold", update.ResolvedAlerts, metricType)
+		// Only notified once the transaction has committed
+		s.notifyResolved(resolved)
+	}
+
+	return update, nil
+}
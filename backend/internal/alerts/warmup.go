@@ -0,0 +1,44 @@
+package alerts
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// warmupReason labels breaches recorded during the startup warmup
+const warmupReason = "startup warmup"
+
+// SetWarmup holds back threshold alerts for period from now, so misleading
+// samples taken while the server starts up do not raise alerts. Breaches in
+// that time are recorded as suppressed, as during maintenance. A
+// non-positive period disables the warmup.
+func (s *Service) SetWarmup(period time.Duration) {
+	if period <= 0 {
+		s.warmupStart, s.warmupEnd = time.Time{}, time.Time{}
+		return
+	}
+
+	now := s.clock.Now()
+	s.warmupStart, s.warmupEnd = now, now.Add(period)
+	time.AfterFunc(period, func() {
+		log.Printf("Startup warmup of %v ended; alerting resumed", period)
+	})
+}
+
+// warmupWindow returns a stand-in maintenance window for the warmup if at
+// falls within it, or nil
+func (s *Service) warmupWindow(at time.Time) *MaintenanceWindow {
+	if s.warmupEnd.IsZero() || !at.Before(s.warmupEnd) {
+		return nil
+	}
+	return &MaintenanceWindow{StartsAt: s.warmupStart, EndsAt: s.warmupEnd, Reason: warmupReason}
+}
+
+// suppressedBy describes what window suppressed an alert, for logging
+func (w *MaintenanceWindow) suppressedBy() string {
+	if w.ID == 0 {
+		return w.Reason
+	}
+	return fmt.Sprintf("maintenance window %d", w.ID)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/alerts"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/audit"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// DiagnosticsAlertLimit is how many of the most recent alerts a diagnostics
+// bundle includes
+const DiagnosticsAlertLimit = 100
+
+// SetDiagnosticsConfig includes cfg, with its secrets redacted, in
+// diagnostics bundles; without it the bundle has no config.json
+func (h *Handlers) SetDiagnosticsConfig(cfg *config.Config) {
+	if cfg == nil {
+		h.diagnosticsConfig = nil
+		return
+	}
+	h.diagnosticsConfig = cfg.Redacted()
+}
+
+// diagnosticsFile is one JSON file of a diagnostics bundle
+type diagnosticsFile struct {
+	name    string
+	content interface{}
+}
+
+// GetDiagnostics streams a zip of what is needed to debug a deployment: the
+// current metrics, recent alerts, the redacted config, collector status and
+// host info. A part that cannot be gathered is listed in errors.json and the
+// rest of the bundle is still sent.
+func (h *Handlers) GetDiagnostics(c *gin.Context) {
+	generatedAt := time.Now().UTC()
+	failures := make(map[string]string)
+
+	files := []diagnosticsFile{
+		{name: "metrics.json", content: h.metricsCollector.Snapshot(c.Request.Context())},
+	}
+
+	recentAlerts, err := h.alertService.GetAlerts("", DiagnosticsAlertLimit, alerts.DefaultAlertSort)
+	if err != nil {
+		failures["alerts.json"] = err.Error()
+	} else {
+		files = append(files, diagnosticsFile{name: "alerts.json", content: recentAlerts})
+	}
+
+	if h.diagnosticsConfig != nil {
+		files = append(files, diagnosticsFile{name: "config.json", content: h.diagnosticsConfig})
+	}
+
+	status := gin.H{
+		"collector":       h.metricsCollector.Stats(),
+		"alert_retention": h.alertService.RetentionStatus(),
+	}
+	if h.notifier != nil {
+		status["notifications"] = h.notifier.Stats()
+	}
+	files = append(files, diagnosticsFile{name: "collector.json", content: status})
+
+	hostInfo := gin.H{
+		"generated_at": generatedAt,
+		"go_version":   runtime.Version(),
+		"os":           runtime.GOOS,
+		"arch":         runtime.GOARCH,
+		"num_cpu":      runtime.NumCPU(),
+		"goroutines":   runtime.NumGoroutine(),
+	}
+	if name, err := os.Hostname(); err == nil {
+		hostInfo["hostname"] = name
+	}
+	if info, err := host.InfoWithContext(c.Request.Context()); err != nil {
+		failures["host.json"] = fmt.Sprintf("failed to get host info: %v", err)
+	} else {
+		hostInfo["host"] = info
+	}
+	files = append(files, diagnosticsFile{name: "host.json", content: hostInfo})
+
+	if len(failures) > 0 {
+		files = append(files, diagnosticsFile{name: "errors.json", content: failures})
+	}
+
+	h.recordAudit(c, audit.ActionAdminDiagnostics, "diagnostics", map[string]interface{}{
+		"files":  len(files),
+		"errors": len(failures),
+	})
+
+	filename := fmt.Sprintf("codexray-diagnostics-%s.zip", generatedAt.Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// Headers are sent by now, so a failure can only cut the download short
+	archive := zip.NewWriter(c.Writer)
+	for _, file := range files {
+		if err := writeDiagnosticsFile(archive, file, generatedAt); err != nil {
+			log.Printf("Failed to write diagnostics bundle: %v", err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("Failed to write diagnostics bundle: %v", err)
+	}
+}
+
+// writeDiagnosticsFile adds file to archive as indented JSON
+func writeDiagnosticsFile(archive *zip.Writer, file diagnosticsFile, modified time.Time) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     file.name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", file.name, err)
+	}
+
+	// The bundle is read by people, not browsers, so URLs keep their '&'
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file.content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file.name, err)
+	}
+	return nil
+}
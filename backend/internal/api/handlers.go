@@ -1,13 +1,25 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/alerts"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/audit"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/auth"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/idempotency"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/integrations"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/logs"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/notify"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,6 +29,40 @@ type Handlers struct {
 	logAnalyzer      *logs.LogAnalyzer
 	metricsCollector *metrics.Collector
 	alertService     *alerts.Service
+	auditService     *audit.Service
+
+	// allowReset enables ResetData; see ALLOW_ADMIN_RESET
+	allowReset bool
+
+	// idempotency stores responses for retried POSTs; nil disables it
+	idempotency *idempotency.Store
+
+	// summaryCache serves repeated GetSummary calls; nil disables it
+	summaryCache *summaryCache
+
+	// notifier is reported on the health check when set
+	notifier *notify.Dispatcher
+
+	// migrator re-runs migrations for RunMigrations; nil disables it
+	migrator Migrator
+	// integrity runs data integrity checks for CheckIntegrity
+	integrity IntegrityChecker
+
+	// diagnosticsConfig is the redacted config included in GetDiagnostics
+	diagnosticsConfig *config.Config
+
+	// streams counts open streaming responses against their cap
+	streams *streamLimiter
+}
+
+// Migrator re-runs database migrations; satisfied by storage.Database
+type Migrator interface {
+	Migrate() (*storage.MigrationReport, error)
+}
+
+// IntegrityChecker finds and optionally fixes invalid rows; satisfied by storage.Database
+type IntegrityChecker interface {
+	VerifyDataIntegrity(fix bool) (*storage.IntegrityReport, error)
 }
 
 // NewHandlers creates a new handlers instance
@@ -25,26 +71,79 @@ func NewHandlers(
 	logAnalyzer *logs.LogAnalyzer,
 	metricsCollector *metrics.Collector,
 	alertService *alerts.Service,
+	auditService *audit.Service,
 ) *Handlers {
 	return &Handlers{
 		authService:      authService,
 		logAnalyzer:      logAnalyzer,
 		metricsCollector: metricsCollector,
 		alertService:     alertService,
+		auditService:     auditService,
+		streams:          &streamLimiter{},
+	}
+}
+
+// SetIdempotencyStore enables Idempotency-Key support on the routes that use it
+func (h *Handlers) SetIdempotencyStore(store *idempotency.Store) {
+	h.idempotency = store
+}
+
+// SetSummaryCacheTTL caches summaries for ttl; a non-positive ttl disables the cache
+func (h *Handlers) SetSummaryCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		h.summaryCache = nil
+		return
+	}
+	h.summaryCache = newSummaryCache(ttl)
+}
+
+// SetNotifier reports the notification queue on the health check
+func (h *Handlers) SetNotifier(notifier *notify.Dispatcher) {
+	h.notifier = notifier
+}
+
+// SetMigrator enables the admin migrate endpoint; see ALLOW_ADMIN_MIGRATE
+func (h *Handlers) SetMigrator(migrator Migrator) {
+	h.migrator = migrator
+}
+
+// SetIntegrityChecker enables the admin integrity check endpoint
+func (h *Handlers) SetIntegrityChecker(checker IntegrityChecker) {
+	h.integrity = checker
+}
+
+// SetAllowAdminReset enables or disables the admin reset endpoint
+func (h *Handlers) SetAllowAdminReset(allow bool) {
+	h.allowReset = allow
+}
+
+// recordAudit writes an audit entry for the authenticated user, if auditing is enabled
+func (h *Handlers) recordAudit(c *gin.Context, action, target string, details map[string]interface{}) {
+	if h.auditService == nil {
+		return
 	}
+	userID, _ := UserIDFromContext(c)
+	h.auditService.Record(userID, action, target, details)
 }
 
 
 // Register handles user registration
 func (h *Handlers) Register(c *gin.Context) {
 	var req auth.RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	user, err := h.authService.Register(&req)
 	if err != nil {
+		var policyErr *auth.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  errValidationFailed,
+				"fields": map[string]string{"password": "must contain " + strings.Join(policyErr.Failures, ", ")},
+			})
+			return
+		}
 		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
@@ -58,13 +157,16 @@ func (h *Handlers) Register(c *gin.Context) {
 // Login handles user authentication
 func (h *Handlers) Login(c *gin.Context) {
 	var req auth.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	authResponse, err := h.authService.Login(&req)
 	if err != nil {
+		if errors.Is(err, auth.ErrEmailNotVerified) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
@@ -72,6 +174,46 @@ func (h *Handlers) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, authResponse)
 }
 
+// VerifyEmail confirms a user's email address from a verification link
+func (h *Handlers) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token parameter is required"})
+		return
+	}
+
+	user, err := h.authService.VerifyEmail(token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+		"user":    user,
+	})
+}
+
+// ResendVerification sends a new verification email
+func (h *Handlers) ResendVerification(c *gin.Context) {
+	var req auth.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ResendVerification(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists and is unverified, a verification email has been sent"})
+}
+
 // ValidateToken handles JWT token validation
 func (h *Handlers) ValidateToken(c *gin.Context) {
 	var req auth.ValidateTokenRequest
@@ -115,192 +257,1991 @@ func (h *Handlers) RefreshToken(c *gin.Context) {
 	})
 }
 
-// Logout handles user logout (JWT is stateless, so this is just a success response)
-func (h *Handlers) Logout(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
+// Me returns the authenticated user
+func (h *Handlers) Me(c *gin.Context) {
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	user, err := h.authService.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Current user retrieved",
+		"user":    user,
+	})
 }
 
-// Log Analysis Handlers
+// ChangePassword changes the authenticated user's password
+func (h *Handlers) ChangePassword(c *gin.Context) {
+	var req auth.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-// AnalyzeLogs handles log file analysis
-func (h *Handlers) AnalyzeLogs(c *gin.Context) {
-	filePath := c.Query("file")
-	if filePath == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "file parameter is required"})
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	if err := h.authService.ChangePassword(userID, &req); err != nil {
+		var policyErr *auth.PasswordPolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrInvalidCurrentPassword):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.recordAudit(c, audit.ActionPasswordChange, fmt.Sprintf("user:%d", userID), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// CreateAPIKey creates an API key for the authenticated user
+func (h *Handlers) CreateAPIKey(c *gin.Context) {
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var req auth.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	stats, err := h.logAnalyzer.ParseLogFile(filePath)
+	key, rawKey, err := h.authService.CreateAPIKey(userID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Log analysis completed",
-		"stats":   stats,
+	h.recordAudit(c, audit.ActionAPIKeyCreate, fmt.Sprintf("api_key:%d", key.ID), map[string]interface{}{
+		"name":   key.Name,
+		"scopes": key.Scopes,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created; store it now, it will not be shown again",
+		"key":     rawKey,
+		"api_key": key,
 	})
 }
 
-// Metrics Handlers
+// ListAPIKeys lists the authenticated user's API keys
+func (h *Handlers) ListAPIKeys(c *gin.Context) {
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
 
-// GetCurrentMetrics returns current system metrics
-func (h *Handlers) GetCurrentMetrics(c *gin.Context) {
-	metrics, err := h.metricsCollector.GetCurrentMetrics()
+	keys, err := h.authService.ListAPIKeys(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Current metrics retrieved",
-		"metrics": metrics,
+		"message":  "API keys retrieved",
+		"api_keys": keys,
 	})
 }
 
-// GetMetricHistory returns historical metrics
-func (h *Handlers) GetMetricHistory(c *gin.Context) {
-	metricType := c.Param("type")
-	if metricType == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "metric type is required"})
+// RevokeAPIKey revokes one of the authenticated user's API keys
+func (h *Handlers) RevokeAPIKey(c *gin.Context) {
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
 		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "100")
-	limit, err := strconv.Atoi(limitStr)
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
 		return
 	}
 
-	history, err := h.metricsCollector.GetMetricHistory(metrics.MetricType(metricType), limit)
-	if err != nil {
+	if err := h.authService.RevokeAPIKey(userID, uint(keyID)); err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Metric history retrieved",
-		"history": history,
-	})
+	h.recordAudit(c, audit.ActionAPIKeyRevoke, fmt.Sprintf("api_key:%d", keyID), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
 }
 
-// Alert Handlers
+// DeleteUser soft-deletes a user account (admin only)
+func (h *Handlers) DeleteUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
 
-// GetAlerts returns alerts with optional filtering
-func (h *Handlers) GetAlerts(c *gin.Context) {
-	status := c.Query("status")
-	limitStr := c.DefaultQuery("limit", "50")
+	if currentID, ok := UserIDFromContext(c); ok && currentID == uint(userID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot delete your own account"})
+		return
+	}
 
-	limit, err := strconv.Atoi(limitStr)
+	if err := h.authService.DeleteUser(uint(userID)); err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionUserDelete, fmt.Sprintf("user:%d", userID), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// RestoreUser recovers a soft-deleted user account (admin only)
+func (h *Handlers) RestoreUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
 		return
 	}
 
-	alertsList, err := h.alertService.GetAlerts(alerts.AlertStatus(status), limit)
+	user, err := h.authService.RestoreUser(uint(userID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, auth.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrUserNotDeleted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
+	h.recordAudit(c, audit.ActionUserRestore, fmt.Sprintf("user:%d", userID), nil)
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Alerts retrieved",
-		"alerts":  alertsList,
+		"message": "User restored",
+		"user":    user,
 	})
 }
 
-// CreateAlert manually creates an alert (for testing)
-func (h *Handlers) CreateAlert(c *gin.Context) {
-	var req alerts.CreateAlertRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+// ImportUsers creates users in bulk from a JSON array, reporting the outcome
+// of each entry. Nothing is created if any entry is invalid.
+func (h *Handlers) ImportUsers(c *gin.Context) {
+	var entries []auth.ImportUserRequest
+	if err := c.ShouldBindJSON(&entries); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	alert, err := h.alertService.CreateAlert(&req)
+	results, err := h.authService.ImportUsers(entries)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, auth.ErrImportInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "results": results})
+		case errors.Is(err, auth.ErrEmptyImport), errors.Is(err, auth.ErrImportTooLarge):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Alert created",
-		"alert":   alert,
+	counts := map[string]int{auth.ImportCreated: 0, auth.ImportSkipped: 0}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	h.recordAudit(c, audit.ActionUserImport, "users", map[string]interface{}{
+		"created": counts[auth.ImportCreated],
+		"skipped": counts[auth.ImportSkipped],
+	})
+
+	code := http.StatusOK
+	if counts[auth.ImportCreated] > 0 {
+		code = http.StatusCreated
+	}
+	c.JSON(code, gin.H{
+		"message": "Users imported",
+		"created": counts[auth.ImportCreated],
+		"skipped": counts[auth.ImportSkipped],
+		"results": results,
 	})
 }
 
-// ResolveAlert resolves an alert
-func (h *Handlers) ResolveAlert(c *gin.Context) {
-	alertIDStr := c.Param("id")
-	alertID, err := strconv.ParseUint(alertIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alert ID"})
+// Logout handles user logout (JWT is stateless, so this is just a success response)
+func (h *Handlers) Logout(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
+}
+
+// Log Analysis Handlers
+
+// AnalyzeLogs handles log file analysis
+func (h *Handlers) AnalyzeLogs(c *gin.Context) {
+	if c.Query("alert") == "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "alerting on the error rate requires POST /logs/analyze"})
 		return
 	}
+	h.analyzeLogFile(c, false)
+}
 
-	if err := h.alertService.ResolveAlert(uint(alertID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// AlertOnLogErrorRate analyzes a log file like AnalyzeLogs, raising an alert
+// when its error ratio exceeds the threshold and resolving the file's alert
+// once a later analysis is back under it
+func (h *Handlers) AlertOnLogErrorRate(c *gin.Context) {
+	h.analyzeLogFile(c, true)
+}
+
+// analyzeLogFile analyzes the file named by the file query parameter,
+// checking its error rate against the alert threshold when alertOnErrorRate
+// is set
+func (h *Handlers) analyzeLogFile(c *gin.Context, alertOnErrorRate bool) {
+	filePath := c.Query("file")
+	if filePath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file parameter is required"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Alert resolved"})
-}
+	errorRateThreshold := logs.DefaultErrorRateThreshold
+	if thresholdStr := c.Query("error_rate_threshold"); thresholdStr != "" {
+		threshold, err := strconv.ParseFloat(thresholdStr, 64)
+		if err != nil || threshold <= 0 || threshold >= 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "error_rate_threshold must be a ratio between 0 and 1"})
+			return
+		}
+		errorRateThreshold = threshold
+	}
 
-// Summary Handler
+	analyzer := h.logAnalyzer
+	if mappingStr := c.Query("level_map"); mappingStr != "" {
+		mapping, err := logs.ParseLevelMapping(mappingStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		analyzer = analyzer.WithLevelMapping(mapping)
+	}
+	if layout := c.Query("time_layout"); layout != "" {
+		if err := logs.ValidateTimeLayout(layout); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		analyzer = analyzer.WithTimeLayout(layout)
+	}
+	if levelsStr := c.Query("levels"); levelsStr != "" {
+		// The error rate of a filtered analysis is not the file's error rate
+		if alertOnErrorRate {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "alert cannot be combined with levels"})
+			return
+		}
+		levels, err := logs.ParseLevels(levelsStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		analyzer = analyzer.WithLevels(levels)
+	}
 
-// GetSummary returns comprehensive system summary
-func (h *Handlers) GetSummary(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		limit = 10
+	var stats *logs.LogStats
+	if c.Query("parallel") == "true" {
+		workers, err := strconv.Atoi(c.DefaultQuery("workers", "0"))
+		if err != nil || workers < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workers parameter"})
+			return
+		}
+		stats, err = analyzer.ParseLogFileParallel(filePath, workers)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		var err error
+		stats, err = analyzer.ParseLogFile(filePath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	// Get current metrics
-	currentMetrics, err := h.metricsCollector.GetCurrentMetrics()
+	response := gin.H{
+		"message":    "Log analysis completed",
+		"stats":      stats,
+		"error_rate": stats.ErrorRate(),
+	}
+
+	if alertOnErrorRate {
+		alert, err := h.alertService.CheckLogErrorRate(filePath, stats.ErrorRate(), errorRateThreshold)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if alert != nil {
+			response["alert"] = alert
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AnalyzeLogDirectory aggregates log statistics across the files in a directory
+func (h *Handlers) AnalyzeLogDirectory(c *gin.Context) {
+	dir := c.Query("dir")
+	if dir == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dir parameter is required"})
+		return
+	}
+
+	pattern := c.DefaultQuery("pattern", "*.log")
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get current metrics"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pattern parameter"})
+		return
+	}
+	if len(paths) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no log files matched"})
 		return
 	}
 
-	// Get alert summary
-	alertSummary, err := h.alertService.GetAlertSummary(limit)
+	analyzer := h.logAnalyzer
+	if layout := c.Query("time_layout"); layout != "" {
+		if err := logs.ValidateTimeLayout(layout); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		analyzer = analyzer.WithTimeLayout(layout)
+	}
+	if levelsStr := c.Query("levels"); levelsStr != "" {
+		levels, err := logs.ParseLevels(levelsStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		analyzer = analyzer.WithLevels(levels)
+	}
+
+	stats, err := analyzer.ParseLogFiles(paths)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get alert summary"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "stats": stats})
 		return
 	}
 
-	// Get metric summaries for last 10 readings
-	cpuSummary, err := h.metricsCollector.GetMetricSummary(metrics.CPUUsage, 10)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Log analysis completed",
+		"stats":   stats,
+	})
+}
+
+// Metrics Handlers
+
+// GetCurrentMetrics returns current system metrics
+func (h *Handlers) GetCurrentMetrics(c *gin.Context) {
+	precision, err := valuePrecision(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get CPU summary"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	memorySummary, err := h.metricsCollector.GetMetricSummary(metrics.MemoryUsage, 10)
+	metrics, err := h.metricsCollector.GetCurrentMetrics()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get memory summary"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Summary retrieved",
-		"summary": gin.H{
-			"current_metrics": currentMetrics,
-			"alerts":          alertSummary,
-			"metric_averages": gin.H{
-				"cpu":    cpuSummary,
-				"memory": memorySummary,
-			},
-		},
+		"message": "Current metrics retrieved",
+		"metrics": metrics.Rounded(precision),
 	})
 }
 
-// Health check handler
-func (h *Handlers) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"message": "CodeXray Observability Service is running",
-	})
+// GetMetricHistory returns historical metrics
+func (h *Handlers) GetMetricHistory(c *gin.Context) {
+	metricType := c.Param("type")
+	if metricType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metric type is required"})
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "100")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	agg, err := metrics.ParseAggregation(c.Query("agg"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	converter, err := unitConverter(c, metrics.MetricType(metricType))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	precision, err := valuePrecision(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	selector, err := labelSelector(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Optional exponential moving average; without it, or with raw=true, the
+	// stored series is returned
+	var smooth float64
+	if smoothStr := c.Query("smooth"); smoothStr != "" && c.Query("raw") != "true" {
+		if smooth, err = metrics.ParseSmoothing(smoothStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Streamed rows are written as they are read, so nothing that needs the
+	// whole series can apply
+	stream := c.Query("stream") == "true"
+	if stream && (c.Query("bucket") != "" || smooth > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stream cannot be combined with bucket or smooth"})
+		return
+	}
+
+	annotationTag := c.Query("annotation_tag")
+	if annotationTag != "" {
+		if err := metrics.ValidateAnnotationTag(annotationTag); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Pages are of raw metrics only
+	page, err := historyPage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (page.Cursor != nil || page.Offset > 0) && (c.Query("bucket") != "" || stream) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor and offset cannot be combined with bucket or stream"})
+		return
+	}
+
+	if bucketStr := c.Query("bucket"); bucketStr != "" {
+		bucket, err := time.ParseDuration(bucketStr)
+		if err != nil || bucket <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bucket parameter"})
+			return
+		}
+
+		history, err := h.metricsCollector.GetMetricHistoryAggregated(metrics.MetricType(metricType), c.Query("mount"), bucket, agg, limit, selector)
+		if errors.Is(err, metrics.ErrMountRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if converter != nil {
+			converter.ConvertAggregated(history)
+		}
+		if smooth > 0 {
+			metrics.SmoothAggregated(history, smooth)
+		}
+		metrics.RoundAggregated(history, precision)
+
+		timestamps := make([]time.Time, len(history))
+		for i, point := range history {
+			timestamps[i] = point.Timestamp
+		}
+		annotations, err := h.seriesAnnotations(timestamps, bucket, annotationTag)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		response := gin.H{
+			"message":     "Metric history retrieved",
+			"bucket":      bucket.String(),
+			"agg":         agg,
+			"history":     history,
+			"annotations": annotations,
+		}
+		if smooth > 0 {
+			response["smooth"] = smooth
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if c.Query("agg") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "agg parameter requires bucket"})
+		return
+	}
+
+	if stream {
+		h.streamMetricHistory(c, metrics.MetricType(metricType), limit, selector, converter, precision)
+		return
+	}
+
+	history, next, err := h.metricsCollector.GetMetricHistoryPage(metrics.MetricType(metricType), limit, selector, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if converter != nil {
+		converter.ConvertMetrics(history)
+	}
+	if smooth > 0 {
+		metrics.SmoothMetrics(history, smooth)
+	}
+	metrics.RoundMetrics(history, precision)
+
+	timestamps := make([]time.Time, len(history))
+	for i, metric := range history {
+		timestamps[i] = metric.Timestamp
+	}
+	annotations, err := h.seriesAnnotations(timestamps, 0, annotationTag)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message":     "Metric history retrieved",
+		"history":     history,
+		"annotations": annotations,
+	}
+	if next != nil {
+		response["next_cursor"] = next.Encode()
+	}
+	if smooth > 0 {
+		response["smooth"] = smooth
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// seriesAnnotations returns the annotations from the earliest to the latest
+// of timestamps, plus span to cover the last bucket of an aggregated series,
+// carrying tag if it is set. An empty series has no annotations.
+func (h *Handlers) seriesAnnotations(timestamps []time.Time, span time.Duration, tag string) ([]metrics.Annotation, error) {
+	if len(timestamps) == 0 {
+		return []metrics.Annotation{}, nil
+	}
+
+	from, to := timestamps[0], timestamps[0]
+	for _, timestamp := range timestamps[1:] {
+		if timestamp.Before(from) {
+			from = timestamp
+		}
+		if timestamp.After(to) {
+			to = timestamp
+		}
+	}
+
+	return h.metricsCollector.GetAnnotations(metrics.AnnotationFilter{From: from, To: to.Add(span), Tag: tag})
+}
+
+// GetMetricHistories returns the latest history of several metric types in
+// one response. The per-type limit is reduced so the total stays within
+// metrics.MaxHistoryRows.
+func (h *Handlers) GetMetricHistories(c *gin.Context) {
+	var names []string
+	if typesStr := c.Query("types"); typesStr != "" {
+		names = strings.Split(typesStr, ",")
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+	}
+
+	types, err := metrics.ParseHistoryTypes(names)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+	if maxLimit := metrics.MaxHistoryRows / len(types); limit > maxLimit {
+		limit = maxLimit
+	}
+
+	precision, err := valuePrecision(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	histories, err := h.metricsCollector.GetMetricHistories(types, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, history := range histories {
+		metrics.RoundMetrics(history, precision)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Metric histories retrieved",
+		"limit":   limit,
+		"history": histories,
+	})
+}
+
+// GetMetricTypes lists the registered metric types with their display
+// names, units and valid ranges, including types added by configuration
+func (h *Handlers) GetMetricTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Metric types retrieved",
+		"types":   metrics.RegisteredTypes(),
+	})
+}
+
+// GetCollectorStatus reports whether the metrics collector is running and
+// collecting, responding 503 when it is unhealthy
+func (h *Handlers) GetCollectorStatus(c *gin.Context) {
+	stats := h.metricsCollector.Stats()
+
+	code, status := http.StatusOK, "healthy"
+	if !stats.Healthy {
+		code, status = http.StatusServiceUnavailable, "unhealthy"
+	} else if stats.Paused {
+		status = "paused"
+	}
+
+	c.JSON(code, gin.H{
+		"message":         "Collector status retrieved",
+		"status":          status,
+		"collector":       stats,
+		"alert_retention": h.alertService.RetentionStatus(),
+		"streaming":       h.streams.status(),
+	})
+}
+
+// PauseCollector stops metric collection, and with it threshold alerting,
+// until ResumeCollector is called
+func (h *Handlers) PauseCollector(c *gin.Context) {
+	message := "Metric collection already paused"
+	if h.metricsCollector.Pause() {
+		message = "Metric collection paused"
+		h.recordAudit(c, audit.ActionCollectorPause, "collector", nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   message,
+		"collector": h.metricsCollector.Stats(),
+	})
+}
+
+// ResumeCollector restarts metric collection after PauseCollector
+func (h *Handlers) ResumeCollector(c *gin.Context) {
+	message := "Metric collection already running"
+	if h.metricsCollector.Resume() {
+		message = "Metric collection resumed"
+		h.recordAudit(c, audit.ActionCollectorResume, "collector", nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   message,
+		"collector": h.metricsCollector.Stats(),
+	})
+}
+
+// GetSystemSnapshot returns every current system measurement in one object
+func (h *Handlers) GetSystemSnapshot(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "System snapshot retrieved",
+		"snapshot": h.metricsCollector.Snapshot(c.Request.Context()),
+	})
+}
+
+// GetCPUModes returns the latest share of CPU time per mode
+func (h *Handlers) GetCPUModes(c *gin.Context) {
+	precision, err := valuePrecision(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := h.metricsCollector.GetCPUModes()
+	if err != nil {
+		if errors.Is(err, metrics.ErrCPUModesDisabled) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if breakdown == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no CPU mode breakdown collected yet"})
+		return
+	}
+
+	for mode, share := range breakdown.Modes {
+		breakdown.Modes[mode] = metrics.Round(share, precision)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "CPU modes retrieved",
+		"modes":     breakdown.Modes,
+		"timestamp": breakdown.Timestamp,
+	})
+}
+
+// GetGrafanaDashboard returns a Grafana dashboard for the registered metric
+// types, unwrapped so the response can be imported as is
+func (h *Handlers) GetGrafanaDashboard(c *gin.Context) {
+	c.JSON(http.StatusOK, integrations.NewGrafanaDashboard())
+}
+
+// GetBreaches returns the metrics currently over their enabled thresholds,
+// without creating or resolving alerts
+func (h *Handlers) GetBreaches(c *gin.Context) {
+	current, err := h.metricsCollector.GetCurrentMetrics()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	breaches, err := h.alertService.CurrentBreaches(current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Threshold breaches retrieved",
+		"breaches":  breaches,
+		"timestamp": current.Timestamp,
+	})
+}
+
+// GetMetricHistogram returns the distribution of a metric's values over a time range
+func (h *Handlers) GetMetricHistogram(c *gin.Context) {
+	metricType := metrics.MetricType(c.Param("type"))
+
+	to := time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339"})
+			return
+		}
+		to = parsed
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339"})
+			return
+		}
+		from = parsed
+	}
+
+	buckets, err := strconv.Atoi(c.DefaultQuery("buckets", "10"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid buckets parameter"})
+		return
+	}
+
+	histogram, err := h.metricsCollector.GetMetricHistogram(metricType, from, to, buckets)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Metric histogram retrieved",
+		"histogram": histogram,
+	})
+}
+
+// GetMetricGaps reports stretches with no samples of a metric type, where
+// the collector was down or not collecting it
+func (h *Handlers) GetMetricGaps(c *gin.Context) {
+	metricType := metrics.MetricType(c.Param("type"))
+	if _, ok := metrics.LookupType(metricType); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown metric type %q", metricType)})
+		return
+	}
+
+	to := time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339"})
+			return
+		}
+		to = parsed
+	}
+
+	from := to.Add(-24 * time.Hour)
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339"})
+			return
+		}
+		from = parsed
+	}
+
+	interval := h.metricsCollector.Interval()
+	if intervalStr := c.Query("interval"); intervalStr != "" {
+		parsed, err := time.ParseDuration(intervalStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid interval parameter"})
+			return
+		}
+		interval = parsed
+	}
+
+	gaps, err := h.metricsCollector.GetMetricGaps(metricType, from, to, interval)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Metric gaps retrieved",
+		"type":     metricType,
+		"from":     from,
+		"to":       to,
+		"interval": interval.String(),
+		"gaps":     gaps,
+	})
+}
+
+// GetFleetMetrics aggregates a metric type across hosts per time bucket
+func (h *Handlers) GetFleetMetrics(c *gin.Context) {
+	metricType := metrics.MetricType(c.Param("type"))
+	if _, ok := metrics.LookupType(metricType); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown metric type %q", metricType)})
+		return
+	}
+
+	agg, err := metrics.ParseAggregation(c.Query("agg"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	to := time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339"})
+			return
+		}
+		to = parsed
+	}
+
+	from := to.Add(-time.Hour)
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339"})
+			return
+		}
+		from = parsed
+	}
+
+	bucket, err := time.ParseDuration(c.DefaultQuery("bucket", "1m"))
+	if err != nil || bucket <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bucket parameter"})
+		return
+	}
+
+	precision, err := valuePrecision(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fleet, err := h.metricsCollector.GetFleetAggregate(metricType, agg, from, to, bucket)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fleet.Round(precision)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Fleet metrics retrieved",
+		"fleet":   fleet,
+	})
+}
+
+// GetLatestMetric returns the most recently stored sample of a metric type,
+// responding 404 when there is none yet
+func (h *Handlers) GetLatestMetric(c *gin.Context) {
+	metricType := metrics.MetricType(c.Param("type"))
+	if _, ok := metrics.LookupType(metricType); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown metric type %q", metricType)})
+		return
+	}
+
+	converter, err := unitConverter(c, metricType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	precision, err := valuePrecision(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	latest, err := h.metricsCollector.GetLatestMetric(metricType)
+	if err != nil {
+		if errors.Is(err, metrics.ErrNoMetricData) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := []metrics.Metric{*latest}
+	if converter != nil {
+		converter.ConvertMetrics(result)
+	}
+	metrics.RoundMetrics(result, precision)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Latest metric retrieved",
+		"metric":  result[0],
+	})
+}
+
+// GetRecentMetrics returns the latest samples for a metric type from memory
+func (h *Handlers) GetRecentMetrics(c *gin.Context) {
+	metricType := metrics.MetricType(c.Param("type"))
+
+	n, err := strconv.Atoi(c.DefaultQuery("n", "60"))
+	if err != nil || n < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid n parameter"})
+		return
+	}
+
+	converter, err := unitConverter(c, metricType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	precision, err := valuePrecision(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recent := h.metricsCollector.GetRecent(metricType, n)
+	if converter != nil {
+		converter.ConvertMetrics(recent)
+	}
+	metrics.RoundMetrics(recent, precision)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Recent metrics retrieved",
+		"metrics": recent,
+	})
+}
+
+// IngestMetricsRequest is the body of an ingest request
+type IngestMetricsRequest struct {
+	Metrics []metrics.IngestRecord `json:"metrics" binding:"required"`
+}
+
+// IngestMetrics validates and stores externally submitted metric samples. The
+// mode query parameter overrides the configured atomic/partial behaviour.
+func (h *Handlers) IngestMetrics(c *gin.Context) {
+	var mode metrics.IngestMode
+	if name := c.Query("mode"); name != "" {
+		parsed, err := metrics.ParseIngestMode(name)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		mode = parsed
+	}
+
+	var req IngestMetricsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.metricsCollector.Ingest(req.Metrics, mode)
+	switch {
+	case errors.Is(err, metrics.ErrIngestRejected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "result": result})
+		return
+	case errors.Is(err, metrics.ErrEmptyBatch), errors.Is(err, metrics.ErrBatchTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Metrics ingested",
+		"result":  result,
+	})
+}
+
+// ListThresholds returns every metric threshold, including disabled ones
+func (h *Handlers) ListThresholds(c *gin.Context) {
+	thresholds, err := h.alertService.ListThresholds()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Thresholds retrieved",
+		"thresholds": thresholds,
+	})
+}
+
+// UpdateThreshold enables or disables the threshold for a metric type, or
+// changes its direction or resolve margin
+func (h *Handlers) UpdateThreshold(c *gin.Context) {
+	var req alerts.UpdateThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Enabled == nil && req.Direction == nil && req.ResolveMargin == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "enabled, direction or resolve_margin is required"})
+		return
+	}
+	if req.Direction != nil {
+		if _, err := metrics.ParseThresholdDirection(*req.Direction); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.ResolveMargin != nil && *req.ResolveMargin < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resolve_margin must not be negative"})
+		return
+	}
+
+	metricType := metrics.MetricType(c.Param("type"))
+	update, err := h.alertService.UpdateThreshold(metricType, &req)
+	if err != nil {
+		if errors.Is(err, alerts.ErrThresholdNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionThresholdUpdate, fmt.Sprintf("threshold:%s", metricType), map[string]interface{}{
+		"enabled":         update.Threshold.Enabled,
+		"direction":       update.Threshold.Direction,
+		"resolve_margin":  update.Threshold.ResolveMargin,
+		"resolved_alerts": update.ResolvedAlerts,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Threshold updated",
+		"threshold":       update.Threshold,
+		"resolved_alerts": update.ResolvedAlerts,
+	})
+}
+
+// unitConverter returns a converter for the unit query parameter, or nil when
+// no unit was requested or raw=true asks for stored values
+func unitConverter(c *gin.Context, metricType metrics.MetricType) (*metrics.UnitConverter, error) {
+	unit := c.Query("unit")
+	if unit == "" || c.Query("raw") == "true" {
+		return nil, nil
+	}
+	return metrics.NewUnitConverter(metricType, unit)
+}
+
+// valuePrecision parses the precision query parameter, the number of decimal
+// places to round values to; without it values are returned unrounded
+func valuePrecision(c *gin.Context) (int, error) {
+	return metrics.ParsePrecision(c.Query("precision"))
+}
+
+// labelSelector parses the repeatable label query parameter, e.g.
+// ?label=env:prod&label=service:api; metrics must carry every label given
+func labelSelector(c *gin.Context) (metrics.LabelSelector, error) {
+	return metrics.ParseLabelSelector(c.QueryArray("label"))
+}
+
+// historyPage parses the cursor and offset query parameters, which pick
+// where a page of history starts; at most one may be given
+func historyPage(c *gin.Context) (metrics.HistoryPage, error) {
+	var page metrics.HistoryPage
+
+	cursor, offset := c.Query("cursor"), c.Query("offset")
+	if cursor != "" && offset != "" {
+		return page, errors.New("cursor and offset cannot be combined")
+	}
+	if cursor != "" {
+		parsed, err := metrics.ParseHistoryCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = parsed
+	}
+	if offset != "" {
+		parsed, err := strconv.Atoi(offset)
+		if err != nil || parsed < 0 {
+			return page, errors.New("invalid offset parameter")
+		}
+		page.Offset = parsed
+	}
+	return page, nil
+}
+
+// CompareMetrics compares a metric's summary over the latest window with the prior window
+func (h *Handlers) CompareMetrics(c *gin.Context) {
+	metricType := c.Query("type")
+	if metricType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type parameter is required"})
+		return
+	}
+
+	window, err := parseWindow(c.DefaultQuery("window", "7d"))
+	if err != nil || window <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window parameter"})
+		return
+	}
+
+	threshold := metrics.DefaultRegressionThreshold
+	if thresholdStr := c.Query("threshold"); thresholdStr != "" {
+		threshold, err = strconv.ParseFloat(thresholdStr, 64)
+		if err != nil || threshold < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid threshold parameter"})
+			return
+		}
+	}
+
+	comparison, err := h.metricsCollector.CompareWindows(metrics.MetricType(metricType), window, threshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Metric comparison retrieved",
+		"threshold":  threshold,
+		"comparison": comparison,
+	})
+}
+
+// parseWindow parses a duration, also accepting whole days such as "7d"
+func parseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// Alert Handlers
+
+// GetAlerts returns alerts with optional filtering
+func (h *Handlers) GetAlerts(c *gin.Context) {
+	status := c.Query("status")
+	limitStr := c.DefaultQuery("limit", "50")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	order, err := alerts.ParseAlertSort(c.Query("sort"), c.Query("order"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alertsList, err := h.alertService.GetAlerts(alerts.AlertStatus(status), limit, order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alerts retrieved",
+		"alerts":  alertsList,
+		"limit":   h.alertService.AlertLimit(limit),
+	})
+}
+
+// GetIncidents returns incidents with their member alerts
+func (h *Handlers) GetIncidents(c *gin.Context) {
+	status := alerts.IncidentStatus(c.Query("status"))
+	if status != "" && status != alerts.IncidentOpen && status != alerts.IncidentClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status parameter, expected open or closed"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	incidents, err := h.alertService.GetIncidents(status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Incidents retrieved",
+		"incidents": incidents,
+	})
+}
+
+// GetActiveAlertCounts returns the number of active alerts per severity, for
+// status badges
+func (h *Handlers) GetActiveAlertCounts(c *gin.Context) {
+	counts, err := h.alertService.GetActiveAlertCountsBySeverity()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Active alert counts retrieved",
+		"by_severity": counts,
+		"total":       total,
+	})
+}
+
+// GetAlertTrend returns alert counts per time bucket for charting
+func (h *Handlers) GetAlertTrend(c *gin.Context) {
+	to := time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339"})
+			return
+		}
+		to = parsed
+	}
+
+	from := to.Add(-7 * 24 * time.Hour)
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339"})
+			return
+		}
+		from = parsed
+	}
+
+	bucket, err := time.ParseDuration(c.DefaultQuery("bucket", "24h"))
+	if err != nil || bucket <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bucket parameter"})
+		return
+	}
+
+	trend, err := h.alertService.GetAlertTrend(from, to, bucket)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alert trend retrieved",
+		"bucket":  bucket.String(),
+		"trend":   trend,
+	})
+}
+
+// GetAlert returns a single alert by ID
+func (h *Handlers) GetAlert(c *gin.Context) {
+	alertIDStr := c.Param("id")
+	alertID, err := strconv.ParseUint(alertIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alert ID"})
+		return
+	}
+
+	alert, err := h.alertService.GetAlertByID(uint(alertID))
+	if err != nil {
+		if errors.Is(err, alerts.ErrAlertNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alert retrieved",
+		"alert":   alert,
+	})
+}
+
+// CreateAlert manually creates an alert (for testing)
+func (h *Handlers) CreateAlert(c *gin.Context) {
+	var req alerts.CreateAlertRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	alert, err := h.alertService.CreateAlert(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionAlertCreate, fmt.Sprintf("alert:%d", alert.ID), map[string]interface{}{
+		"type":      alert.Type,
+		"value":     alert.Value,
+		"threshold": alert.Threshold,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Alert created",
+		"alert":   alert,
+	})
+}
+
+// ResolveAlert resolves an alert
+func (h *Handlers) ResolveAlert(c *gin.Context) {
+	alertIDStr := c.Param("id")
+	alertID, err := strconv.ParseUint(alertIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid alert ID"})
+		return
+	}
+
+	if err := h.alertService.ResolveAlert(uint(alertID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionAlertResolve, fmt.Sprintf("alert:%d", alertID), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert resolved"})
+}
+
+// RecalculateSeverity recomputes severity for existing alerts with the current rules
+func (h *Handlers) RecalculateSeverity(c *gin.Context) {
+	filter := alerts.SeverityFilter{
+		Status: alerts.AlertStatus(c.Query("status")),
+		Type:   metrics.MetricType(c.Query("type")),
+	}
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339"})
+			return
+		}
+		filter.From = from
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339"})
+			return
+		}
+		filter.To = to
+	}
+
+	result, err := h.alertService.RecalculateSeverity(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionAlertRecalculate, "alerts", map[string]interface{}{
+		"status":  filter.Status,
+		"type":    filter.Type,
+		"updated": result.Updated,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alert severity recalculated",
+		"result":  result,
+	})
+}
+
+// Annotation Handlers
+
+// CreateAnnotation marks an event such as a deploy on the metric timeline
+func (h *Handlers) CreateAnnotation(c *gin.Context) {
+	var req metrics.CreateAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := UserIDFromContext(c)
+	annotation, err := h.metricsCollector.CreateAnnotation(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionAnnotationCreate, fmt.Sprintf("annotation:%d", annotation.ID), map[string]interface{}{
+		"timestamp": annotation.Timestamp,
+		"tags":      annotation.Tags,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Annotation created",
+		"annotation": annotation,
+	})
+}
+
+// GetAnnotations lists annotations in a time range, optionally by tag
+func (h *Handlers) GetAnnotations(c *gin.Context) {
+	to := time.Now()
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339"})
+			return
+		}
+		to = parsed
+	}
+
+	from := to.Add(-7 * 24 * time.Hour)
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339"})
+			return
+		}
+		from = parsed
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+
+	tag := c.Query("tag")
+	if tag != "" {
+		if err := metrics.ValidateAnnotationTag(tag); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	annotations, err := h.metricsCollector.GetAnnotations(metrics.AnnotationFilter{From: from, To: to, Tag: tag, Limit: limit})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Annotations retrieved",
+		"annotations": annotations,
+	})
+}
+
+// Maintenance Handlers
+
+// ResetData deletes all stored metrics, alerts or both, for development and
+// integration tests. It is refused unless ALLOW_ADMIN_RESET is enabled.
+func (h *Handlers) ResetData(c *gin.Context) {
+	if !h.allowReset {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin reset is disabled (set ALLOW_ADMIN_RESET=true to enable)"})
+		return
+	}
+
+	scope := c.Query("scope")
+	if scope != "metrics" && scope != "alerts" && scope != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope parameter, expected metrics, alerts or all"})
+		return
+	}
+
+	deleted := make(map[string]int64)
+	if scope == "metrics" || scope == "all" {
+		counts, err := h.metricsCollector.ResetMetrics()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for table, count := range counts {
+			deleted[table] = count
+		}
+	}
+	if scope == "alerts" || scope == "all" {
+		counts, err := h.alertService.ResetAlerts()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for table, count := range counts {
+			deleted[table] = count
+		}
+	}
+
+	h.recordAudit(c, audit.ActionAdminReset, "scope:"+scope, map[string]interface{}{
+		"deleted": deleted,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Data reset",
+		"scope":   scope,
+		"deleted": deleted,
+	})
+}
+
+// RunMigrations re-runs database migrations and data fixups, such as filling
+// in metric types missing from imported rows, and reports what changed
+func (h *Handlers) RunMigrations(c *gin.Context) {
+	if h.migrator == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin migrate is disabled (set ALLOW_ADMIN_MIGRATE=true to enable)"})
+		return
+	}
+
+	report, err := h.migrator.Migrate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionAdminMigrate, "database", map[string]interface{}{
+		"metric_types_fixed": report.MetricTypesFixed,
+		"emails_normalized":  report.EmailsNormalized,
+		"dropped_columns":    report.DroppedColumns,
+		"warnings":           len(report.Warnings),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Migrations completed",
+		"report":  report,
+	})
+}
+
+// TestNotification sends a test alert through one notification channel, or
+// all of them with channel=all, and reports whether each delivery succeeded
+func (h *Handlers) TestNotification(c *gin.Context) {
+	if h.notifier == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "notifications are not available"})
+		return
+	}
+
+	channel := c.Query("channel")
+	if channel == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "channel parameter is required"})
+		return
+	}
+
+	results, err := h.notifier.SendTest(c.Request.Context(), channel)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var failed int
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+
+	h.recordAudit(c, audit.ActionNotificationTest, "notification:"+channel, map[string]interface{}{
+		"channels": len(results),
+		"failed":   failed,
+	})
+
+	if failed > 0 {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   fmt.Sprintf("test notification failed on %d of %d channels", failed, len(results)),
+			"results": results,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Test notification sent",
+		"results": results,
+	})
+}
+
+// CheckIntegrity reports rows with missing or unknown metric types and other
+// invalid data, repairing what can be repaired safely with fix=true
+func (h *Handlers) CheckIntegrity(c *gin.Context) {
+	if h.integrity == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "integrity checks are not available"})
+		return
+	}
+
+	fix := c.Query("fix") == "true"
+	report, err := h.integrity.VerifyDataIntegrity(fix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if fix {
+		var fixed int64
+		for _, issue := range report.Issues {
+			fixed += issue.Fixed
+		}
+		h.recordAudit(c, audit.ActionAdminIntegrityFix, "database", map[string]interface{}{
+			"issues": len(report.Issues),
+			"fixed":  fixed,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Integrity check completed",
+		"report":  report,
+	})
+}
+
+// ScheduleMaintenance schedules a maintenance window that suppresses alerting
+func (h *Handlers) ScheduleMaintenance(c *gin.Context) {
+	var req alerts.ScheduleMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := UserIDFromContext(c)
+	window, err := h.alertService.ScheduleMaintenance(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionMaintenanceSchedule, fmt.Sprintf("maintenance:%d", window.ID), map[string]interface{}{
+		"starts_at": window.StartsAt,
+		"ends_at":   window.EndsAt,
+		"reason":    window.Reason,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":            "Maintenance window scheduled",
+		"maintenance_window": window,
+	})
+}
+
+// ListMaintenance lists current and upcoming maintenance windows
+func (h *Handlers) ListMaintenance(c *gin.Context) {
+	windows, err := h.alertService.ListMaintenanceWindows(c.Query("all") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Maintenance windows retrieved",
+		"maintenance_windows": windows,
+	})
+}
+
+// CancelMaintenance cancels a maintenance window
+func (h *Handlers) CancelMaintenance(c *gin.Context) {
+	windowID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid maintenance window ID"})
+		return
+	}
+
+	if err := h.alertService.CancelMaintenance(uint(windowID)); err != nil {
+		if errors.Is(err, alerts.ErrMaintenanceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionMaintenanceCancel, fmt.Sprintf("maintenance:%d", windowID), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance window cancelled"})
+}
+
+// Subscription Handlers
+
+// CreateSubscription subscribes the authenticated user to alerts. Email
+// subscriptions without a target go to the user's own address.
+func (h *Handlers) CreateSubscription(c *gin.Context) {
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var req alerts.CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Channel == notify.ChannelEmail && req.Target == "" {
+		user, err := h.authService.GetUserByID(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		req.Target = user.Email
+	}
+
+	subscription, err := h.alertService.CreateSubscription(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionSubscriptionCreate, fmt.Sprintf("subscription:%d", subscription.ID), map[string]interface{}{
+		"metric_type":  subscription.MetricType,
+		"min_severity": subscription.MinSeverity,
+		"channel":      subscription.Channel,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Subscription created",
+		"subscription": subscription,
+	})
+}
+
+// ListSubscriptions lists the authenticated user's alert subscriptions
+func (h *Handlers) ListSubscriptions(c *gin.Context) {
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	subscriptions, err := h.alertService.ListSubscriptions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Subscriptions retrieved",
+		"subscriptions": subscriptions,
+	})
+}
+
+// DeleteSubscription removes one of the authenticated user's subscriptions
+func (h *Handlers) DeleteSubscription(c *gin.Context) {
+	userID, ok := UserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	subscriptionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription ID"})
+		return
+	}
+
+	if err := h.alertService.DeleteSubscription(userID, uint(subscriptionID)); err != nil {
+		if errors.Is(err, alerts.ErrSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordAudit(c, audit.ActionSubscriptionDelete, fmt.Sprintf("subscription:%d", subscriptionID), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted"})
+}
+
+// Audit Handlers
+
+// GetAuditLog returns audit entries filtered by actor, action and time range
+func (h *Handlers) GetAuditLog(c *gin.Context) {
+	var filter audit.Filter
+
+	if actorStr := c.Query("actor_id"); actorStr != "" {
+		actorID, err := strconv.ParseUint(actorStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor_id parameter"})
+			return
+		}
+		filter.ActorID = uint(actorID)
+	}
+	filter.Action = c.Query("action")
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err := time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from parameter, expected RFC3339"})
+			return
+		}
+		filter.From = from
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		to, err := time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to parameter, expected RFC3339"})
+			return
+		}
+		filter.To = to
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+		return
+	}
+	filter.Limit = limit
+
+	entries, err := h.auditService.Query(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Audit log retrieved",
+		"entries": entries,
+	})
+}
+
+// Summary Handler
+
+// GetSummary returns comprehensive system summary
+func (h *Handlers) GetSummary(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "10")
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		limit = 10
+	}
+
+	precision, err := valuePrecision(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	selector, err := labelSelector(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cacheKey := summaryCacheKey{limit: limit, precision: precision, labels: selector.String()}
+
+	// Serve a recent summary if alerts have not changed since it was built
+	revision := h.alertService.Revision()
+	if h.summaryCache != nil {
+		if summary, builtAt, ok := h.summaryCache.get(cacheKey, revision); ok {
+			c.JSON(http.StatusOK, gin.H{
+				"message":           "Summary retrieved",
+				"summary":           summary,
+				"cached":            true,
+				"cache_age_seconds": math.Round(time.Since(builtAt).Seconds()*1000) / 1000,
+			})
+			return
+		}
+	}
+
+	// Get current metrics
+	currentMetrics, err := h.metricsCollector.GetCurrentMetrics()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get current metrics"})
+		return
+	}
+
+	// Get alert summary
+	alertSummary, err := h.alertService.GetAlertSummary(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get alert summary"})
+		return
+	}
+
+	// Get metric summaries for last 10 readings
+	cpuSummary, err := h.metricsCollector.GetMetricSummary(metrics.CPUUsage, 10, selector)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get CPU summary"})
+		return
+	}
+
+	memorySummary, err := h.metricsCollector.GetMetricSummary(metrics.MemoryUsage, 10, selector)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get memory summary"})
+		return
+	}
+
+	summary := gin.H{
+		"current_metrics": currentMetrics.Rounded(precision),
+		"alerts":          alertSummary,
+		"metric_averages": gin.H{
+			"cpu":    cpuSummary.Rounded(precision),
+			"memory": memorySummary.Rounded(precision),
+		},
+	}
+	if h.summaryCache != nil {
+		h.summaryCache.put(cacheKey, revision, summary, time.Now())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Summary retrieved",
+		"summary":           summary,
+		"cached":            false,
+		"cache_age_seconds": 0,
+	})
+}
+
+// Health check handler
+func (h *Handlers) HealthCheck(c *gin.Context) {
+	response := gin.H{
+		"status":  "healthy",
+		"message": "CodeXray Observability Service is running",
+	}
+	if h.metricsCollector != nil {
+		response["collector"] = h.metricsCollector.Stats()
+	}
+	if h.notifier != nil {
+		response["notifications"] = h.notifier.Stats()
+	}
+	c.JSON(http.StatusOK, response)
 }
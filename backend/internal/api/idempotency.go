@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/idempotency"
+	"github.com/gin-gonic/gin"
+)
+
+// Idempotency headers
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotency-Replayed"
+)
+
+// responseRecorder keeps a copy of the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// IdempotencyMiddleware makes a POST safe to retry. A request carrying an
+// Idempotency-Key header runs once; repeats with the same key and body, from
+// the same user to the same route, get the stored response back with
+// Idempotency-Replayed: true. Server errors are not stored, so those requests
+// can be retried. Requests without the header, or with a nil store, are
+// passed through. It must run after the authentication middleware.
+func IdempotencyMiddleware(store *idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if store == nil || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("%s must be at most %d characters", HeaderIdempotencyKey, idempotency.MaxKeyLength),
+			})
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+
+		userID, _ := UserIDFromContext(c)
+		scope := fmt.Sprintf("%d:%s %s", userID, c.Request.Method, c.FullPath())
+
+		record, err := store.Begin(scope, key, requestHash)
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case errors.Is(err, idempotency.ErrKeyReused):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case record != nil:
+			c.Header(HeaderIdempotencyReplayed, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// A panicking handler would otherwise leave the key reserved, and
+		// every retry would be refused as in progress
+		defer func() {
+			if r := recover(); r != nil {
+				if err := store.Release(scope, key); err != nil {
+					log.Printf("Idempotency key %q: %v", key, err)
+				}
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		if status := recorder.Status(); status >= http.StatusInternalServerError {
+			err = store.Release(scope, key)
+		} else {
+			err = store.Complete(scope, key, status, recorder.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("Idempotency key %q: %v", key, err)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package api
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/auth"
 	"github.com/gin-gonic/gin"
@@ -34,22 +39,166 @@ func AuthMiddleware(authService *auth.Service) gin.HandlerFunc {
 			return
 		}
 
-		// Set user info in context
-		c.Set("user_id", user.ID)
-		c.Set("user", user)
+		setUserContext(c, user)
 		c.Set("token", token)
 
 		c.Next()
 	}
 }
 
-// CORSMiddleware handles CORS headers
-func CORSMiddleware() gin.HandlerFunc {
+// AuthOrAPIKeyMiddleware accepts either a JWT or an API key carrying scope.
+// API keys may be sent as "Authorization: Bearer <key>" or in the X-API-Key header.
+func AuthOrAPIKeyMiddleware(authService *auth.Service, scope string) gin.HandlerFunc {
+	jwtAuth := AuthMiddleware(authService)
+
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
+		apiKey := c.GetHeader("X-API-Key")
+		if apiKey == "" {
+			apiKey = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if !auth.IsAPIKey(apiKey) {
+				jwtAuth(c)
+				return
+			}
+		}
+
+		user, key, err := authService.ValidateAPIKey(apiKey)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or revoked API key"})
+			c.Abort()
+			return
+		}
+
+		if !key.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API key lacks the %q scope", scope)})
+			c.Abort()
+			return
+		}
+
+		setUserContext(c, user)
+		c.Set(ContextAPIKeyID, key.ID)
+
+		c.Next()
+	}
+}
+
+// AdminMiddleware rejects authenticated users who are not admins. It must run
+// after AuthMiddleware.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := RoleFromContext(c)
+		if role != auth.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setUserContext stores the authenticated user for downstream handlers
+func setUserContext(c *gin.Context, user *auth.User) {
+	c.Set(ContextUserID, user.ID)
+	c.Set(ContextUsername, user.Username)
+	c.Set(ContextRole, user.Role)
+	c.Set("user", user)
+}
+
+// Context keys set by AuthMiddleware for downstream handlers
+const (
+	ContextUserID   = "user_id"
+	ContextUsername = "username"
+	ContextRole     = "role"
+	ContextAPIKeyID = "api_key_id"
+)
+
+// UserIDFromContext returns the authenticated user's ID set by AuthMiddleware
+func UserIDFromContext(c *gin.Context) (uint, bool) {
+	value, ok := c.Get(ContextUserID)
+	if !ok {
+		return 0, false
+	}
+	userID, ok := value.(uint)
+	return userID, ok
+}
+
+// UsernameFromContext returns the authenticated user's username set by AuthMiddleware
+func UsernameFromContext(c *gin.Context) (string, bool) {
+	value, ok := c.Get(ContextUsername)
+	if !ok {
+		return "", false
+	}
+	username, ok := value.(string)
+	return username, ok
+}
+
+// RoleFromContext returns the authenticated user's role set by AuthMiddleware
+func RoleFromContext(c *gin.Context) (auth.Role, bool) {
+	value, ok := c.Get(ContextRole)
+	if !ok {
+		return "", false
+	}
+	role, ok := value.(auth.Role)
+	return role, ok
+}
+
+// Default request body limits, used when SecurityOptions leaves them unset
+const (
+	DefaultMaxBodyBytes       = 1 << 20  // 1 MiB
+	DefaultMaxIngestBodyBytes = 16 << 20 // 16 MiB
+)
+
+// SecurityOptions configures CORS, the security headers sent on every response
+// and request body limits
+type SecurityOptions struct {
+	// AllowedOrigins lists origins allowed by CORS; empty or "*" allows any
+	AllowedOrigins []string
+	// ContentSecurityPolicy is the Content-Security-Policy value; empty omits it
+	ContentSecurityPolicy string
+	// HSTS is the Strict-Transport-Security value, sent only over HTTPS;
+	// empty omits it
+	HSTS string
+
+	// MaxBodyBytes limits request bodies on regular endpoints
+	MaxBodyBytes int64
+	// MaxIngestBodyBytes limits request bodies on bulk endpoints such as ingest
+	MaxIngestBodyBytes int64
+}
+
+// allowsAnyOrigin reports whether CORS is open to every origin
+func (o SecurityOptions) allowsAnyOrigin() bool {
+	if len(o.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, origin := range o.AllowedOrigins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// CORSMiddleware handles CORS headers. Requests from origins that are not
+// allowed get no Access-Control-Allow-Origin header, so browsers block them.
+func CORSMiddleware(options SecurityOptions) gin.HandlerFunc {
+	anyOrigin := options.allowsAnyOrigin()
+	allowed := make(map[string]bool, len(options.AllowedOrigins))
+	for _, origin := range options.AllowedOrigins {
+		allowed[origin] = true
+	}
+
+	return func(c *gin.Context) {
+		if anyOrigin {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Vary", "Origin")
+			if origin := c.GetHeader("Origin"); allowed[origin] {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+		}
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -60,19 +209,94 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
-// LoggingMiddleware logs HTTP requests
-func LoggingMiddleware() gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("%s - [%s] \"%s %s %s %d %s \"%s\" %s\"\n",
-			param.ClientIP,
-			param.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
-			param.Method,
-			param.Path,
-			param.Request.Proto,
-			param.StatusCode,
-			param.Latency,
-			param.Request.UserAgent(),
-			param.ErrorMessage,
-		)
+// SecurityHeadersMiddleware sets standard hardening headers on every response.
+// HSTS is only sent when the request arrived over HTTPS, either directly or
+// through a proxy reporting X-Forwarded-Proto: https.
+func SecurityHeadersMiddleware(options SecurityOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("X-Frame-Options", "DENY")
+		c.Header("Referrer-Policy", "no-referrer")
+		if options.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", options.ContentSecurityPolicy)
+		}
+		if options.HSTS != "" && isHTTPS(c) {
+			c.Header("Strict-Transport-Security", options.HSTS)
+		}
+
+		c.Next()
+	}
+}
+
+// isHTTPS reports whether the client connected over TLS
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
+
+// BodyLimitMiddleware rejects requests whose body exceeds maxBytes with 413.
+// The body is read through http.MaxBytesReader, so an oversized or unbounded
+// (chunked) upload is cut off at the limit instead of being buffered whole.
+func BodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			abortTooLarge(c, maxBytes)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				abortTooLarge(c, maxBytes)
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		c.Next()
+	}
+}
+
+// abortTooLarge responds with 413 Request Entity Too Large
+func abortTooLarge(c *gin.Context, maxBytes int64) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": fmt.Sprintf("request body exceeds the %d byte limit", maxBytes),
 	})
+	c.Abort()
+}
+
+// LoggingMiddleware logs each HTTP request through the default slog logger,
+// so requests follow LOG_FORMAT like the rest of the output
+func LoggingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		if c.Request.URL.RawQuery != "" {
+			path += "?" + c.Request.URL.RawQuery
+		}
+
+		c.Next()
+
+		attrs := []interface{}{
+			"client_ip", c.ClientIP(),
+			"method", c.Request.Method,
+			"path", path,
+			"proto", c.Request.Proto,
+			"status", c.Writer.Status(),
+			"latency", time.Since(start),
+			"user_agent", c.Request.UserAgent(),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate).String(); errs != "" {
+			attrs = append(attrs, "error", errs)
+		}
+		slog.Info("HTTP request", attrs...)
+	}
 }
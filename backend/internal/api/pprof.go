@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http/pprof"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupPprofRoutes mounts the net/http/pprof handlers under /debug/pprof for
+// authenticated admins. It is only called when ENABLE_PPROF is set.
+func SetupPprofRoutes(router *gin.Engine, authService *auth.Service) {
+	debug := router.Group("/debug/pprof")
+	debug.Use(AuthMiddleware(authService), AdminMiddleware())
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		// Named profiles such as goroutine, heap, allocs, block and mutex
+		debug.GET("/:profile", gin.WrapF(pprof.Index))
+	}
+}
@@ -6,9 +6,18 @@ import (
 )
 
 // SetupRoutes configures all API routes
-func SetupRoutes(router *gin.Engine, handlers *Handlers, authService *auth.Service) {
+func SetupRoutes(router *gin.Engine, handlers *Handlers, authService *auth.Service, security SecurityOptions) {
 	// Add middleware
-	router.Use(CORSMiddleware())
+	router.Use(SecurityHeadersMiddleware(security))
+	router.Use(CORSMiddleware(security))
+
+	maxBody, maxIngestBody := security.MaxBodyBytes, security.MaxIngestBodyBytes
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBodyBytes
+	}
+	if maxIngestBody <= 0 {
+		maxIngestBody = DefaultMaxIngestBodyBytes
+	}
 	router.Use(LoggingMiddleware())
 
 	// Health check
@@ -19,42 +28,135 @@ func SetupRoutes(router *gin.Engine, handlers *Handlers, authService *auth.Servi
 
 	// Authentication routes (public)
 	authRoutes := v1.Group("/auth")
+	authRoutes.Use(BodyLimitMiddleware(maxBody))
 	{
 		authRoutes.POST("/register", handlers.Register)
 		authRoutes.POST("/login", handlers.Login)
 		authRoutes.POST("/validate", handlers.ValidateToken)
 		authRoutes.POST("/refresh", handlers.RefreshToken)
+		authRoutes.GET("/verify", handlers.VerifyEmail)
+		authRoutes.POST("/verify/resend", handlers.ResendVerification)
+	}
+
+	// Read-only routes (JWT or an API key with the read scope)
+	readable := v1.Group("")
+	readable.Use(AuthOrAPIKeyMiddleware(authService, auth.ScopeRead), BodyLimitMiddleware(maxBody))
+	{
+		// Metrics routes
+		metricsRoutes := readable.Group("/metrics")
+		{
+			metricsRoutes.GET("/current", handlers.GetCurrentMetrics)
+			metricsRoutes.GET("/types", handlers.GetMetricTypes)
+			metricsRoutes.GET("/history", handlers.GetMetricHistories)
+			metricsRoutes.GET("/history/:type", handlers.GetMetricHistory)
+			metricsRoutes.GET("/compare", handlers.CompareMetrics)
+			metricsRoutes.GET("/latest/:type", handlers.GetLatestMetric)
+			metricsRoutes.GET("/recent/:type", handlers.GetRecentMetrics)
+			metricsRoutes.GET("/histogram/:type", handlers.GetMetricHistogram)
+			metricsRoutes.GET("/fleet/:type", handlers.GetFleetMetrics)
+			metricsRoutes.GET("/thresholds", handlers.ListThresholds)
+			metricsRoutes.GET("/breaches", handlers.GetBreaches)
+			metricsRoutes.GET("/collector/status", handlers.GetCollectorStatus)
+			metricsRoutes.GET("/cpu/modes", handlers.GetCPUModes)
+			metricsRoutes.GET("/:type/gaps", handlers.GetMetricGaps)
+		}
+
+		// Alert routes
+		alertRoutes := readable.Group("/alerts")
+		{
+			alertRoutes.GET("", handlers.GetAlerts)
+			alertRoutes.GET("/trend", handlers.GetAlertTrend)
+			alertRoutes.GET("/active/counts", handlers.GetActiveAlertCounts)
+			alertRoutes.GET("/:id", handlers.GetAlert)
+		}
+
+		// System routes
+		readable.GET("/system/snapshot", handlers.GetSystemSnapshot)
+
+		// Annotation routes
+		readable.GET("/annotations", handlers.GetAnnotations)
+
+		// Incident routes
+		readable.GET("/incidents", handlers.GetIncidents)
+
+		// Summary route
+		readable.GET("/summary", handlers.GetSummary)
+
+		// Integration routes
+		readable.GET("/integrations/grafana-dashboard", handlers.GetGrafanaDashboard)
+	}
+
+	// Ingest routes (JWT or an API key with the ingest scope)
+	ingest := v1.Group("")
+	ingest.Use(AuthOrAPIKeyMiddleware(authService, auth.ScopeIngest), BodyLimitMiddleware(maxIngestBody))
+	{
+		ingest.POST("/metrics/ingest", handlers.IngestMetrics)
 	}
 
 	// Protected routes (require authentication)
 	protected := v1.Group("")
-	protected.Use(AuthMiddleware(authService))
+	protected.Use(AuthMiddleware(authService), BodyLimitMiddleware(maxBody))
 	{
 		// Auth routes
 		protected.POST("/auth/logout", handlers.Logout)
+		protected.GET("/auth/me", handlers.Me)
+		protected.PUT("/auth/password", handlers.ChangePassword)
+
+		// API key routes
+		apiKeyRoutes := protected.Group("/auth/api-keys")
+		{
+			apiKeyRoutes.POST("", handlers.CreateAPIKey)
+			apiKeyRoutes.GET("", handlers.ListAPIKeys)
+			apiKeyRoutes.DELETE("/:id", handlers.RevokeAPIKey)
+		}
 
 		// Log analysis routes
 		logRoutes := protected.Group("/logs")
 		{
 			logRoutes.GET("/analyze", handlers.AnalyzeLogs)
+			logRoutes.POST("/analyze", handlers.AlertOnLogErrorRate)
+			logRoutes.GET("/analyze-dir", handlers.AnalyzeLogDirectory)
 		}
 
-		// Metrics routes
-		metricsRoutes := protected.Group("/metrics")
-		{
-			metricsRoutes.GET("/current", handlers.GetCurrentMetrics)
-			metricsRoutes.GET("/history/:type", handlers.GetMetricHistory)
-		}
+		// Annotation routes
+		protected.POST("/annotations", handlers.CreateAnnotation)
 
 		// Alert routes
 		alertRoutes := protected.Group("/alerts")
 		{
-			alertRoutes.GET("", handlers.GetAlerts)
-			alertRoutes.POST("", handlers.CreateAlert)
+			alertRoutes.POST("", IdempotencyMiddleware(handlers.idempotency), handlers.CreateAlert)
 			alertRoutes.PUT("/:id/resolve", handlers.ResolveAlert)
+
+			alertRoutes.GET("/subscriptions", handlers.ListSubscriptions)
+			alertRoutes.POST("/subscriptions", handlers.CreateSubscription)
+			alertRoutes.DELETE("/subscriptions/:id", handlers.DeleteSubscription)
 		}
 
-		// Summary route
-		protected.GET("/summary", handlers.GetSummary)
+		// Maintenance window routes
+		maintenanceRoutes := protected.Group("/maintenance")
+		{
+			maintenanceRoutes.POST("", handlers.ScheduleMaintenance)
+			maintenanceRoutes.GET("", handlers.ListMaintenance)
+			maintenanceRoutes.DELETE("/:id", handlers.CancelMaintenance)
+		}
+
+		// Admin-only routes
+		admin := protected.Group("")
+		admin.Use(AdminMiddleware())
+		{
+			admin.GET("/audit", handlers.GetAuditLog)
+			admin.POST("/alerts/recalculate-severity", handlers.RecalculateSeverity)
+			admin.PATCH("/metrics/thresholds/:type", handlers.UpdateThreshold)
+			admin.POST("/alerts/test-notification", handlers.TestNotification)
+			admin.POST("/admin/reset", handlers.ResetData)
+			admin.POST("/admin/migrate", handlers.RunMigrations)
+			admin.POST("/admin/integrity-check", handlers.CheckIntegrity)
+			admin.GET("/admin/diagnostics", handlers.GetDiagnostics)
+			admin.POST("/metrics/collector/pause", handlers.PauseCollector)
+			admin.POST("/metrics/collector/resume", handlers.ResumeCollector)
+			admin.POST("/auth/users/bulk", handlers.ImportUsers)
+			admin.DELETE("/auth/users/:id", handlers.DeleteUser)
+			admin.POST("/auth/users/:id/restore", handlers.RestoreUser)
+		}
 	}
 }
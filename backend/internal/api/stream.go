@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// ndjsonContentType is the media type of newline-delimited JSON
+const ndjsonContentType = "application/x-ndjson"
+
+// streamFlushRows is how many streamed rows are written between flushes
+const streamFlushRows = 100
+
+// streamRetryAfter is how long clients turned away by the streaming client
+// cap are told to wait before retrying
+const streamRetryAfter = 5 * time.Second
+
+// streamLimiter caps how many streaming responses are open at once, since
+// each holds a goroutine and a database cursor for as long as the client
+// reads
+type streamLimiter struct {
+	// max is the cap; zero or less means no cap
+	max     atomic.Int64
+	current atomic.Int64
+}
+
+// StreamingStatus reports open streaming responses against the cap
+type StreamingStatus struct {
+	Clients int64 `json:"clients"`
+	// MaxClients is zero when there is no cap
+	MaxClients int64 `json:"max_clients"`
+}
+
+// acquire claims a slot for a new stream, reporting false when the cap is
+// reached. Every successful acquire must be paired with a release.
+func (l *streamLimiter) acquire() bool {
+	max := l.max.Load()
+	for {
+		current := l.current.Load()
+		if max > 0 && current >= max {
+			return false
+		}
+		if l.current.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+// release frees the slot of a stream that has ended
+func (l *streamLimiter) release() {
+	l.current.Add(-1)
+}
+
+// status returns the open streams and the cap
+func (l *streamLimiter) status() StreamingStatus {
+	max := l.max.Load()
+	if max < 0 {
+		max = 0
+	}
+	return StreamingStatus{Clients: l.current.Load(), MaxClients: max}
+}
+
+// SetMaxStreamClients caps concurrent streaming responses at max; further
+// clients get 503 with Retry-After until one ends. A non-positive max removes
+// the cap.
+func (h *Handlers) SetMaxStreamClients(max int) {
+	h.streams.max.Store(int64(max))
+}
+
+// acquireStream claims a streaming slot, responding 503 when none is free
+func (h *Handlers) acquireStream(c *gin.Context) bool {
+	if h.streams.acquire() {
+		return true
+	}
+	c.Header("Retry-After", strconv.Itoa(int(streamRetryAfter/time.Second)))
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "too many streaming clients, retry later"})
+	return false
+}
+
+// streamMetricHistory writes a metric type's history as newline-delimited
+// JSON, one metric per line, as rows are read from the database. Once the
+// first row is written the status can no longer change, so a later failure
+// only ends the stream early and is logged.
+func (h *Handlers) streamMetricHistory(c *gin.Context, metricType metrics.MetricType, limit int, selector metrics.LabelSelector, converter *metrics.UnitConverter, precision int) {
+	if !h.acquireStream(c) {
+		return
+	}
+	defer h.streams.release()
+
+	encoder := json.NewEncoder(c.Writer)
+
+	written := 0
+	err := h.metricsCollector.StreamMetricHistory(metricType, limit, selector, func(metric metrics.Metric) error {
+		row := []metrics.Metric{metric}
+		if converter != nil {
+			converter.ConvertMetrics(row)
+		}
+		metrics.RoundMetrics(row, precision)
+
+		if written == 0 {
+			c.Header("Content-Type", ndjsonContentType)
+		}
+		if err := encoder.Encode(row[0]); err != nil {
+			return err
+		}
+		written++
+		if written%streamFlushRows == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+
+	if err != nil {
+		if written == 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Streaming %s history stopped after %d rows: %v", metricType, written, err)
+		return
+	}
+	if written == 0 {
+		c.Header("Content-Type", ndjsonContentType)
+	}
+	c.Status(http.StatusOK)
+}
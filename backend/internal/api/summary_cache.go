@@ -0,0 +1,72 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSummaryCacheEntries bounds the cache, since the parameters it is keyed
+// by come from the client
+const maxSummaryCacheEntries = 32
+
+// summaryCacheKey is the request parameters a summary was built for
+type summaryCacheKey struct {
+	limit     int
+	precision int
+	// labels is the label selector in its canonical string form
+	labels string
+}
+
+// summaryCacheEntry is a built summary and the alert revision it reflects
+type summaryCacheEntry struct {
+	summary  gin.H
+	revision uint64
+	builtAt  time.Time
+}
+
+// summaryCache keeps recently built summaries per key for a short TTL, so
+// dashboards refreshing rapidly do not rerun the summary queries each time
+type summaryCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[summaryCacheKey]summaryCacheEntry
+}
+
+// newSummaryCache creates a cache keeping summaries for ttl
+func newSummaryCache(ttl time.Duration) *summaryCache {
+	return &summaryCache{ttl: ttl, entries: make(map[summaryCacheKey]summaryCacheEntry)}
+}
+
+// get returns the summary cached for key and when it was built, unless it
+// has expired or alerts have changed since (revision differs)
+func (c *summaryCache) get(key summaryCacheKey, revision uint64) (gin.H, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.revision != revision || time.Since(entry.builtAt) > c.ttl {
+		return nil, time.Time{}, false
+	}
+	return entry.summary, entry.builtAt, true
+}
+
+// put caches summary for key, dropping expired entries when the cache is full
+func (c *summaryCache) put(key summaryCacheKey, revision uint64, summary gin.H, builtAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxSummaryCacheEntries {
+		for key, entry := range c.entries {
+			if time.Since(entry.builtAt) > c.ttl {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxSummaryCacheEntries {
+			c.entries = make(map[summaryCacheKey]summaryCacheEntry)
+		}
+	}
+	c.entries[key] = summaryCacheEntry{summary: summary, revision: revision, builtAt: builtAt}
+}
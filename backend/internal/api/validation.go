@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// errValidationFailed is the envelope error for requests with invalid fields
+const errValidationFailed = "validation failed"
+
+func init() {
+	// Report fields by their JSON names rather than Go struct field names
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON binds the request body into obj. On failure it responds 400 with
+// the standard error envelope, adding a field→message map under "fields" for
+// invalid or mistyped fields, and returns false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	if fields := bindingFieldErrors(err); fields != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errValidationFailed, "fields": fields})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+	return false
+}
+
+// bindingFieldErrors converts validation and type errors into per-field
+// messages, or returns nil for other errors such as malformed JSON
+func bindingFieldErrors(err error) map[string]string {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make(map[string]string, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields[fieldPath(fieldErr)] = fieldMessage(fieldErr)
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{typeErr.Field: fmt.Sprintf("must be a %s", jsonTypeName(typeErr.Type))}
+	}
+
+	return nil
+}
+
+// fieldPath returns the field's JSON path without the request struct's name,
+// e.g. "metrics[0].type"
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".")
+	if !found {
+		return fieldErr.Field()
+	}
+	return path
+}
+
+// fieldMessage describes a failed validation rule in words
+func fieldMessage(fieldErr validator.FieldError) string {
+	isString := fieldErr.Kind() == reflect.String
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	default:
+		return fmt.Sprintf("failed the %s rule", fieldErr.Tag())
+	}
+}
+
+// jsonTypeName names a Go type the way a JSON client would see it
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	default:
+		return "object"
+	}
+}
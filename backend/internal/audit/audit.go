@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionAlertCreate         = "alert.create"
+	ActionAlertResolve        = "alert.resolve"
+	ActionAlertRecalculate    = "alert.recalculate_severity"
+	ActionAdminReset          = "admin.reset"
+	ActionAdminMigrate        = "admin.migrate"
+	ActionAdminIntegrityFix   = "admin.integrity_fix"
+	ActionAdminDiagnostics    = "admin.diagnostics"
+	ActionCollectorPause      = "collector.pause"
+	ActionCollectorResume     = "collector.resume"
+	ActionAPIKeyCreate        = "api_key.create"
+	ActionAPIKeyRevoke        = "api_key.revoke"
+	ActionAnnotationCreate    = "annotation.create"
+	ActionMaintenanceSchedule = "maintenance.schedule"
+	ActionMaintenanceCancel   = "maintenance.cancel"
+	ActionNotificationTest    = "notification.test"
+	ActionPasswordChange      = "user.password_change"
+	ActionSubscriptionCreate  = "subscription.create"
+	ActionSubscriptionDelete  = "subscription.delete"
+	ActionThresholdUpdate     = "threshold.update"
+	ActionUserDelete          = "user.delete"
+	ActionUserImport          = "user.import"
+	ActionUserRestore         = "user.restore"
+)
+
+// AuditLog records a mutating action taken by a user
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ActorID   uint      `json:"actor_id" gorm:"not null;index"`
+	Action    string    `json:"action" gorm:"not null;index"`
+	Target    string    `json:"target"`
+	Details   string    `json:"-" gorm:"type:text"`
+	Timestamp time.Time `json:"timestamp" gorm:"not null;index"`
+
+	// DetailsJSON exposes Details as raw JSON in API responses
+	DetailsJSON json.RawMessage `json:"details,omitempty" gorm:"-"`
+}
+
+// Filter narrows an audit log query; zero values are ignored
+type Filter struct {
+	ActorID uint
+	Action  string
+	From    time.Time
+	To      time.Time
+	Limit   int
+}
+
+// Service handles audit log operations
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a new audit service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Record writes an audit entry. Failures are logged and never returned, so
+// auditing cannot fail the action being audited.
+func (s *Service) Record(actorID uint, action, target string, details map[string]interface{}) {
+	entry := AuditLog{
+		ActorID:   actorID,
+		Action:    action,
+		Target:    target,
+		Timestamp: time.Now(),
+	}
+
+	if len(details) > 0 {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			log.Printf("Failed to encode audit details for %s: %v", action, err)
+		} else {
+			entry.Details = string(encoded)
+		}
+	}
+
+	if err := s.db.Create(&entry).Error; err != nil {
+		log.Printf("Failed to write audit log for %s by user %d: %v", action, actorID, err)
+	}
+}
+
+// Query returns audit entries matching filter, newest first
+func (s *Service) Query(filter Filter) ([]AuditLog, error) {
+	query := s.db.Order("timestamp DESC")
+
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("timestamp >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("timestamp <= ?", filter.To)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var entries []AuditLog
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+
+	for i := range entries {
+		if entries[i].Details != "" {
+			entries[i].DetailsJSON = json.RawMessage(entries[i].Details)
+		}
+	}
+
+	return entries, nil
+}
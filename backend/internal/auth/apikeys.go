@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix marks API keys so they can be told apart from JWTs in the
+// Authorization header
+const APIKeyPrefix = "cxk_"
+
+// Scopes an API key can be limited to; a key with no scopes may use any
+// route that accepts API keys
+const (
+	ScopeRead   = "read"
+	ScopeIngest = "ingest"
+)
+
+// ErrInvalidAPIKey is returned for unknown, revoked or malformed API keys
+var ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+
+// ErrAPIKeyNotFound is returned when a key does not exist or belongs to another user
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKey is a long-lived credential for scripts and agents. Only a hash of
+// the key is stored; the raw key is shown once at creation.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	Scopes     string     `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Revoked    bool       `json:"revoked" gorm:"not null;default:false"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyRequest represents a request to create an API key
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes"`
+}
+
+// HasScope reports whether the key may be used for scope
+func (k *APIKey) HasScope(scope string) bool {
+	if k.Scopes == "" {
+		return true
+	}
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIKey reports whether a credential looks like an API key rather than a JWT
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// CreateAPIKey creates a key owned by userID, returning the stored record and
+// the raw key, which cannot be recovered later
+func (s *Service) CreateAPIKey(userID uint, req *CreateAPIKeyRequest) (*APIKey, string, error) {
+	for _, scope := range req.Scopes {
+		if scope != ScopeRead && scope != ScopeIngest {
+			return nil, "", fmt.Errorf("unknown scope %q (expected %s or %s)", scope, ScopeRead, ScopeIngest)
+		}
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	rawKey := APIKeyPrefix + token
+
+	key := APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		KeyHash: hashToken(rawKey),
+		Prefix:  rawKey[:len(APIKeyPrefix)+8],
+		Scopes:  strings.Join(req.Scopes, ","),
+	}
+
+	if err := s.db.Create(&key).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &key, rawKey, nil
+}
+
+// ListAPIKeys returns the keys owned by userID, newest first
+func (s *Service) ListAPIKeys(userID uint) ([]APIKey, error) {
+	var keys []APIKey
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key owned by userID
+func (s *Service) RevokeAPIKey(userID, keyID uint) error {
+	now := time.Now()
+	result := s.db.Model(&APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked = ?", keyID, userID, false).
+		Updates(map[string]interface{}{
+			"revoked":    true,
+			"revoked_at": &now,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// ValidateAPIKey resolves a raw key to its owner and records its use
+func (s *Service) ValidateAPIKey(rawKey string) (*User, *APIKey, error) {
+	if !IsAPIKey(rawKey) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var key APIKey
+	if err := s.db.Where("key_hash = ? AND revoked = ?", hashToken(rawKey), false).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+
+	user, err := s.GetUserByID(key.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&key).Update("last_used_at", &now).Error; err != nil {
+		log.Printf("Failed to record API key use for key %d: %v", key.ID, err)
+	}
+	key.LastUsedAt = &now
+
+	return user, &key, nil
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MaxImportUsers caps the users one import can create. Each password is
+// hashed with bcrypt, so large imports take seconds.
+const MaxImportUsers = 100
+
+var (
+	ErrEmptyImport    = errors.New("no users to import")
+	ErrImportTooLarge = fmt.Errorf("at most %d users can be imported at once", MaxImportUsers)
+	// ErrImportInvalid is returned with the per-entry results when any entry
+	// is invalid; nothing is created
+	ErrImportInvalid = errors.New("user import rejected: one or more entries are invalid")
+)
+
+// ImportUserRequest is one user of a bulk import
+type ImportUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// Role is user (the default) or admin
+	Role Role `json:"role"`
+}
+
+// Import entry statuses
+const (
+	ImportCreated = "created"
+	// ImportSkipped marks an entry whose username or email already belongs
+	// to an account, including a deleted one
+	ImportSkipped = "skipped"
+	ImportInvalid = "invalid"
+)
+
+// ImportUserResult is the outcome of one import entry, by its position in
+// the request
+type ImportUserResult struct {
+	Index    int    `json:"index"`
+	Username string `json:"username"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	User     *User  `json:"user,omitempty"`
+}
+
+// ImportUsers creates accounts in bulk, for seeding and migrations. Every
+// entry is validated first, including for usernames and emails repeated
+// within the batch; if any is invalid nothing is created and ErrImportInvalid
+// is returned with the results. Entries whose username or email is already
+// taken are skipped. The rest are created in one transaction.
+func (s *Service) ImportUsers(entries []ImportUserRequest) ([]ImportUserResult, error) {
+	if len(entries) == 0 {
+		return nil, ErrEmptyImport
+	}
+	if len(entries) > MaxImportUsers {
+		return nil, ErrImportTooLarge
+	}
+
+	results := make([]ImportUserResult, len(entries))
+	usernames := make(map[string]int, len(entries))
+	emails := make(map[string]int, len(entries))
+	invalid := false
+	for i, entry := range entries {
+		results[i] = ImportUserResult{Index: i, Username: entry.Username}
+		username, email := strings.ToLower(entry.Username), NormalizeEmail(entry.Email)
+		err := s.validateImportEntry(entry)
+		if err == nil {
+			if first, ok := usernames[username]; ok {
+				err = fmt.Errorf("username repeats entry %d", first)
+			} else if first, ok := emails[email]; ok {
+				err = fmt.Errorf("email repeats entry %d", first)
+			} else {
+				usernames[username], emails[email] = i, i
+			}
+		}
+		if err != nil {
+			results[i].Status, results[i].Error = ImportInvalid, err.Error()
+			invalid = true
+		}
+	}
+	if invalid {
+		return results, ErrImportInvalid
+	}
+
+	// Deleted users keep their username and email until purged, as for Register
+	var existing []User
+	if err := s.db.Unscoped().
+		Where("LOWER(username) IN ? OR email IN ?", mapKeys(usernames), mapKeys(emails)).
+		Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check existing users: %w", err)
+	}
+	taken := make(map[int]string)
+	for _, user := range existing {
+		if i, ok := usernames[strings.ToLower(user.Username)]; ok {
+			taken[i] = "username already exists"
+		}
+		if i, ok := emails[user.Email]; ok {
+			if _, ok := taken[i]; !ok {
+				taken[i] = "email already exists"
+			}
+		}
+	}
+
+	// Passwords are hashed before the transaction so it is not held open
+	// for the slow part
+	pending := make(map[int]*User, len(entries))
+	for i, entry := range entries {
+		if reason, ok := taken[i]; ok {
+			results[i].Status, results[i].Error = ImportSkipped, reason
+			continue
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(entry.Password), s.bcryptCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password of entry %d: %w", i, err)
+		}
+		user := &User{
+			Username: entry.Username,
+			Email:    NormalizeEmail(entry.Email),
+			Password: string(hashedPassword),
+			Role:     entry.Role,
+		}
+		if user.Role == "" {
+			user.Role = RoleUser
+		}
+		if s.adminUsernames[strings.ToLower(entry.Username)] {
+			user.Role = RoleAdmin
+		}
+		pending[i] = user
+	}
+
+	var created []*User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range entries {
+			user, ok := pending[i]
+			if !ok {
+				continue
+			}
+			if err := tx.Create(user).Error; err != nil {
+				return fmt.Errorf("failed to create user of entry %d: %w", i, err)
+			}
+			created = append(created, user)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, user := range pending {
+		results[i].Status, results[i].User = ImportCreated, user
+	}
+
+	// As for Register, a failed email does not undo the import
+	for _, user := range created {
+		if err := s.sendVerification(user); err != nil {
+			log.Printf("Failed to send verification for user %d: %v", user.ID, err)
+		}
+	}
+
+	return results, nil
+}
+
+// validateImportEntry applies the rules of registration to one import entry
+func (s *Service) validateImportEntry(entry ImportUserRequest) error {
+	if length := utf8.RuneCountInString(entry.Username); length < 3 || length > 50 {
+		return errors.New("username must be 3 to 50 characters")
+	}
+	if address, err := mail.ParseAddress(entry.Email); err != nil || address.Address != strings.TrimSpace(entry.Email) {
+		return errors.New("email is not a valid address")
+	}
+	if entry.Role != "" && entry.Role != RoleUser && entry.Role != RoleAdmin {
+		return fmt.Errorf("role must be %s or %s", RoleUser, RoleAdmin)
+	}
+	return s.passwordPolicy.Validate(entry.Password)
+}
+
+// mapKeys returns the keys of m in no particular order
+func mapKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -2,6 +2,16 @@ package auth
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Role represents a user's authorization level
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
 )
 
 // User represents a user in the system
@@ -10,8 +20,17 @@ type User struct {
 	Username  string    `json:"username" gorm:"unique;not null"`
 	Email     string    `json:"email" gorm:"unique;not null"`
 	Password  string    `json:"-" gorm:"not null"` // Never return password in JSON
+	Role      Role      `json:"role" gorm:"default:'user'"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt soft-deletes the user; deleted users are excluded from
+	// queries and cannot log in until restored
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Email verification state; the token is stored hashed
+	EmailVerified         bool       `json:"email_verified" gorm:"not null;default:false"`
+	VerificationToken     string     `json:"-" gorm:"index"`
+	VerificationExpiresAt *time.Time `json:"-"`
 }
 
 // Session represents an active user session (kept for backward compatibility)
@@ -34,7 +53,13 @@ func (s *Session) IsExpired() bool {
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ChangePasswordRequest represents a password change for the current user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
 // LoginRequest represents user login request
@@ -51,6 +76,11 @@ type AuthResponse struct {
 	Message      string `json:"message"`
 }
 
+// ResendVerificationRequest represents a request for a new verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
 // ValidateTokenRequest represents token validation request
 type ValidateTokenRequest struct {
 	Token string `json:"token" binding:"required"`
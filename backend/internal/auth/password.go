@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy describes the rules a new password must satisfy
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// DefaultPasswordPolicy matches the original minimum-length-only rule
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 6}
+
+// PasswordPolicyError lists every rule a password failed
+type PasswordPolicyError struct {
+	Failures []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password must contain " + strings.Join(e.Failures, ", ")
+}
+
+// Validate checks password against the policy, returning a *PasswordPolicyError
+// describing all failed rules, or nil if it passes
+func (p PasswordPolicy) Validate(password string) error {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	var failures []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		failures = append(failures, "at least "+strconv.Itoa(p.MinLength)+" characters")
+	}
+	if p.RequireUpper && !hasUpper {
+		failures = append(failures, "an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		failures = append(failures, "a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		failures = append(failures, "a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		failures = append(failures, "a symbol")
+	}
+
+	if len(failures) > 0 {
+		return &PasswordPolicyError{Failures: failures}
+	}
+	return nil
+}
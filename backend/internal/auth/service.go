@@ -3,6 +3,9 @@ package auth
 import (
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -10,26 +13,90 @@ import (
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/utils"
 )
 
+// ErrUserNotFound is returned when a user does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrInvalidCurrentPassword is returned when a password change supplies the wrong current password
+var ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+
 // Service handles authentication operations
 type Service struct {
-	db *gorm.DB
+	db             *gorm.DB
+	passwordPolicy PasswordPolicy
+	bcryptCost     int
+	mailer         Mailer
+	verification   VerificationConfig
+	adminUsernames map[string]bool
 }
 
 // NewService creates a new authentication service
 func NewService(db *gorm.DB) *Service {
-	return &Service{db: db}
+	return &Service{
+		db:             db,
+		passwordPolicy: DefaultPasswordPolicy,
+		bcryptCost:     bcrypt.DefaultCost,
+		verification:   VerificationConfig{TokenTTL: 24 * time.Hour},
+	}
+}
+
+// SetBcryptCost sets the work factor used when hashing new passwords.
+// Values outside bcrypt's allowed range are rejected.
+func (s *Service) SetBcryptCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	s.bcryptCost = cost
+	return nil
+}
+
+// SetPasswordPolicy replaces the policy applied to new passwords
+func (s *Service) SetPasswordPolicy(policy PasswordPolicy) {
+	s.passwordPolicy = policy
+}
+
+// SetAdminUsernames grants the admin role to the named accounts, both existing
+// ones and any registered later. Names are matched case-insensitively.
+func (s *Service) SetAdminUsernames(usernames []string) error {
+	s.adminUsernames = make(map[string]bool, len(usernames))
+	lowered := make([]string, 0, len(usernames))
+	for _, name := range usernames {
+		name = strings.ToLower(name)
+		s.adminUsernames[name] = true
+		lowered = append(lowered, name)
+	}
+
+	if len(lowered) == 0 {
+		return nil
+	}
+
+	if err := s.db.Model(&User{}).
+		Where("LOWER(username) IN ?", lowered).
+		Update("role", RoleAdmin).Error; err != nil {
+		return fmt.Errorf("failed to grant admin role: %w", err)
+	}
+
+	return nil
 }
 
 // Register creates a new user account
 func (s *Service) Register(req *RegisterRequest) (*User, error) {
-	// Check if user already exists
+	if err := s.passwordPolicy.Validate(req.Password); err != nil {
+		return nil, err
+	}
+
+	// Emails are stored normalized; usernames keep their display casing but
+	// are compared case-insensitively
+	email := NormalizeEmail(req.Email)
+
+	// Check if user already exists, including soft-deleted users, who keep
+	// their username and email until purged
 	var existingUser User
-	if err := s.db.Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser).Error; err == nil {
+	if err := s.db.Unscoped().Where("LOWER(username) = ? OR email = ?", strings.ToLower(req.Username), email).First(&existingUser).Error; err == nil {
 		return nil, errors.New("user with this username or email already exists")
 	}
 
 	// Hash password using bcrypt
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), s.bcryptCost)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -37,14 +104,23 @@ func (s *Service) Register(req *RegisterRequest) (*User, error) {
 	// Create new user
 	user := User{
 		Username: req.Username,
-		Email:    req.Email,
+		Email:    email,
 		Password: string(hashedPassword),
+		Role:     RoleUser,
+	}
+	if s.adminUsernames[strings.ToLower(req.Username)] {
+		user.Role = RoleAdmin
 	}
 
 	if err := s.db.Create(&user).Error; err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// A failed email does not undo the registration; the user can request a resend
+	if err := s.sendVerification(&user); err != nil {
+		log.Printf("Failed to send verification for user %d: %v", user.ID, err)
+	}
+
 	return &user, nil
 }
 
@@ -52,7 +128,7 @@ func (s *Service) Register(req *RegisterRequest) (*User, error) {
 func (s *Service) Login(req *LoginRequest) (*AuthResponse, error) {
 	// Find user by username
 	var user User
-	if err := s.db.Where("username = ?", req.Username).First(&user).Error; err != nil {
+	if err := s.db.Where("LOWER(username) = ?", strings.ToLower(req.Username)).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invalid username or password")
 		}
@@ -64,6 +140,10 @@ func (s *Service) Login(req *LoginRequest) (*AuthResponse, error) {
 		return nil, errors.New("invalid username or password")
 	}
 
+	if s.verification.Required && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
 	// Generate JWT tokens
 	accessToken, refreshToken, err := utils.GenerateToken(user.ID, user.Username)
 	if err != nil {
@@ -96,7 +176,7 @@ func (s *Service) ValidateToken(tokenString string) (*User, error) {
 	var user User
 	if err := s.db.First(&user, uint(userId)).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("database error: %w", err)
 	}
@@ -114,12 +194,39 @@ func (s *Service) RefreshToken(refreshToken string) (string, error) {
 	return newAccessToken, nil
 }
 
+// ChangePassword verifies the user's current password and replaces it
+func (s *Service) ChangePassword(userID uint, req *ChangePasswordRequest) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return ErrInvalidCurrentPassword
+	}
+
+	if err := s.passwordPolicy.Validate(req.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), s.bcryptCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.db.Model(user).Update("password", string(hashedPassword)).Error; err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return nil
+}
+
 // GetUserByID retrieves a user by ID
 func (s *Service) GetUserByID(userID uint) (*User, error) {
 	var user User
 	if err := s.db.First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("database error: %w", err)
 	}
@@ -135,3 +242,8 @@ func (s *Service) GetUserFromToken(tokenString string) (*User, error) {
 
 	return s.GetUserByID(userID)
 }
+
+// NormalizeEmail returns the canonical form an email is stored and compared in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
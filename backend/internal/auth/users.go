@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrUserNotDeleted is returned when restoring a user that is not deleted
+var ErrUserNotDeleted = errors.New("user is not deleted")
+
+// userPurgeInterval is how often soft-deleted users are checked for purging
+const userPurgeInterval = time.Hour
+
+// DeleteUser soft-deletes a user. The account can no longer log in or use its
+// tokens and API keys, but its data is kept until restored or purged.
+func (s *Service) DeleteUser(userID uint) error {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(user).Error; err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+// RestoreUser undoes a soft delete
+func (s *Service) RestoreUser(userID uint) (*User, error) {
+	var user User
+	if err := s.db.Unscoped().First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if !user.DeletedAt.Valid {
+		return nil, ErrUserNotDeleted
+	}
+
+	if err := s.db.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+
+	return &user, nil
+}
+
+// PurgeDeletedUsers permanently deletes users soft-deleted more than olderThan
+// ago, along with their sessions and API keys. Audit entries are kept.
+func (s *Service) PurgeDeletedUsers(olderThan time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-olderThan)
+
+	var ids []uint
+	if err := s.db.Unscoped().Model(&User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to find deleted users: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var purged int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id IN ?", ids).Delete(&APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&Session{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("id IN ?", ids).Delete(&User{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+
+	return purged, nil
+}
+
+// StartUserPurge periodically purges users soft-deleted more than purgeAfter
+// ago until ctx is done. A non-positive purgeAfter disables purging.
+func (s *Service) StartUserPurge(ctx context.Context, purgeAfter time.Duration) {
+	if purgeAfter <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(userPurgeInterval)
+	defer ticker.Stop()
+
+	log.Printf("Purging deleted users after %v", purgeAfter)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeDeletedUsers(purgeAfter)
+			if err != nil {
+				log.Printf("Error purging deleted users: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d deleted users", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrInvalidVerificationToken is returned for unknown or expired verification tokens
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// ErrEmailNotVerified is returned on login when verification is required but pending
+var ErrEmailNotVerified = errors.New("email address has not been verified")
+
+// Mailer sends email; satisfied by notify.EmailNotifier
+type Mailer interface {
+	SendEmail(to []string, subject, body string) error
+}
+
+// VerificationConfig controls the email verification flow
+type VerificationConfig struct {
+	// Required blocks login until the account's email is verified
+	Required bool
+	// TokenTTL is how long a verification link stays valid
+	TokenTTL time.Duration
+	// BaseURL is the public URL the verification link points at
+	BaseURL string
+}
+
+// SetMailer sets the mailer used to send verification emails
+func (s *Service) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// SetVerificationConfig configures the email verification flow
+func (s *Service) SetVerificationConfig(cfg VerificationConfig) {
+	s.verification = cfg
+}
+
+// VerifyEmail marks the account owning token as verified
+func (s *Service) VerifyEmail(token string) (*User, error) {
+	var user User
+	err := s.db.Where("verification_token = ?", hashToken(token)).First(&user).Error
+	if err != nil || user.VerificationExpiresAt == nil || time.Now().After(*user.VerificationExpiresAt) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if err := s.db.Model(&user).Updates(map[string]interface{}{
+		"email_verified":          true,
+		"verification_token":      "",
+		"verification_expires_at": nil,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return &user, nil
+}
+
+// ResendVerification issues a fresh verification token for an unverified account.
+// Unknown or already verified emails are ignored so callers cannot probe for accounts.
+func (s *Service) ResendVerification(email string) error {
+	var user User
+	if err := s.db.Where("email = ? AND email_verified = ?", NormalizeEmail(email), false).First(&user).Error; err != nil {
+		return nil
+	}
+
+	return s.sendVerification(&user)
+}
+
+// sendVerification stores a new verification token for user and emails the link
+func (s *Service) sendVerification(user *User) error {
+	token, err := generateToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.verification.TokenTTL)
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"verification_token":      hashToken(token),
+		"verification_expires_at": expiresAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to store verification token: %w", err)
+	}
+
+	if s.mailer == nil {
+		log.Printf("No mailer configured; verification email not sent to user %d", user.ID)
+		return nil
+	}
+
+	link := fmt.Sprintf("%s/api/v1/auth/verify?token=%s", s.verification.BaseURL, token)
+	body := fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+		user.Username, link, s.verification.TokenTTL)
+
+	if err := s.mailer.SendEmail([]string{user.Email}, "Verify your CodeXray account", body); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
+// generateToken returns a random hex token
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken returns the SHA-256 digest stored in place of a raw token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,56 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time. Services take one instead of calling
+// time.Now, so tests can control time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is the system clock
+type Real struct{}
+
+// Now returns time.Now()
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Mock is a clock that only moves when told to, for tests of cooldowns,
+// durations, expiry and retention. It is safe for concurrent use.
+type Mock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewMock returns a mock clock set to now
+func NewMock(now time.Time) *Mock {
+	return &Mock{now: now}
+}
+
+// Now returns the mock's current time
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.now
+}
+
+// Set moves the clock to now, which may be in the past
+func (m *Mock) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = now
+}
+
+// Advance moves the clock forward by d
+func (m *Mock) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+}
@@ -2,9 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Config holds all configuration for the application
@@ -13,25 +18,182 @@ type Config struct {
 	Database DatabaseConfig `mapstructure:"database"`
 	Auth     AuthConfig     `mapstructure:"auth"`
 	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Logs     LogsConfig     `mapstructure:"logs"`
+	SMTP     SMTPConfig     `mapstructure:"smtp"`
+	Notify   NotifyConfig   `mapstructure:"notify"`
+	Alerts   AlertsConfig   `mapstructure:"alerts"`
+	Logging  LoggingConfig  `mapstructure:"logging"`
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Port         string        `mapstructure:"port"`
 	Host         string        `mapstructure:"host"`
+	PublicURL    string        `mapstructure:"public_url"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+
+	// CORSAllowedOrigins lists origins allowed to call the API; "*" allows any
+	CORSAllowedOrigins []string `mapstructure:"cors_allowed_origins"`
+	// TrustedProxies lists the proxy IPs or CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers are believed for the client IP; "none" trusts none
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// ContentSecurityPolicy is sent as the Content-Security-Policy header
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
+	// HSTS is sent as the Strict-Transport-Security header over HTTPS
+	HSTS string `mapstructure:"hsts"`
+
+	// MaxBodyBytes limits request bodies; MaxIngestBodyBytes applies to ingest
+	MaxBodyBytes       int64 `mapstructure:"max_body_bytes"`
+	MaxIngestBodyBytes int64 `mapstructure:"max_ingest_body_bytes"`
+
+	// EnablePprof mounts the admin-only /debug/pprof profiling handlers
+	EnablePprof bool `mapstructure:"enable_pprof"`
+
+	// AllowAdminReset enables the endpoint that deletes all metrics and alerts.
+	// Leave it off outside development and tests.
+	AllowAdminReset bool `mapstructure:"allow_admin_reset"`
+
+	// AllowAdminMigrate enables the endpoint that re-runs database
+	// migrations and data fixups on demand
+	AllowAdminMigrate bool `mapstructure:"allow_admin_migrate"`
+
+	// IdempotencyKeyTTL is how long an Idempotency-Key and its response are
+	// remembered
+	IdempotencyKeyTTL time.Duration `mapstructure:"idempotency_key_ttl"`
+
+	// SummaryCacheTTL is how long /summary responses are reused; a negative
+	// value disables the cache
+	SummaryCacheTTL time.Duration `mapstructure:"summary_cache_ttl"`
+
+	// MaxStreamClients caps concurrent streaming responses; a negative value
+	// removes the cap
+	MaxStreamClients int `mapstructure:"max_stream_clients"`
 }
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
 	URL string `mapstructure:"url"`
+
+	// DataDir holds SQLite database files given by a relative path
+	DataDir string `mapstructure:"data_dir"`
+	// SQLiteJournalMode and SQLiteBusyTimeout apply to file-based SQLite
+	SQLiteJournalMode string        `mapstructure:"sqlite_journal_mode"`
+	SQLiteBusyTimeout time.Duration `mapstructure:"sqlite_busy_timeout"`
+
+	// IntegrityCheckInterval is how often stored data is checked for invalid
+	// rows; a negative value disables the check. IntegrityCheckFix repairs
+	// what can be repaired rather than only logging it.
+	IntegrityCheckInterval time.Duration `mapstructure:"integrity_check_interval"`
+	IntegrityCheckFix      bool          `mapstructure:"integrity_check_fix"`
 }
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
 	JWTSecret       string        `mapstructure:"jwt_secret"`
 	SessionDuration time.Duration `mapstructure:"session_duration"`
+
+	// Password policy applied on registration and password change
+	PasswordMinLength     int  `mapstructure:"password_min_length"`
+	PasswordRequireUpper  bool `mapstructure:"password_require_upper"`
+	PasswordRequireLower  bool `mapstructure:"password_require_lower"`
+	PasswordRequireDigit  bool `mapstructure:"password_require_digit"`
+	PasswordRequireSymbol bool `mapstructure:"password_require_symbol"`
+
+	// BcryptCost is the work factor for password hashes (4-31)
+	BcryptCost int `mapstructure:"bcrypt_cost"`
+
+	// RequireEmailVerification blocks login for accounts that have not verified their email
+	RequireEmailVerification bool          `mapstructure:"require_email_verification"`
+	VerificationTokenTTL     time.Duration `mapstructure:"verification_token_ttl"`
+
+	// AdminUsernames are granted the admin role at startup and on registration
+	AdminUsernames []string `mapstructure:"admin_usernames"`
+
+	// UserPurgeAfterDays permanently deletes users this many days after they
+	// were soft-deleted; zero keeps deleted users indefinitely
+	UserPurgeAfterDays int `mapstructure:"user_purge_after_days"`
+}
+
+// SMTPConfig holds outgoing email configuration
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// NotifyConfig holds alert notification channels; empty values disable a channel
+type NotifyConfig struct {
+	SlackWebhookURL string   `mapstructure:"slack_webhook_url"`
+	WebhookURL      string   `mapstructure:"webhook_url"`
+	EmailTo         []string `mapstructure:"email_to"`
+
+	// QuietHours are daily windows such as "22:00-07:00" in QuietHoursTimezone
+	// during which only alerts at or above QuietMinSeverity are notified
+	QuietHours         string `mapstructure:"quiet_hours"`
+	QuietHoursTimezone string `mapstructure:"quiet_hours_timezone"`
+	QuietMinSeverity   string `mapstructure:"quiet_min_severity"`
+
+	// FallbackMinSeverity limits the channels above to alerts no user
+	// subscribed to that are at or above this severity (default low)
+	FallbackMinSeverity string `mapstructure:"fallback_min_severity"`
+
+	// Routes picks the channels above per alert severity and metric type,
+	// e.g. "critical:webhook|slack,low:slack"
+	Routes string `mapstructure:"routes"`
+
+	// Workers deliveries run at once, with up to QueueSize more waiting;
+	// QueueOverflow is drop or block
+	Workers       int    `mapstructure:"workers"`
+	QueueSize     int    `mapstructure:"queue_size"`
+	QueueOverflow string `mapstructure:"queue_overflow"`
+}
+
+// AlertsConfig holds alert generation configuration
+type AlertsConfig struct {
+	// MessageTemplate is a text/template for all alert messages
+	MessageTemplate string `mapstructure:"message_template"`
+	// MessageTemplates overrides MessageTemplate per metric type, read from
+	// ALERT_MESSAGE_TEMPLATE_<METRIC_TYPE> (e.g. ALERT_MESSAGE_TEMPLATE_CPU_USAGE)
+	MessageTemplates map[string]string `mapstructure:"message_templates"`
+
+	// IncidentWindow groups threshold alerts firing within this long of an
+	// open incident's latest alert into that incident
+	IncidentWindow time.Duration `mapstructure:"incident_window"`
+
+	// StalenessWindow raises an alert when a type in StalenessTypes has had
+	// no new data for this long; a negative value disables the watch
+	StalenessWindow time.Duration `mapstructure:"staleness_window"`
+	StalenessTypes  []string      `mapstructure:"staleness_types"`
+
+	// MaxQueryLimit caps how many alerts one request can return; larger
+	// limits are clamped to it
+	MaxQueryLimit int `mapstructure:"max_query_limit"`
+
+	// WarmupPeriod suppresses threshold alerts for this long after startup
+	// (default: one collection interval); a negative value disables it
+	WarmupPeriod time.Duration `mapstructure:"warmup_period"`
+
+	// FlapWindow and FlapTransitions raise a flapping alert when a metric
+	// type's alert triggers or resolves more than FlapTransitions times
+	// within FlapWindow; a negative window disables detection
+	FlapWindow      time.Duration `mapstructure:"flap_window"`
+	FlapTransitions int           `mapstructure:"flap_transitions"`
+
+	// RealertCooldown holds back a threshold alert for this long after the
+	// last one for the same metric type and mount resolved; zero disables it
+	RealertCooldown time.Duration `mapstructure:"realert_cooldown"`
+
+	// RetentionPeriod is how long resolved alerts are kept before being
+	// deleted, independently of metric retention; a negative value keeps
+	// them forever
+	RetentionPeriod time.Duration `mapstructure:"retention_period"`
+
+	// ComparisonEpsilon is how far past its threshold a value must be to
+	// breach it; zero or a negative value compares exactly
+	ComparisonEpsilon float64 `mapstructure:"comparison_epsilon"`
 }
 
 // MetricsConfig holds metrics collection configuration
@@ -39,6 +201,92 @@ type MetricsConfig struct {
 	CollectionInterval time.Duration `mapstructure:"collection_interval"`
 	CPUThreshold       float64       `mapstructure:"cpu_threshold"`
 	MemoryThreshold    float64       `mapstructure:"memory_threshold"`
+
+	// CollectionJitter moves each collection cycle by up to this fraction of
+	// CollectionInterval, so agents started together spread their writes
+	CollectionJitter float64 `mapstructure:"collection_jitter"`
+
+	// StartDelay postpones the first collection after startup, separately
+	// from the alert warmup
+	StartDelay time.Duration `mapstructure:"start_delay"`
+
+	// AlertCheckInterval is how often thresholds are checked against the
+	// latest metrics, independently of CollectionInterval
+	AlertCheckInterval time.Duration `mapstructure:"alert_check_interval"`
+	// AlertCheckOnCollect checks thresholds right after every collection
+	// cycle instead of on the AlertCheckInterval timer
+	AlertCheckOnCollect bool `mapstructure:"alert_check_on_collect"`
+
+	// CPUSampleInterval is the blocking window for on-demand CPU samples
+	CPUSampleInterval time.Duration `mapstructure:"cpu_sample_interval"`
+
+	// CPUModes also collects the share of CPU time per mode (user, system,
+	// iowait, idle)
+	CPUModes bool `mapstructure:"cpu_modes"`
+
+	// CollectorTimeout bounds each collector within a collection cycle
+	CollectorTimeout time.Duration `mapstructure:"collector_timeout"`
+
+	// RecentBufferSize is the number of samples per type kept in memory
+	RecentBufferSize int `mapstructure:"recent_buffer_size"`
+
+	// CgroupMode is "auto", "host" or "container" and decides whether CPU and
+	// memory are reported relative to the host or to container (cgroup) limits
+	CgroupMode string `mapstructure:"cgroup_mode"`
+
+	// IngestMode is "atomic" or "partial" and decides whether a batch with
+	// invalid records is rejected or stored without them
+	IngestMode string `mapstructure:"ingest_mode"`
+
+	// OutOfRange is "clamp" or "reject" and decides what happens to
+	// collected values outside their metric type's valid range
+	OutOfRange string `mapstructure:"out_of_range"`
+
+	// TypeMetadata sets the display name, unit, description and range of
+	// metric types, keyed by type and read from METRIC_TYPE_<TYPE> variables
+	TypeMetadata map[string]string `mapstructure:"type_metadata"`
+
+	// Labels are name=value pairs attached to every collected metric
+	Labels []string `mapstructure:"labels"`
+	// IngestMaxBatch caps the number of records per ingest request
+	IngestMaxBatch int `mapstructure:"ingest_max_batch"`
+
+	// DiskMounts are the mount points whose usage is collected
+	DiskMounts []string `mapstructure:"disk_mounts"`
+	// DiskThresholds overrides the default disk usage threshold per mount
+	DiskThresholds map[string]float64 `mapstructure:"disk_thresholds"`
+
+	// NetworkInterfaces restricts network rates to the named interfaces;
+	// empty sums all interfaces, excluding loopback unless NetworkIncludeLoopback
+	NetworkInterfaces      []string `mapstructure:"network_interfaces"`
+	NetworkIncludeLoopback bool     `mapstructure:"network_include_loopback"`
+
+	// PersistCounters stores the counters behind rate metrics so rates carry
+	// on across a restart no longer than CounterMaxGap
+	PersistCounters bool          `mapstructure:"persist_counters"`
+	CounterMaxGap   time.Duration `mapstructure:"counter_max_gap"`
+
+	// Rollup compaction; RawRetention of zero disables it
+	RawRetention       time.Duration `mapstructure:"raw_retention"`
+	HourlyRetention    time.Duration `mapstructure:"hourly_retention"`
+	CompactionInterval time.Duration `mapstructure:"compaction_interval"`
+}
+
+// LoggingConfig holds the service's own log output configuration
+type LoggingConfig struct {
+	// Format is json or text; empty picks text in debug mode, json otherwise
+	Format string `mapstructure:"format"`
+	// Color is auto, always or never and colors text output by level
+	Color string `mapstructure:"color"`
+}
+
+// LogsConfig holds log analysis configuration
+type LogsConfig struct {
+	// LevelMapping overrides JSON log level mapping, e.g. "verbose:DEBUG,notice:INFO"
+	LevelMapping string `mapstructure:"level_mapping"`
+	// ContinuationPattern matches lines appended to the preceding entry,
+	// such as stack trace frames; empty uses the default, "none" disables it
+	ContinuationPattern string `mapstructure:"continuation_pattern"`
 }
 
 // Load loads configuration from .env file and environment variables
@@ -62,33 +310,221 @@ func Load() (*Config, error) {
 	// Enable automatic environment variable reading
 	viper.AutomaticEnv()
 
+	// Secrets mounted as files take precedence over the variables themselves
+	if err := loadSecretFiles(); err != nil {
+		return nil, err
+	}
+
 	// Map environment variables to config structure
 	viper.BindEnv("DATABASE_URL")
+	viper.BindEnv("DATA_DIR")
+	viper.BindEnv("SQLITE_JOURNAL_MODE")
+	viper.BindEnv("SQLITE_BUSY_TIMEOUT")
+	viper.BindEnv("INTEGRITY_CHECK_INTERVAL")
+	viper.BindEnv("INTEGRITY_CHECK_FIX")
 	viper.BindEnv("PORT")
 	viper.BindEnv("JWT_SECRET")
 	viper.BindEnv("ACCESS_TOKEN_SECRET")
 	viper.BindEnv("CPU_THRESHOLD")
 	viper.BindEnv("MEMORY_THRESHOLD")
+	viper.BindEnv("LOG_FORMAT")
+	viper.BindEnv("LOG_COLOR")
+	viper.BindEnv("LOG_LEVEL_MAPPING")
+	viper.BindEnv("LOG_CONTINUATION_PATTERN")
+	viper.BindEnv("METRICS_ALERT_CHECK_INTERVAL")
+	viper.BindEnv("METRICS_ALERT_CHECK_ON_COLLECT")
+	viper.BindEnv("METRICS_CPU_SAMPLE_INTERVAL")
+	viper.BindEnv("METRICS_CPU_MODES")
+	viper.BindEnv("METRICS_COLLECTOR_TIMEOUT")
+	viper.BindEnv("METRICS_COLLECTION_JITTER")
+	viper.BindEnv("METRICS_START_DELAY")
+	viper.BindEnv("METRICS_RECENT_BUFFER_SIZE")
+	viper.BindEnv("METRICS_DISK_MOUNTS")
+	viper.BindEnv("METRICS_NETWORK_INTERFACES")
+	viper.BindEnv("METRICS_NETWORK_INCLUDE_LOOPBACK")
+	viper.BindEnv("METRICS_PERSIST_COUNTERS")
+	viper.BindEnv("METRICS_COUNTER_MAX_GAP")
+	viper.BindEnv("METRICS_CGROUP_MODE")
+	viper.BindEnv("METRICS_INGEST_MODE")
+	viper.BindEnv("METRICS_OUT_OF_RANGE")
+	viper.BindEnv("METRICS_LABELS")
+	viper.BindEnv("METRICS_INGEST_MAX_BATCH")
+	viper.BindEnv("DISK_THRESHOLDS")
+	viper.BindEnv("METRICS_RAW_RETENTION")
+	viper.BindEnv("METRICS_HOURLY_RETENTION")
+	viper.BindEnv("METRICS_COMPACTION_INTERVAL")
+	viper.BindEnv("PASSWORD_MIN_LENGTH")
+	viper.BindEnv("PASSWORD_REQUIRE_UPPER")
+	viper.BindEnv("PASSWORD_REQUIRE_LOWER")
+	viper.BindEnv("PASSWORD_REQUIRE_DIGIT")
+	viper.BindEnv("PASSWORD_REQUIRE_SYMBOL")
+	viper.BindEnv("BCRYPT_COST")
+	viper.BindEnv("PUBLIC_URL")
+	viper.BindEnv("ALLOW_ADMIN_RESET")
+	viper.BindEnv("ALLOW_ADMIN_MIGRATE")
+	viper.BindEnv("ENABLE_PPROF")
+	viper.BindEnv("CORS_ALLOWED_ORIGINS")
+	viper.BindEnv("TRUSTED_PROXIES")
+	viper.BindEnv("SECURITY_CSP")
+	viper.BindEnv("SECURITY_HSTS")
+	viper.BindEnv("MAX_BODY_BYTES")
+	viper.BindEnv("MAX_INGEST_BODY_BYTES")
+	viper.BindEnv("IDEMPOTENCY_KEY_TTL")
+	viper.BindEnv("SUMMARY_CACHE_TTL")
+	viper.BindEnv("MAX_STREAM_CLIENTS")
+	viper.BindEnv("REQUIRE_EMAIL_VERIFICATION")
+	viper.BindEnv("ADMIN_USERNAMES")
+	viper.BindEnv("USER_PURGE_AFTER_DAYS")
+	viper.BindEnv("ALERT_MESSAGE_TEMPLATE")
+	viper.BindEnv("ALERT_INCIDENT_WINDOW")
+	viper.BindEnv("ALERT_STALENESS_WINDOW")
+	viper.BindEnv("ALERT_STALENESS_TYPES")
+	viper.BindEnv("ALERT_WARMUP_PERIOD")
+	viper.BindEnv("ALERT_MAX_QUERY_LIMIT")
+	viper.BindEnv("ALERT_FLAP_WINDOW")
+	viper.BindEnv("ALERT_FLAP_TRANSITIONS")
+	viper.BindEnv("ALERT_REALERT_COOLDOWN")
+	viper.BindEnv("ALERT_RETENTION_PERIOD")
+	viper.BindEnv("ALERT_COMPARISON_EPSILON")
+	viper.BindEnv("VERIFICATION_TOKEN_TTL")
+	viper.BindEnv("SMTP_HOST")
+	viper.BindEnv("SMTP_PORT")
+	viper.BindEnv("SMTP_USERNAME")
+	viper.BindEnv("SMTP_PASSWORD")
+	viper.BindEnv("SMTP_FROM")
+	viper.BindEnv("SLACK_WEBHOOK_URL")
+	viper.BindEnv("ALERT_WEBHOOK_URL")
+	viper.BindEnv("ALERT_EMAIL_TO")
+	viper.BindEnv("NOTIFY_QUIET_HOURS")
+	viper.BindEnv("NOTIFY_QUIET_HOURS_TZ")
+	viper.BindEnv("NOTIFY_QUIET_MIN_SEVERITY")
+	viper.BindEnv("NOTIFY_FALLBACK_MIN_SEVERITY")
+	viper.BindEnv("NOTIFY_ROUTES")
+	viper.BindEnv("NOTIFY_WORKERS")
+	viper.BindEnv("NOTIFY_QUEUE_SIZE")
+	viper.BindEnv("NOTIFY_QUEUE_OVERFLOW")
 
 	// Create config with direct viper calls
 	config := &Config{
 		Server: ServerConfig{
 			Port:         viper.GetString("PORT"),
 			Host:         viper.GetString("HOST"),
+			PublicURL:    viper.GetString("PUBLIC_URL"),
 			ReadTimeout:  viper.GetDuration("server.read_timeout"),
 			WriteTimeout: viper.GetDuration("server.write_timeout"),
+
+			CORSAllowedOrigins:    splitList(viper.GetString("CORS_ALLOWED_ORIGINS")),
+			TrustedProxies:        splitList(viper.GetString("TRUSTED_PROXIES")),
+			ContentSecurityPolicy: viper.GetString("SECURITY_CSP"),
+			HSTS:                  viper.GetString("SECURITY_HSTS"),
+			MaxBodyBytes:          viper.GetInt64("MAX_BODY_BYTES"),
+			MaxIngestBodyBytes:    viper.GetInt64("MAX_INGEST_BODY_BYTES"),
+
+			EnablePprof:       viper.GetBool("ENABLE_PPROF"),
+			AllowAdminReset:   viper.GetBool("ALLOW_ADMIN_RESET"),
+			AllowAdminMigrate: viper.GetBool("ALLOW_ADMIN_MIGRATE"),
+
+			IdempotencyKeyTTL: viper.GetDuration("IDEMPOTENCY_KEY_TTL"),
+			SummaryCacheTTL:   viper.GetDuration("SUMMARY_CACHE_TTL"),
+			MaxStreamClients:  viper.GetInt("MAX_STREAM_CLIENTS"),
 		},
 		Database: DatabaseConfig{
-			URL: viper.GetString("DATABASE_URL"),
+			URL:               viper.GetString("DATABASE_URL"),
+			DataDir:           viper.GetString("DATA_DIR"),
+			SQLiteJournalMode: viper.GetString("SQLITE_JOURNAL_MODE"),
+			SQLiteBusyTimeout: viper.GetDuration("SQLITE_BUSY_TIMEOUT"),
+
+			IntegrityCheckInterval: viper.GetDuration("INTEGRITY_CHECK_INTERVAL"),
+			IntegrityCheckFix:      viper.GetBool("INTEGRITY_CHECK_FIX"),
 		},
 		Auth: AuthConfig{
 			JWTSecret:       getJWTSecret(),
 			SessionDuration: viper.GetDuration("auth.session_duration"),
+
+			PasswordMinLength:     viper.GetInt("PASSWORD_MIN_LENGTH"),
+			PasswordRequireUpper:  viper.GetBool("PASSWORD_REQUIRE_UPPER"),
+			PasswordRequireLower:  viper.GetBool("PASSWORD_REQUIRE_LOWER"),
+			PasswordRequireDigit:  viper.GetBool("PASSWORD_REQUIRE_DIGIT"),
+			PasswordRequireSymbol: viper.GetBool("PASSWORD_REQUIRE_SYMBOL"),
+			BcryptCost:            viper.GetInt("BCRYPT_COST"),
+
+			RequireEmailVerification: viper.GetBool("REQUIRE_EMAIL_VERIFICATION"),
+			VerificationTokenTTL:     viper.GetDuration("VERIFICATION_TOKEN_TTL"),
+			AdminUsernames:           splitList(viper.GetString("ADMIN_USERNAMES")),
+			UserPurgeAfterDays:       viper.GetInt("USER_PURGE_AFTER_DAYS"),
 		},
 		Metrics: MetricsConfig{
-			CollectionInterval: viper.GetDuration("metrics.collection_interval"),
-			CPUThreshold:       viper.GetFloat64("CPU_THRESHOLD"),
-			MemoryThreshold:    viper.GetFloat64("MEMORY_THRESHOLD"),
+			CollectionInterval:     viper.GetDuration("metrics.collection_interval"),
+			CollectionJitter:       viper.GetFloat64("METRICS_COLLECTION_JITTER"),
+			StartDelay:             viper.GetDuration("METRICS_START_DELAY"),
+			CPUThreshold:           viper.GetFloat64("CPU_THRESHOLD"),
+			MemoryThreshold:        viper.GetFloat64("MEMORY_THRESHOLD"),
+			AlertCheckInterval:     viper.GetDuration("METRICS_ALERT_CHECK_INTERVAL"),
+			AlertCheckOnCollect:    viper.GetBool("METRICS_ALERT_CHECK_ON_COLLECT"),
+			CPUSampleInterval:      viper.GetDuration("METRICS_CPU_SAMPLE_INTERVAL"),
+			CPUModes:               viper.GetBool("METRICS_CPU_MODES"),
+			CollectorTimeout:       viper.GetDuration("METRICS_COLLECTOR_TIMEOUT"),
+			RecentBufferSize:       viper.GetInt("METRICS_RECENT_BUFFER_SIZE"),
+			DiskMounts:             splitList(viper.GetString("METRICS_DISK_MOUNTS")),
+			NetworkInterfaces:      splitList(viper.GetString("METRICS_NETWORK_INTERFACES")),
+			NetworkIncludeLoopback: viper.GetBool("METRICS_NETWORK_INCLUDE_LOOPBACK"),
+			PersistCounters:        viper.GetBool("METRICS_PERSIST_COUNTERS"),
+			CounterMaxGap:          viper.GetDuration("METRICS_COUNTER_MAX_GAP"),
+			CgroupMode:             viper.GetString("METRICS_CGROUP_MODE"),
+			IngestMode:             viper.GetString("METRICS_INGEST_MODE"),
+			OutOfRange:             viper.GetString("METRICS_OUT_OF_RANGE"),
+			Labels:                 splitList(viper.GetString("METRICS_LABELS")),
+			TypeMetadata:           prefixedValues(metricTypePrefix),
+			IngestMaxBatch:         viper.GetInt("METRICS_INGEST_MAX_BATCH"),
+			RawRetention:           viper.GetDuration("METRICS_RAW_RETENTION"),
+			HourlyRetention:        viper.GetDuration("METRICS_HOURLY_RETENTION"),
+			CompactionInterval:     viper.GetDuration("METRICS_COMPACTION_INTERVAL"),
+		},
+		Logs: LogsConfig{
+			LevelMapping:        viper.GetString("LOG_LEVEL_MAPPING"),
+			ContinuationPattern: viper.GetString("LOG_CONTINUATION_PATTERN"),
+		},
+		Logging: LoggingConfig{
+			Format: viper.GetString("LOG_FORMAT"),
+			Color:  viper.GetString("LOG_COLOR"),
+		},
+		SMTP: SMTPConfig{
+			Host:     viper.GetString("SMTP_HOST"),
+			Port:     viper.GetInt("SMTP_PORT"),
+			Username: viper.GetString("SMTP_USERNAME"),
+			Password: viper.GetString("SMTP_PASSWORD"),
+			From:     viper.GetString("SMTP_FROM"),
+		},
+		Alerts: AlertsConfig{
+			MessageTemplate:  viper.GetString("ALERT_MESSAGE_TEMPLATE"),
+			MessageTemplates: prefixedValues(messageTemplatePrefix),
+			IncidentWindow:   viper.GetDuration("ALERT_INCIDENT_WINDOW"),
+			StalenessWindow:  viper.GetDuration("ALERT_STALENESS_WINDOW"),
+			StalenessTypes:   splitList(viper.GetString("ALERT_STALENESS_TYPES")),
+			WarmupPeriod:     viper.GetDuration("ALERT_WARMUP_PERIOD"),
+			MaxQueryLimit:    viper.GetInt("ALERT_MAX_QUERY_LIMIT"),
+			FlapWindow:       viper.GetDuration("ALERT_FLAP_WINDOW"),
+			FlapTransitions:  viper.GetInt("ALERT_FLAP_TRANSITIONS"),
+			RealertCooldown:  viper.GetDuration("ALERT_REALERT_COOLDOWN"),
+			RetentionPeriod:  viper.GetDuration("ALERT_RETENTION_PERIOD"),
+
+			ComparisonEpsilon: viper.GetFloat64("ALERT_COMPARISON_EPSILON"),
+		},
+		Notify: NotifyConfig{
+			SlackWebhookURL: viper.GetString("SLACK_WEBHOOK_URL"),
+			WebhookURL:      viper.GetString("ALERT_WEBHOOK_URL"),
+			EmailTo:         splitList(viper.GetString("ALERT_EMAIL_TO")),
+
+			QuietHours:         viper.GetString("NOTIFY_QUIET_HOURS"),
+			QuietHoursTimezone: viper.GetString("NOTIFY_QUIET_HOURS_TZ"),
+			QuietMinSeverity:   viper.GetString("NOTIFY_QUIET_MIN_SEVERITY"),
+
+			FallbackMinSeverity: viper.GetString("NOTIFY_FALLBACK_MIN_SEVERITY"),
+			Routes:              viper.GetString("NOTIFY_ROUTES"),
+
+			Workers:       viper.GetInt("NOTIFY_WORKERS"),
+			QueueSize:     viper.GetInt("NOTIFY_QUEUE_SIZE"),
+			QueueOverflow: viper.GetString("NOTIFY_QUEUE_OVERFLOW"),
 		},
 	}
 
@@ -99,19 +535,187 @@ func Load() (*Config, error) {
 	if config.Server.Host == "" {
 		config.Server.Host = "localhost"
 	}
+	if config.Server.PublicURL == "" {
+		config.Server.PublicURL = fmt.Sprintf("http://%s:%s", config.Server.Host, config.Server.Port)
+	}
+	if len(config.Server.CORSAllowedOrigins) == 0 {
+		config.Server.CORSAllowedOrigins = []string{"*"}
+	}
+	if len(config.Server.TrustedProxies) == 0 {
+		config.Server.TrustedProxies = []string{"127.0.0.1", "::1"}
+	}
+	if config.Server.ContentSecurityPolicy == "" {
+		config.Server.ContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+	}
+	if config.Server.HSTS == "" {
+		config.Server.HSTS = "max-age=31536000; includeSubDomains"
+	}
+	if config.Database.SQLiteJournalMode == "" {
+		config.Database.SQLiteJournalMode = "WAL"
+	}
+	if config.Database.SQLiteBusyTimeout == 0 {
+		config.Database.SQLiteBusyTimeout = 5 * time.Second
+	}
+	if config.Database.IntegrityCheckInterval == 0 {
+		config.Database.IntegrityCheckInterval = time.Hour
+	}
+	if config.Server.MaxBodyBytes == 0 {
+		config.Server.MaxBodyBytes = 1 << 20
+	}
+	if config.Server.MaxIngestBodyBytes == 0 {
+		config.Server.MaxIngestBodyBytes = 16 << 20
+	}
+	if config.Server.IdempotencyKeyTTL == 0 {
+		config.Server.IdempotencyKeyTTL = 24 * time.Hour
+	}
+	if config.Server.SummaryCacheTTL == 0 {
+		config.Server.SummaryCacheTTL = 5 * time.Second
+	}
+	if config.Server.MaxStreamClients == 0 {
+		config.Server.MaxStreamClients = 50
+	}
 	if config.Auth.JWTSecret == "" {
 		config.Auth.JWTSecret = "your-secret-key"
 	}
+	if config.Auth.PasswordMinLength == 0 {
+		config.Auth.PasswordMinLength = 6
+	}
+	if config.Auth.VerificationTokenTTL == 0 {
+		config.Auth.VerificationTokenTTL = 24 * time.Hour
+	}
+	if config.SMTP.Port == 0 {
+		config.SMTP.Port = 587
+	}
+	if config.Auth.BcryptCost == 0 {
+		config.Auth.BcryptCost = bcrypt.DefaultCost
+	}
+	if config.Auth.BcryptCost < bcrypt.MinCost || config.Auth.BcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("BCRYPT_COST must be between %d and %d, got %d",
+			bcrypt.MinCost, bcrypt.MaxCost, config.Auth.BcryptCost)
+	}
 	if config.Metrics.CPUThreshold == 0 {
 		config.Metrics.CPUThreshold = 80.0
 	}
 	if config.Metrics.MemoryThreshold == 0 {
 		config.Metrics.MemoryThreshold = 75.0
 	}
+	if config.Metrics.CPUSampleInterval == 0 {
+		config.Metrics.CPUSampleInterval = time.Second
+	}
+	if config.Alerts.IncidentWindow == 0 {
+		config.Alerts.IncidentWindow = 5 * time.Minute
+	}
+	if config.Alerts.WarmupPeriod == 0 {
+		config.Alerts.WarmupPeriod = config.Metrics.CollectionInterval
+	}
+	if config.Alerts.StalenessWindow == 0 {
+		config.Alerts.StalenessWindow = 5 * time.Minute
+	}
+	if len(config.Alerts.StalenessTypes) == 0 {
+		config.Alerts.StalenessTypes = []string{"cpu_usage", "memory_usage"}
+	}
+	if config.Alerts.MaxQueryLimit == 0 {
+		config.Alerts.MaxQueryLimit = 500
+	}
+	if config.Alerts.FlapWindow == 0 {
+		config.Alerts.FlapWindow = 30 * time.Minute
+	}
+	if config.Alerts.FlapTransitions == 0 {
+		config.Alerts.FlapTransitions = 6
+	}
+	if config.Alerts.RetentionPeriod == 0 {
+		config.Alerts.RetentionPeriod = 90 * 24 * time.Hour
+	}
+	// Zero is a valid setting that compares exactly, so the default only
+	// applies when the variable is unset
+	if !viper.IsSet("ALERT_COMPARISON_EPSILON") {
+		config.Alerts.ComparisonEpsilon = 0.01
+	}
+	if config.Notify.Workers == 0 {
+		config.Notify.Workers = 4
+	}
+	if config.Notify.QueueSize == 0 {
+		config.Notify.QueueSize = 100
+	}
+	if config.Notify.QueueOverflow == "" {
+		config.Notify.QueueOverflow = "drop"
+	}
+	if config.Metrics.AlertCheckInterval == 0 {
+		config.Metrics.AlertCheckInterval = 30 * time.Second
+	}
+	if config.Metrics.CollectorTimeout == 0 {
+		config.Metrics.CollectorTimeout = 10 * time.Second
+	}
+	if config.Metrics.RecentBufferSize == 0 {
+		config.Metrics.RecentBufferSize = 120
+	}
+	if config.Metrics.CgroupMode == "" {
+		config.Metrics.CgroupMode = "auto"
+	}
+	if config.Metrics.IngestMode == "" {
+		config.Metrics.IngestMode = "atomic"
+	}
+	if config.Metrics.OutOfRange == "" {
+		config.Metrics.OutOfRange = "clamp"
+	}
+	if config.Metrics.IngestMaxBatch == 0 {
+		config.Metrics.IngestMaxBatch = 1000
+	}
+	if len(config.Metrics.DiskMounts) == 0 {
+		config.Metrics.DiskMounts = []string{"/"}
+	}
+	diskThresholds, err := parseMountThresholds(viper.GetString("DISK_THRESHOLDS"))
+	if err != nil {
+		return nil, err
+	}
+	config.Metrics.DiskThresholds = diskThresholds
+	if config.Metrics.CounterMaxGap == 0 {
+		config.Metrics.CounterMaxGap = 5 * time.Minute
+	}
+	if config.Metrics.CompactionInterval == 0 {
+		config.Metrics.CompactionInterval = time.Hour
+	}
 
 	return config, nil
 }
 
+// secretVariables may be read from the file named by the variable with a
+// _FILE suffix, as Docker and Kubernetes mount secrets
+var secretVariables = []string{
+	"DATABASE_URL",
+	"JWT_SECRET",
+	"ACCESS_TOKEN_SECRET",
+	"SMTP_PASSWORD",
+	"SLACK_WEBHOOK_URL",
+	"ALERT_WEBHOOK_URL",
+}
+
+// loadSecretFiles sets each secret variable whose _FILE variant is set to the
+// contents of that file, without the trailing newline. The file wins over the
+// variable itself, so a stale value left in the environment cannot shadow it.
+// An unreadable or empty file is an error rather than a silent fallback.
+func loadSecretFiles() error {
+	for _, name := range secretVariables {
+		fileVar := name + "_FILE"
+		viper.BindEnv(fileVar)
+
+		path := viper.GetString(fileVar)
+		if path == "" {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", fileVar, err)
+		}
+		value := strings.TrimRight(string(content), "\r\n")
+		if value == "" {
+			return fmt.Errorf("%s points to an empty file: %s", fileVar, path)
+		}
+		viper.Set(name, value)
+	}
+	return nil
+}
+
 // getJWTSecret tries multiple environment variables for JWT secret
 func getJWTSecret() string {
 	if secret := viper.GetString("JWT_SECRET"); secret != "" {
@@ -123,6 +727,65 @@ func getJWTSecret() string {
 	return ""
 }
 
+// messageTemplatePrefix prefixes per-metric-type alert message template variables
+const messageTemplatePrefix = "ALERT_MESSAGE_TEMPLATE_"
+
+// metricTypePrefix prefixes metric type metadata variables
+const metricTypePrefix = "METRIC_TYPE_"
+
+// prefixedValues collects variables named prefix+SUFFIX from the environment
+// and .env file, keyed by the lowercased suffix
+func prefixedValues(prefix string) map[string]string {
+	values := make(map[string]string)
+
+	keys := viper.AllKeys()
+	for _, env := range os.Environ() {
+		if name, _, ok := strings.Cut(env, "="); ok {
+			keys = append(keys, name)
+		}
+	}
+
+	for _, key := range keys {
+		upper := strings.ToUpper(key)
+		if !strings.HasPrefix(upper, prefix) || upper == prefix {
+			continue
+		}
+		if value := viper.GetString(key); value != "" {
+			values[strings.ToLower(strings.TrimPrefix(upper, prefix))] = value
+		}
+	}
+
+	return values
+}
+
+// parseMountThresholds parses "mount:percent" pairs such as "/var:85,/data:95"
+func parseMountThresholds(value string) (map[string]float64, error) {
+	thresholds := make(map[string]float64)
+	for _, item := range splitList(value) {
+		idx := strings.LastIndex(item, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid DISK_THRESHOLDS entry %q, expected mount:percent", item)
+		}
+		threshold, err := strconv.ParseFloat(item[idx+1:], 64)
+		if err != nil || threshold <= 0 || threshold > 100 {
+			return nil, fmt.Errorf("invalid DISK_THRESHOLDS percent in %q", item)
+		}
+		thresholds[item[:idx]] = threshold
+	}
+	return thresholds, nil
+}
+
+// splitList splits a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // setDefaults sets default configuration values
 func setDefaults() {
 	// Server defaults
@@ -148,3 +811,61 @@ func setDefaults() {
 func (c *Config) GetDatabaseDSN() string {
 	return c.Database.URL
 }
+
+// RedactedValue replaces secrets in Redacted
+const RedactedValue = "[REDACTED]"
+
+// Redacted returns a copy of the configuration safe to share: the JWT secret,
+// SMTP password and webhook URLs are replaced with RedactedValue, and so is
+// the password in the database URL. The receiver is not modified.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Auth.JWTSecret = redactSecret(c.Auth.JWTSecret)
+	redacted.SMTP.Password = redactSecret(c.SMTP.Password)
+	redacted.Notify.SlackWebhookURL = redactSecret(c.Notify.SlackWebhookURL)
+	redacted.Notify.WebhookURL = redactSecret(c.Notify.WebhookURL)
+	redacted.Database.URL = redactDatabaseURL(c.Database.URL)
+	return &redacted
+}
+
+// redactSecret hides a secret while keeping whether it was set
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+// redactDatabaseURL hides the password of a postgres:// URL or of key=value
+// connection options; SQLite paths carry no secrets and are kept as is
+func redactDatabaseURL(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		parsed, err := url.Parse(dsn)
+		if err != nil {
+			return RedactedValue
+		}
+		if parsed.User != nil {
+			if _, ok := parsed.User.Password(); ok {
+				parsed.User = url.UserPassword(parsed.User.Username(), RedactedValue)
+			}
+		}
+		query := parsed.Query()
+		if query.Has("password") {
+			query.Set("password", RedactedValue)
+			parsed.RawQuery = query.Encode()
+		}
+		// Keep the marker readable rather than percent-encoded
+		return strings.Replace(parsed.String(), url.QueryEscape(RedactedValue), RedactedValue, -1)
+	}
+
+	if !strings.Contains(dsn, "password=") {
+		return dsn
+	}
+	fields := strings.Fields(dsn)
+	for i, field := range fields {
+		if key, _, ok := strings.Cut(field, "="); ok && key == "password" {
+			fields[i] = "password=" + RedactedValue
+		}
+	}
+	return strings.Join(fields, " ")
+}
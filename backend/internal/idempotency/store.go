@@ -0,0 +1,170 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultTTL is how long a key is remembered when no TTL is configured
+const DefaultTTL = 24 * time.Hour
+
+// MaxKeyLength bounds the Idempotency-Key header
+const MaxKeyLength = 255
+
+// InFlightTimeout is how long a key may stay reserved before the request
+// holding it is taken to have died without releasing it
+const InFlightTimeout = 5 * time.Minute
+
+// purgeInterval is how often expired keys are deleted
+const purgeInterval = time.Hour
+
+var (
+	// ErrInProgress is returned when a request with the same key is still running
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+	// ErrKeyReused is returned when a key is repeated with a different request body
+	ErrKeyReused = errors.New("idempotency key was already used with a different request")
+)
+
+// Record is a stored idempotency key and the response it produced. A record
+// with a zero StatusCode is reserved by a request that has not finished.
+type Record struct {
+	ID          uint      `gorm:"primaryKey"`
+	Scope       string    `gorm:"not null;uniqueIndex:idx_idempotency_scope_key"`
+	Key         string    `gorm:"not null;uniqueIndex:idx_idempotency_scope_key"`
+	RequestHash string    `gorm:"not null"`
+	StatusCode  int       `gorm:"not null;default:0"`
+	Body        []byte    `gorm:""`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+}
+
+// TableName keeps the table name readable
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+// Store remembers the responses to requests carrying an idempotency key, so
+// a retried request can be answered without being run again
+type Store struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewStore creates a store keeping keys for ttl (DefaultTTL if not positive)
+func NewStore(db *gorm.DB, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{db: db, ttl: ttl}
+}
+
+// Begin reserves key within scope for a request whose body hashes to
+// requestHash. It returns nil if the request should run, or the stored record
+// to replay if the key has already completed. Expired keys, and keys reserved
+// for longer than InFlightTimeout without completing, are reused.
+func (s *Store) Begin(scope, key, requestHash string) (*Record, error) {
+	now := time.Now()
+	record := Record{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			return nil, nil
+		}
+
+		var existing Record
+		if err := s.db.Where("scope = ? AND key = ?", scope, key).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Deleted between the insert and the lookup; try again
+				record.ID = 0
+				continue
+			}
+			return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+		}
+
+		if existing.ExpiresAt.Before(now) {
+			if err := s.db.Delete(&existing).Error; err != nil {
+				return nil, fmt.Errorf("failed to expire idempotency key: %w", err)
+			}
+			record.ID = 0
+			continue
+		}
+		if existing.StatusCode == 0 && existing.CreatedAt.Before(now.Add(-InFlightTimeout)) {
+			// Only deleted if it is still unfinished
+			if err := s.db.Where("status_code = 0").Delete(&existing).Error; err != nil {
+				return nil, fmt.Errorf("failed to release abandoned idempotency key: %w", err)
+			}
+			record.ID = 0
+			continue
+		}
+		if existing.RequestHash != requestHash {
+			return nil, ErrKeyReused
+		}
+		if existing.StatusCode == 0 {
+			return nil, ErrInProgress
+		}
+		return &existing, nil
+	}
+
+	return nil, ErrInProgress
+}
+
+// Complete stores the response for a reserved key
+func (s *Store) Complete(scope, key string, statusCode int, body []byte) error {
+	err := s.db.Model(&Record{}).
+		Where("scope = ? AND key = ?", scope, key).
+		Updates(map[string]interface{}{"status_code": statusCode, "body": body}).Error
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release forgets a reserved key so the request can be retried, for
+// requests that failed without a response worth replaying
+func (s *Store) Release(scope, key string) error {
+	if err := s.db.Where("scope = ? AND key = ?", scope, key).Delete(&Record{}).Error; err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes keys whose window has passed
+func (s *Store) PurgeExpired() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now()).Delete(&Record{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// StartPurge periodically deletes expired keys until ctx is done
+func (s *Store) StartPurge(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.PurgeExpired(); err != nil {
+				log.Printf("Error purging idempotency keys: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package integrations
+
+import (
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+)
+
+// PrometheusNamespace prefixes every exported series name
+const PrometheusNamespace = "system_monitor"
+
+// grafanaDatasource refers to the Prometheus datasource chosen on import
+var grafanaDatasource = map[string]string{"type": "prometheus", "uid": "${DS_PROMETHEUS}"}
+
+// Dashboard layout
+const (
+	panelWidth   = 12
+	panelHeight  = 8
+	gridColumns  = 24
+	dashboardUID = "system-monitor"
+)
+
+// prometheusUnitSuffixes names each base unit the way Prometheus series do
+var prometheusUnitSuffixes = map[string]string{
+	metrics.UnitPercent:        "percent",
+	metrics.UnitBytes:          "bytes",
+	metrics.UnitBytesPerSecond: "bytes_per_second",
+	metrics.UnitSeconds:        "seconds",
+}
+
+// grafanaUnits maps each base unit to Grafana's unit id
+var grafanaUnits = map[string]string{
+	metrics.UnitPercent:        "percent",
+	metrics.UnitBytes:          "bytes",
+	metrics.UnitBytesPerSecond: "Bps",
+	metrics.UnitSeconds:        "s",
+}
+
+// PrometheusName returns the series name a metric type is exported under,
+// e.g. system_monitor_cpu_usage_percent
+func PrometheusName(info metrics.TypeInfo) string {
+	name := PrometheusNamespace + "_" + string(info.Type)
+	if suffix, ok := prometheusUnitSuffixes[info.Unit]; ok {
+		name += "_" + suffix
+	}
+	return name
+}
+
+// GrafanaDashboard is an importable Grafana dashboard
+type GrafanaDashboard struct {
+	Inputs        []GrafanaInput    `json:"__inputs"`
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	Timezone      string            `json:"timezone"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Refresh       string            `json:"refresh"`
+	Time          map[string]string `json:"time"`
+	Panels        []GrafanaPanel    `json:"panels"`
+}
+
+// GrafanaInput is a value Grafana asks for when the dashboard is imported
+type GrafanaInput struct {
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Type       string `json:"type"`
+	PluginID   string `json:"pluginId"`
+	PluginName string `json:"pluginName"`
+}
+
+// GrafanaPanel is a time series panel charting one metric type
+type GrafanaPanel struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Datasource  map[string]string      `json:"datasource"`
+	GridPos     map[string]int         `json:"gridPos"`
+	FieldConfig map[string]interface{} `json:"fieldConfig"`
+	Targets     []GrafanaTarget        `json:"targets"`
+}
+
+// GrafanaTarget is a panel's Prometheus query
+type GrafanaTarget struct {
+	RefID        string            `json:"refId"`
+	Datasource   map[string]string `json:"datasource"`
+	Expr         string            `json:"expr"`
+	LegendFormat string            `json:"legendFormat"`
+}
+
+// NewGrafanaDashboard builds a dashboard with a panel for each registered
+// metric type, querying the series named by PrometheusName
+func NewGrafanaDashboard() *GrafanaDashboard {
+	types := metrics.RegisteredTypes()
+	panels := make([]GrafanaPanel, 0, len(types))
+	for i, info := range types {
+		panels = append(panels, newGrafanaPanel(i, info))
+	}
+
+	return &GrafanaDashboard{
+		Inputs: []GrafanaInput{{
+			Name:       "DS_PROMETHEUS",
+			Label:      "Prometheus",
+			Type:       "datasource",
+			PluginID:   "prometheus",
+			PluginName: "Prometheus",
+		}},
+		UID:           dashboardUID,
+		Title:         "System Monitor",
+		Tags:          []string{"system-monitor"},
+		Timezone:      "browser",
+		SchemaVersion: 39,
+		Refresh:       "30s",
+		Time:          map[string]string{"from": "now-6h", "to": "now"},
+		Panels:        panels,
+	}
+}
+
+// newGrafanaPanel charts one metric type, laid out two panels per row
+func newGrafanaPanel(index int, info metrics.TypeInfo) GrafanaPanel {
+	defaults := map[string]interface{}{}
+	if unit, ok := grafanaUnits[info.Unit]; ok {
+		defaults["unit"] = unit
+	}
+	if info.Unit == metrics.UnitPercent {
+		defaults["min"] = 0
+		defaults["max"] = 100
+	}
+
+	// Disk usage is exported per mount point
+	legend := string(info.Type)
+	if info.Type == metrics.DiskUsage {
+		legend = "{{mount}}"
+	}
+
+	return GrafanaPanel{
+		ID:          index + 1,
+		Type:        "timeseries",
+		Title:       metrics.DisplayName(info.Type),
+		Description: info.Description,
+		Datasource:  grafanaDatasource,
+		GridPos: map[string]int{
+			"h": panelHeight,
+			"w": panelWidth,
+			"x": (index * panelWidth) % gridColumns,
+			"y": (index * panelWidth / gridColumns) * panelHeight,
+		},
+		FieldConfig: map[string]interface{}{
+			"defaults":  defaults,
+			"overrides": []interface{}{},
+		},
+		Targets: []GrafanaTarget{{
+			RefID:        "A",
+			Datasource:   grafanaDatasource,
+			Expr:         PrometheusName(info),
+			LegendFormat: legend,
+		}},
+	}
+}
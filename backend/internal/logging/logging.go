@@ -0,0 +1,232 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format is how log records are written
+type Format string
+
+const (
+	// FormatJSON writes one JSON object per record, for log aggregation
+	FormatJSON Format = "json"
+	// FormatText writes one human-friendly line per record
+	FormatText Format = "text"
+)
+
+// ParseFormat validates a LOG_FORMAT value. Empty gives text in debug mode
+// and JSON otherwise.
+func ParseFormat(value string, debug bool) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(value))) {
+	case "":
+		if debug {
+			return FormatText, nil
+		}
+		return FormatJSON, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatText:
+		return FormatText, nil
+	default:
+		return "", fmt.Errorf("invalid log format %q (expected json or text)", value)
+	}
+}
+
+// ColorMode decides whether text records are colored by level
+type ColorMode string
+
+const (
+	// ColorAuto colors when writing to a terminal and NO_COLOR is not set
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+// ParseColorMode validates a LOG_COLOR value; empty gives ColorAuto
+func ParseColorMode(value string) (ColorMode, error) {
+	switch mode := ColorMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return ColorAuto, nil
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid log color mode %q (expected auto, always or never)", value)
+	}
+}
+
+// enabled reports whether output to w is colored
+func (m ColorMode) enabled(w io.Writer) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return IsTerminal(w)
+}
+
+// IsTerminal reports whether w is a terminal rather than a file or pipe
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// NewHandler returns a handler writing records to w in format; text records
+// are colored by level when color says so
+func NewHandler(w io.Writer, format Format, color ColorMode) slog.Handler {
+	if format == FormatJSON {
+		return slog.NewJSONHandler(w, nil)
+	}
+	return &textHandler{
+		out:   &lockedWriter{w: w},
+		color: color.enabled(w),
+	}
+}
+
+// Setup makes a handler for w the default logger. Output of the standard log
+// package, which most of the code base uses, goes through it as info records.
+func Setup(w io.Writer, format Format, color ColorMode) {
+	slog.SetDefault(slog.New(NewHandler(w, format, color)))
+}
+
+// lockedWriter serializes writes from handlers derived with WithAttrs and
+// WithGroup, which share it
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) write(line []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.w.Write(line)
+	return err
+}
+
+// levelColors are the ANSI colors of each level in text output
+var levelColors = map[slog.Level]string{
+	slog.LevelDebug: "\x1b[90m",
+	slog.LevelInfo:  "\x1b[32m",
+	slog.LevelWarn:  "\x1b[33m",
+	slog.LevelError: "\x1b[31m",
+}
+
+const colorReset = "\x1b[0m"
+
+// textHandler writes records as "2006-01-02 15:04:05.000 INFO  message
+// key=value ..." lines
+type textHandler struct {
+	out   *lockedWriter
+	color bool
+	// attrs are the formatted attributes added with WithAttrs
+	attrs string
+	// group prefixes the keys of attributes added after WithGroup
+	group string
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	if !record.Time.IsZero() {
+		b.WriteString(record.Time.Format("2006-01-02 15:04:05.000"))
+		b.WriteByte(' ')
+	}
+
+	level := fmt.Sprintf("%-5s", record.Level.String())
+	if h.color {
+		if color, ok := levelColors[record.Level]; ok {
+			level = color + level + colorReset
+		}
+	}
+	b.WriteString(level)
+	b.WriteByte(' ')
+	b.WriteString(record.Message)
+	b.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		appendAttr(&b, h.group, attr)
+		return true
+	})
+	b.WriteByte('\n')
+
+	return h.out.write([]byte(b.String()))
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, attr := range attrs {
+		appendAttr(&b, h.group, attr)
+	}
+	clone := *h
+	clone.attrs += b.String()
+	return &clone
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group += name + "."
+	return &clone
+}
+
+// appendAttr writes attr as " key=value", flattening groups into dotted keys
+func appendAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			appendAttr(b, prefix, member)
+		}
+		return
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(prefix)
+	b.WriteString(attr.Key)
+	b.WriteByte('=')
+	b.WriteString(quoteValue(attr.Value))
+}
+
+// quoteValue formats a value, quoting it when it is empty or would not read
+// back as one token
+func quoteValue(value slog.Value) string {
+	var s string
+	switch value.Kind() {
+	case slog.KindTime:
+		s = value.Time().Format(time.RFC3339Nano)
+	default:
+		s = value.String()
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") || !strconv.CanBackquote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -2,11 +2,16 @@ package logs
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // LogLevel represents different log levels
@@ -19,11 +24,17 @@ const (
 	DEBUG LogLevel = "DEBUG"
 )
 
+// DefaultErrorRateThreshold is the error ratio above which log analysis raises an alert
+// when alerting is requested without an explicit threshold
+const DefaultErrorRateThreshold = 0.1
+
 // LogEntry represents a parsed log entry
 type LogEntry struct {
 	Level   LogLevel
 	Message string
 	Time    string
+	// Timestamp is Time parsed, or zero if it was missing or unrecognized
+	Timestamp time.Time
 }
 
 // LogStats holds statistics about log analysis
@@ -31,6 +42,39 @@ type LogStats struct {
 	LevelCounts  map[LogLevel]int `json:"level_counts"`
 	TopErrors    []ErrorFrequency `json:"top_errors"`
 	TotalEntries int              `json:"total_entries"`
+	// TopMessages lists the most frequent messages of each selected level,
+	// when the analysis is limited to some levels
+	TopMessages map[LogLevel][]ErrorFrequency `json:"top_messages,omitempty"`
+
+	// UnmatchedLines counts non-empty lines the log pattern did not recognize
+	UnmatchedLines   int           `json:"unmatched_lines"`
+	UnmatchedSamples []string      `json:"unmatched_samples,omitempty"`
+	Files            []FileSummary `json:"files,omitempty"`
+
+	// Time range covered by entries with a parseable timestamp
+	EarliestTimestamp *time.Time `json:"earliest_timestamp,omitempty"`
+	LatestTimestamp   *time.Time `json:"latest_timestamp,omitempty"`
+	UntimedEntries    int        `json:"untimed_entries"`
+}
+
+// maxUnmatchedSamples caps how many unmatched lines are kept for debugging
+const maxUnmatchedSamples = 5
+
+// FileSummary holds per-file results for a multi-file analysis
+type FileSummary struct {
+	Path           string           `json:"path"`
+	LevelCounts    map[LogLevel]int `json:"level_counts,omitempty"`
+	TotalEntries   int              `json:"total_entries"`
+	UnmatchedLines int              `json:"unmatched_lines"`
+	Error          string           `json:"error,omitempty"`
+}
+
+// ErrorRate returns the fraction of entries logged at ERROR level
+func (s *LogStats) ErrorRate() float64 {
+	if s.TotalEntries == 0 {
+		return 0
+	}
+	return float64(s.LevelCounts[ERROR]) / float64(s.TotalEntries)
 }
 
 // ErrorFrequency represents error message frequency
@@ -41,7 +85,15 @@ type ErrorFrequency struct {
 
 // LogAnalyzer handles log file analysis
 type LogAnalyzer struct {
-	logPattern *regexp.Regexp
+	logPattern   *regexp.Regexp
+	levelMapping map[string]LogLevel
+	// timeLayout overrides timestamp auto-detection when set
+	timeLayout string
+	// continuation matches lines appended to the preceding entry, such as
+	// stack trace frames; nil parses every line on its own
+	continuation *regexp.Regexp
+	// levels are the levels counted; nil counts every level
+	levels map[LogLevel]bool
 }
 
 // NewLogAnalyzer creates a new log analyzer instance
@@ -50,72 +102,342 @@ func NewLogAnalyzer() *LogAnalyzer {
 	pattern := regexp.MustCompile(`(?i)\[(INFO|WARN|ERROR|DEBUG)\]|^(INFO|WARN|ERROR|DEBUG):`)
 
 	return &LogAnalyzer{
-		logPattern: pattern,
+		logPattern:   pattern,
+		levelMapping: defaultLevelMapping,
+		continuation: regexp.MustCompile(DefaultContinuationPattern),
 	}
 }
 
 // ParseLogFile parses a log file and returns statistics
 func (la *LogAnalyzer) ParseLogFile(filePath string) (*LogStats, error) {
+	stats, messages, err := la.parseFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate the most frequent errors, and messages of selected levels
+	la.summarize(stats, messages)
+
+	return stats, nil
+}
+
+// ParseLogFiles parses several log files and aggregates their statistics.
+// A file that cannot be read is recorded in the per-file summary without
+// aborting the others; an error is returned only if no file could be parsed.
+func (la *LogAnalyzer) ParseLogFiles(paths []string) (*LogStats, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no log files to analyze")
+	}
+
+	stats := newLogStats()
+	stats.Files = make([]FileSummary, 0, len(paths))
+	messages := make(levelMessages)
+	parsed := 0
+
+	for _, path := range paths {
+		fileStats, fileMessages, err := la.parseFile(path)
+		if err != nil {
+			stats.Files = append(stats.Files, FileSummary{Path: path, Error: err.Error()})
+			continue
+		}
+		parsed++
+
+		stats.Files = append(stats.Files, FileSummary{
+			Path:           path,
+			LevelCounts:    fileStats.LevelCounts,
+			TotalEntries:   fileStats.TotalEntries,
+			UnmatchedLines: fileStats.UnmatchedLines,
+		})
+
+		mergeStats(stats, messages, fileStats, fileMessages)
+	}
+
+	if parsed == 0 {
+		return stats, fmt.Errorf("failed to parse any of %d log files", len(paths))
+	}
+
+	la.summarize(stats, messages)
+
+	return stats, nil
+}
+
+// ParseLogFileParallel parses a log file by splitting it into line-aligned
+// chunks parsed concurrently by a pool of workers. A non-positive workers value
+// defaults to GOMAXPROCS. The merged result matches ParseLogFile.
+func (la *LogAnalyzer) ParseLogFileParallel(filePath string, workers int) (*LogStats, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
 	defer file.Close()
 
-	stats := &LogStats{
-		LevelCounts: make(map[LogLevel]int),
-		TopErrors:   make([]ErrorFrequency, 0),
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	// A chunk must not start inside a multi-line entry; blank lines are
+	// skipped too, since they do not end one
+	boundaries, err := chunkBoundaries(file, info.Size(), workers, func(line string) bool {
+		return strings.TrimSpace(line) == "" || la.continues(line)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading log file: %w", err)
 	}
 
-	errorMessages := make(map[string]int)
-	scanner := bufio.NewScanner(file)
+	type chunkResult struct {
+		stats    *LogStats
+		messages levelMessages
+		err      error
+	}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+	chunks := len(boundaries) - 1
+	results := make([]chunkResult, chunks)
+	var wg sync.WaitGroup
+
+	for i := 0; i < chunks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			section := io.NewSectionReader(file, boundaries[i], boundaries[i+1]-boundaries[i])
+			stats := newLogStats()
+			messages := make(levelMessages)
+			err := la.parseReader(section, stats, messages)
+
+			results[i] = chunkResult{stats: stats, messages: messages, err: err}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := newLogStats()
+	messages := make(levelMessages)
+	for _, result := range results {
+		if result.err != nil {
+			return nil, fmt.Errorf("error reading log file: %w", result.err)
+		}
+		mergeStats(stats, messages, result.stats, result.messages)
+	}
+
+	la.summarize(stats, messages)
+
+	return stats, nil
+}
+
+// chunkBoundaries splits a file of the given size into at most n byte ranges,
+// moving each split point forward to just past the next newline so that no
+// line is divided between chunks, and then past any lines continues accepts,
+// so no entry is either. The returned offsets start at 0 and end at size.
+func chunkBoundaries(r io.ReaderAt, size int64, n int, continues func(line string) bool) ([]int64, error) {
+	boundaries := []int64{0}
+	chunkSize := size / int64(n)
+	if chunkSize == 0 {
+		return append(boundaries, size), nil
+	}
+
+	buf := make([]byte, 4096)
+	for i := 1; i < n; i++ {
+		pos := int64(i) * chunkSize
+		if prev := boundaries[len(boundaries)-1]; pos <= prev {
 			continue
 		}
 
-		entry := la.ParseLine(line)
-		if entry != nil {
-			stats.LevelCounts[entry.Level]++
-			stats.TotalEntries++
+		// Scan forward to the end of the line containing pos
+		pos, err := lineEnd(r, pos, size, buf)
+		if err != nil {
+			return nil, err
+		}
 
-			// Track error messages for frequency analysis
-			if entry.Level == ERROR {
-				errorMessages[entry.Message]++
+		// Then past the lines that continue it
+		for pos < size {
+			end, err := lineEnd(r, pos, size, buf)
+			if err != nil {
+				return nil, err
+			}
+			line := make([]byte, end-pos)
+			if _, err := r.ReadAt(line, pos); err != nil && err != io.EOF {
+				return nil, err
 			}
+			if !continues(strings.TrimRight(string(line), "\r\n")) {
+				break
+			}
+			pos = end
+		}
+
+		if pos >= size {
+			break
 		}
+		boundaries = append(boundaries, pos)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading log file: %w", err)
+	return append(boundaries, size), nil
+}
+
+// lineEnd returns the offset just past the newline ending the line that
+// contains pos, or size if the line is the last
+func lineEnd(r io.ReaderAt, pos, size int64, buf []byte) (int64, error) {
+	for pos < size {
+		read, err := r.ReadAt(buf, pos)
+		if idx := bytes.IndexByte(buf[:read], '\n'); idx >= 0 {
+			return pos + int64(idx) + 1, nil
+		}
+		pos += int64(read)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
 	}
+	return size, nil
+}
 
-	// Calculate top 5 most frequent errors
-	stats.TopErrors = la.getTopErrors(errorMessages, 5)
+// mergeStats adds the counts from src into dst
+func mergeStats(dst *LogStats, dstMessages levelMessages, src *LogStats, srcMessages levelMessages) {
+	dst.TotalEntries += src.TotalEntries
+	for level, count := range src.LevelCounts {
+		dst.LevelCounts[level] += count
+	}
+	dstMessages.merge(srcMessages)
 
-	return stats, nil
+	if src.EarliestTimestamp != nil {
+		dst.observeTimestamp(*src.EarliestTimestamp)
+	}
+	if src.LatestTimestamp != nil {
+		dst.observeTimestamp(*src.LatestTimestamp)
+	}
+	dst.UntimedEntries += src.UntimedEntries
+
+	dst.UnmatchedLines += src.UnmatchedLines
+	for _, sample := range src.UnmatchedSamples {
+		if len(dst.UnmatchedSamples) >= maxUnmatchedSamples {
+			break
+		}
+		dst.UnmatchedSamples = append(dst.UnmatchedSamples, sample)
+	}
+}
+
+// parseFile parses a single log file, returning its statistics along with the
+// full message frequencies so results can be merged across files
+func (la *LogAnalyzer) parseFile(filePath string) (*LogStats, levelMessages, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	stats := newLogStats()
+	messages := make(levelMessages)
+
+	if err := la.parseReader(file, stats, messages); err != nil {
+		return nil, nil, fmt.Errorf("error reading log file: %w", err)
+	}
+
+	return stats, messages, nil
+}
+
+// parseReader scans lines from r, accumulating level counts into stats and
+// message frequencies into messages. Continuation lines, such as stack trace
+// frames, are appended to the preceding entry's message, so an entry is only
+// counted once the line after it starts something else. Entries at levels not
+// selected are dropped as soon as they are parsed, along with their
+// continuation lines.
+func (la *LogAnalyzer) parseReader(r io.Reader, stats *LogStats, messages levelMessages) error {
+	scanner := bufio.NewScanner(r)
+
+	var pending *LogEntry
+	skipping := false
+	record := func() {
+		if pending == nil {
+			return
+		}
+		stats.LevelCounts[pending.Level]++
+		stats.TotalEntries++
+		stats.observeTimestamp(pending.Timestamp)
+
+		// Track messages for frequency analysis
+		if la.tracks(pending.Level) {
+			messages.add(pending.Level, pending.Message)
+		}
+		pending = nil
+	}
+
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+
+		if (pending != nil || skipping) && la.continues(raw) {
+			if pending != nil {
+				pending.Message += "\n" + line
+			}
+			continue
+		}
+		record()
+		skipping = false
+
+		if entry := la.ParseLine(line); entry != nil {
+			if la.selects(entry.Level) {
+				pending = entry
+			} else {
+				skipping = true
+			}
+		} else {
+			stats.UnmatchedLines++
+			if len(stats.UnmatchedSamples) < maxUnmatchedSamples {
+				stats.UnmatchedSamples = append(stats.UnmatchedSamples, line)
+			}
+		}
+	}
+	record()
+
+	return scanner.Err()
+}
+
+// newLogStats returns an empty LogStats ready for accumulation
+func newLogStats() *LogStats {
+	return &LogStats{
+		LevelCounts: make(map[LogLevel]int),
+		TopErrors:   make([]ErrorFrequency, 0),
+	}
 }
 
 // ParseLine extracts log level and message from a single line
 func (la *LogAnalyzer) ParseLine(line string) *LogEntry {
-	matches := la.logPattern.FindStringSubmatch(line)
-	if len(matches) == 0 {
+	// Structured JSON logs carry the level in a field rather than the text
+	if strings.HasPrefix(line, "{") {
+		if entry := la.parseJSONLine(line); entry != nil {
+			return entry
+		}
+	}
+
+	loc := la.logPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
 		return nil
 	}
+	matches := make([]string, len(loc)/2)
+	for i := range matches {
+		if loc[2*i] >= 0 {
+			matches[i] = line[loc[2*i]:loc[2*i+1]]
+		}
+	}
 
 	var level LogLevel
 	var message string
+	var timestamp string
 
 	// Check which group matched
 	if matches[1] != "" {
 		level = LogLevel(strings.ToUpper(matches[1]))
-		// Extract message after [LEVEL]
-		parts := strings.SplitN(line, "]", 2)
-		if len(parts) > 1 {
-			message = strings.TrimSpace(parts[1])
-		}
+		// Extract message after [LEVEL]; anything before it may be a timestamp
+		message = strings.TrimSpace(line[loc[1]:])
+		timestamp = strings.TrimSpace(line[:loc[0]])
 	} else if matches[2] != "" {
 		level = LogLevel(strings.ToUpper(matches[2]))
 		// Extract message after LEVEL:
@@ -129,10 +451,14 @@ func (la *LogAnalyzer) ParseLine(line string) *LogEntry {
 		message = line
 	}
 
-	return &LogEntry{
+	entry := &LogEntry{
 		Level:   level,
 		Message: message,
+		Time:    timestamp,
 	}
+	entry.Timestamp, _ = la.parseTimestamp(timestamp)
+
+	return entry
 }
 
 // getTopErrors returns the top N most frequent error messages
@@ -146,9 +472,12 @@ func (la *LogAnalyzer) getTopErrors(errorMessages map[string]int, topN int) []Er
 		})
 	}
 
-	// Sort by frequency (descending)
+	// Sort by frequency (descending), breaking ties by message for stable output
 	sort.Slice(errors, func(i, j int) bool {
-		return errors[i].Count > errors[j].Count
+		if errors[i].Count != errors[j].Count {
+			return errors[i].Count > errors[j].Count
+		}
+		return errors[i].Message < errors[j].Message
 	})
 
 	// Return top N errors
@@ -172,4 +501,15 @@ func (la *LogAnalyzer) PrintStats(stats *LogStats) {
 	for i, err := range stats.TopErrors {
 		fmt.Printf("  %d. [%d times] %s\n", i+1, err.Count, err.Message)
 	}
+
+	for _, level := range []LogLevel{DEBUG, INFO, WARN, UNKNOWN} {
+		top, ok := stats.TopMessages[level]
+		if !ok {
+			continue
+		}
+		fmt.Printf("\nTop 5 Most Frequent %s Messages:\n", level)
+		for i, msg := range top {
+			fmt.Printf("  %d. [%d times] %s\n", i+1, msg.Count, msg.Message)
+		}
+	}
 }
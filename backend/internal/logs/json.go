@@ -0,0 +1,134 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UNKNOWN collects entries whose source level has no mapping, so they are
+// surfaced in the stats instead of being dropped
+const UNKNOWN LogLevel = "UNKNOWN"
+
+// defaultLevelMapping maps source level values (lowercased) from common JSON
+// loggers to the internal LogLevel enum
+var defaultLevelMapping = map[string]LogLevel{
+	// pino / bunyan numeric levels
+	"10": DEBUG, // trace
+	"20": DEBUG,
+	"30": INFO,
+	"40": WARN,
+	"50": ERROR,
+	"60": ERROR, // fatal
+
+	// zap and logrus string levels
+	"trace":   DEBUG,
+	"debug":   DEBUG,
+	"info":    INFO,
+	"warn":    WARN,
+	"warning": WARN,
+	"error":   ERROR,
+	"dpanic":  ERROR,
+	"panic":   ERROR,
+	"fatal":   ERROR,
+}
+
+// JSON field names checked, in order, for each part of an entry
+var (
+	jsonLevelKeys   = []string{"level", "lvl", "severity"}
+	jsonMessageKeys = []string{"msg", "message"}
+	jsonTimeKeys    = []string{"time", "ts", "timestamp"}
+)
+
+// ParseLevelMapping parses a mapping override of the form "source:LEVEL,..."
+// such as "verbose:DEBUG,notice:INFO,35:WARN"
+func ParseLevelMapping(spec string) (map[string]LogLevel, error) {
+	mapping := make(map[string]LogLevel)
+	if strings.TrimSpace(spec) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid level mapping %q (expected source:LEVEL)", pair)
+		}
+
+		level := LogLevel(strings.ToUpper(strings.TrimSpace(parts[1])))
+		switch level {
+		case DEBUG, INFO, WARN, ERROR:
+		default:
+			return nil, fmt.Errorf("invalid target level %q in mapping %q", parts[1], pair)
+		}
+
+		mapping[strings.ToLower(strings.TrimSpace(parts[0]))] = level
+	}
+
+	return mapping, nil
+}
+
+// WithLevelMapping returns a copy of the analyzer whose JSON level mapping is
+// the current mapping overlaid with overrides. The receiver is not modified.
+func (la *LogAnalyzer) WithLevelMapping(overrides map[string]LogLevel) *LogAnalyzer {
+	clone := *la
+	clone.levelMapping = make(map[string]LogLevel, len(la.levelMapping)+len(overrides))
+	for source, level := range la.levelMapping {
+		clone.levelMapping[source] = level
+	}
+	for source, level := range overrides {
+		clone.levelMapping[strings.ToLower(source)] = level
+	}
+
+	return &clone
+}
+
+// parseJSONLine parses a structured JSON log line, returning nil if the line
+// is not a JSON object or has no recognizable level field
+func (la *LogAnalyzer) parseJSONLine(line string) *LogEntry {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil
+	}
+
+	rawLevel, ok := firstField(fields, jsonLevelKeys)
+	if !ok {
+		return nil
+	}
+
+	entry := &LogEntry{Level: la.mapLevel(rawLevel)}
+	if msg, ok := firstField(fields, jsonMessageKeys); ok {
+		entry.Message = msg
+	}
+	if ts, ok := firstField(fields, jsonTimeKeys); ok {
+		entry.Time = ts
+		entry.Timestamp, _ = la.parseTimestamp(ts)
+	}
+	if entry.Message == "" {
+		entry.Message = line
+	}
+
+	return entry
+}
+
+// mapLevel converts a source level value to a LogLevel, falling back to UNKNOWN
+func (la *LogAnalyzer) mapLevel(raw string) LogLevel {
+	if level, ok := la.levelMapping[strings.ToLower(raw)]; ok {
+		return level
+	}
+	return UNKNOWN
+}
+
+// firstField returns the first present key as a string, formatting numbers
+// without a trailing fraction so 30 and 30.0 both become "30"
+func firstField(fields map[string]interface{}, keys []string) (string, bool) {
+	for _, key := range keys {
+		switch v := fields[key].(type) {
+		case string:
+			return v, true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+package logs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// topMessagesPerLevel is how many of the most frequent messages are reported
+// for each selected level
+const topMessagesPerLevel = 5
+
+// ParseLevels parses a comma-separated list of levels such as "ERROR,WARN",
+// case-insensitively. An empty list returns nil, which selects every level.
+func ParseLevels(spec string) ([]LogLevel, error) {
+	var levels []LogLevel
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		level := LogLevel(strings.ToUpper(name))
+		switch level {
+		case DEBUG, INFO, WARN, ERROR, UNKNOWN:
+		default:
+			return nil, fmt.Errorf("invalid level %q (expected DEBUG, INFO, WARN, ERROR or UNKNOWN)", name)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// WithLevels returns a copy of the analyzer that only counts entries at the
+// given levels, skipping the rest as soon as their level is known. Counts,
+// TotalEntries and the error rate then cover the selected levels only, and
+// TopMessages lists the most frequent messages of each. Empty levels selects
+// every level. The receiver is not modified.
+func (la *LogAnalyzer) WithLevels(levels []LogLevel) *LogAnalyzer {
+	clone := *la
+	clone.levels = nil
+	if len(levels) > 0 {
+		clone.levels = make(map[LogLevel]bool, len(levels))
+		for _, level := range levels {
+			clone.levels[level] = true
+		}
+	}
+	return &clone
+}
+
+// selects reports whether entries at level are counted
+func (la *LogAnalyzer) selects(level LogLevel) bool {
+	return la.levels == nil || la.levels[level]
+}
+
+// levelMessages counts messages per level. ERROR messages are always
+// counted, for TopErrors; other levels only when levels are selected.
+type levelMessages map[LogLevel]map[string]int
+
+// add counts one message at level
+func (m levelMessages) add(level LogLevel, message string) {
+	counts, ok := m[level]
+	if !ok {
+		counts = make(map[string]int)
+		m[level] = counts
+	}
+	counts[message]++
+}
+
+// merge adds the counts of src
+func (m levelMessages) merge(src levelMessages) {
+	for level, counts := range src {
+		for message, count := range counts {
+			if _, ok := m[level]; !ok {
+				m[level] = make(map[string]int)
+			}
+			m[level][message] += count
+		}
+	}
+}
+
+// tracks reports whether messages at level are counted
+func (la *LogAnalyzer) tracks(level LogLevel) bool {
+	return level == ERROR || la.levels != nil
+}
+
+// summarize sets the top message lists of stats from messages
+func (la *LogAnalyzer) summarize(stats *LogStats, messages levelMessages) {
+	stats.TopErrors = la.getTopErrors(messages[ERROR], topMessagesPerLevel)
+	if la.levels == nil {
+		return
+	}
+
+	levels := make([]LogLevel, 0, len(la.levels))
+	for level := range la.levels {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+
+	stats.TopMessages = make(map[LogLevel][]ErrorFrequency, len(levels))
+	for _, level := range levels {
+		stats.TopMessages[level] = la.getTopErrors(messages[level], topMessagesPerLevel)
+	}
+}
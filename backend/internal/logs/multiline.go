@@ -0,0 +1,52 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultContinuationPattern matches the lines of Java, Python and Go stack
+// traces that follow the entry they belong to: indented lines, "at " frames,
+// "Traceback", "Caused by:", "... N more", goroutine headers and exception
+// lines such as "java.lang.IllegalStateException: boom"
+const DefaultContinuationPattern = `^(\s|at\s|Traceback|Caused by:|\.\.\. \d+ more|goroutine \d+ \[|[\w$.]+(Exception|Error)(:|$))`
+
+// ContinuationNone disables multi-line handling in ParseContinuationPattern
+const ContinuationNone = "none"
+
+// ParseContinuationPattern compiles a continuation line pattern. An empty
+// pattern gives DefaultContinuationPattern; "none" returns nil, so every line
+// is parsed on its own.
+func ParseContinuationPattern(pattern string) (*regexp.Regexp, error) {
+	switch strings.TrimSpace(pattern) {
+	case "":
+		pattern = DefaultContinuationPattern
+	case ContinuationNone:
+		return nil, nil
+	}
+
+	continuation, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid continuation pattern %q: %w", pattern, err)
+	}
+	return continuation, nil
+}
+
+// WithContinuationPattern returns a copy of the analyzer that appends lines
+// matching continuation to the preceding entry's message instead of parsing
+// them on their own; nil disables this. The receiver is not modified.
+func (la *LogAnalyzer) WithContinuationPattern(continuation *regexp.Regexp) *LogAnalyzer {
+	clone := *la
+	clone.continuation = continuation
+	return &clone
+}
+
+// continues reports whether raw, an untrimmed line, continues the preceding
+// entry. Lines the log pattern recognizes always start a new entry.
+func (la *LogAnalyzer) continues(raw string) bool {
+	if la.continuation == nil || !la.continuation.MatchString(raw) {
+		return false
+	}
+	return la.ParseLine(strings.TrimSpace(raw)) == nil
+}
@@ -0,0 +1,114 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// autoTimeLayouts are tried in order when no explicit time layout is set
+var autoTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700", // common log format
+	time.RFC1123Z,
+	time.RFC1123,
+	time.Stamp, // syslog
+}
+
+// ValidateTimeLayout checks that layout is a usable Go reference layout: it
+// must contain at least one time element and parse its own output
+func ValidateTimeLayout(layout string) error {
+	if strings.TrimSpace(layout) == "" {
+		return fmt.Errorf("time layout is empty")
+	}
+
+	sample := time.Date(2023, time.November, 24, 13, 47, 9, 0, time.UTC)
+	formatted := sample.Format(layout)
+	if formatted == layout {
+		return fmt.Errorf("invalid time layout %q (expected a Go reference layout such as 2006-01-02 15:04:05)", layout)
+	}
+	if _, err := time.Parse(layout, formatted); err != nil {
+		return fmt.Errorf("invalid time layout %q: %v", layout, err)
+	}
+
+	return nil
+}
+
+// WithTimeLayout returns a copy of the analyzer that parses timestamps with
+// layout only, instead of auto-detecting common formats. The receiver is not
+// modified; layout should be checked with ValidateTimeLayout first.
+func (la *LogAnalyzer) WithTimeLayout(layout string) *LogAnalyzer {
+	clone := *la
+	clone.timeLayout = layout
+	return &clone
+}
+
+// parseTimestamp parses raw with the analyzer's time layout, or with the
+// auto-detected layouts and Unix epoch values when none is set
+func (la *LogAnalyzer) parseTimestamp(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, false
+	}
+
+	// Text logs often bracket the timestamp, as in "[2024-01-15 10:30:00] [INFO]"
+	candidates := []string{raw}
+	if trimmed := strings.TrimSpace(strings.Trim(raw, "[]")); trimmed != raw {
+		candidates = append(candidates, trimmed)
+	}
+
+	for _, candidate := range candidates {
+		if la.timeLayout != "" {
+			if ts, err := time.Parse(la.timeLayout, candidate); err == nil {
+				return ts, true
+			}
+			continue
+		}
+
+		for _, layout := range autoTimeLayouts {
+			if ts, err := time.Parse(layout, candidate); err == nil {
+				return ts, true
+			}
+		}
+		if ts, ok := parseEpoch(candidate); ok {
+			return ts, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// parseEpoch parses Unix seconds or milliseconds, as written by zap and pino
+func parseEpoch(raw string) (time.Time, bool) {
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 1e9 {
+		return time.Time{}, false
+	}
+	if value >= 1e12 {
+		return time.UnixMilli(int64(value)).UTC(), true
+	}
+
+	seconds := int64(value)
+	return time.Unix(seconds, int64((value-float64(seconds))*1e9)).UTC(), true
+}
+
+// observeTimestamp widens the stats' time range to include entry's timestamp,
+// or counts the entry as untimed
+func (s *LogStats) observeTimestamp(ts time.Time) {
+	if ts.IsZero() {
+		s.UntimedEntries++
+		return
+	}
+	if s.EarliestTimestamp == nil || ts.Before(*s.EarliestTimestamp) {
+		earliest := ts
+		s.EarliestTimestamp = &earliest
+	}
+	if s.LatestTimestamp == nil || ts.After(*s.LatestTimestamp) {
+		latest := ts
+		s.LatestTimestamp = &latest
+	}
+}
@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Annotation limits
+const (
+	MaxAnnotationTags      = 16
+	MaxAnnotationTagBytes  = 64
+	MaxAnnotationTextBytes = 1024
+	// MaxAnnotations caps how many annotations one query returns
+	MaxAnnotations = 500
+)
+
+// annotationTagPattern is what annotation tags may look like, e.g. deploy or
+// api-v1.2
+var annotationTagPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Annotation marks an event such as a deploy on the metric timeline, so
+// metric changes can be correlated with it
+type Annotation struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Timestamp time.Time      `json:"timestamp" gorm:"not null;index"`
+	Text      string         `json:"text" gorm:"not null"`
+	Tags      AnnotationTags `json:"tags" gorm:"type:text;not null;default:''"`
+	CreatedBy uint           `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// AnnotationTags are the tags of an annotation. They are stored as a JSON
+// array; no tags are stored as "".
+type AnnotationTags []string
+
+// Value encodes the tags for storage
+func (t AnnotationTags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "", nil
+	}
+	encoded, err := json.Marshal([]string(t))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode annotation tags: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// Scan decodes stored tags
+func (t *AnnotationTags) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into annotation tags", src)
+	}
+
+	if len(data) == 0 {
+		*t = AnnotationTags{}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// ValidateAnnotationTag checks one annotation tag
+func ValidateAnnotationTag(tag string) error {
+	if !annotationTagPattern.MatchString(tag) {
+		return fmt.Errorf("invalid annotation tag %q (letters, digits, '_', '.' and '-')", tag)
+	}
+	if len(tag) > MaxAnnotationTagBytes {
+		return fmt.Errorf("annotation tag %q exceeds %d bytes", tag, MaxAnnotationTagBytes)
+	}
+	return nil
+}
+
+// CreateAnnotationRequest represents a request to annotate the timeline
+type CreateAnnotationRequest struct {
+	Timestamp *time.Time `json:"timestamp"`
+	Text      string     `json:"text" binding:"required"`
+	Tags      []string   `json:"tags"`
+}
+
+// CreateAnnotation stores an annotation, at the current time if the request
+// gives none. Duplicate tags are dropped.
+func (c *Collector) CreateAnnotation(req *CreateAnnotationRequest, createdBy uint) (*Annotation, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+	if len(text) > MaxAnnotationTextBytes {
+		return nil, fmt.Errorf("text exceeds %d bytes", MaxAnnotationTextBytes)
+	}
+
+	tags := AnnotationTags{}
+	seen := make(map[string]bool, len(req.Tags))
+	for _, tag := range req.Tags {
+		tag = strings.TrimSpace(tag)
+		if err := ValidateAnnotationTag(tag); err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > MaxAnnotationTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", MaxAnnotationTags)
+	}
+
+	annotation := &Annotation{
+		Timestamp: c.clock.Now(),
+		Text:      text,
+		Tags:      tags,
+		CreatedBy: createdBy,
+	}
+	if req.Timestamp != nil {
+		annotation.Timestamp = *req.Timestamp
+	}
+
+	if err := c.db.Create(annotation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create annotation: %w", err)
+	}
+	return annotation, nil
+}
+
+// AnnotationFilter narrows an annotation query; zero values are ignored
+type AnnotationFilter struct {
+	From time.Time
+	To   time.Time
+	// Tag keeps only annotations carrying this tag
+	Tag   string
+	Limit int
+}
+
+// GetAnnotations returns annotations matching filter, oldest first, with
+// From and To inclusive. At most MaxAnnotations are returned.
+func (c *Collector) GetAnnotations(filter AnnotationFilter) ([]Annotation, error) {
+	query := c.db.Order("timestamp ASC, id ASC")
+
+	if !filter.From.IsZero() {
+		query = query.Where("timestamp >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("timestamp <= ?", filter.To)
+	}
+	if filter.Tag != "" {
+		if err := ValidateAnnotationTag(filter.Tag); err != nil {
+			return nil, err
+		}
+		// Tags cannot contain quotes, so the quoted tag only matches a whole tag
+		tag, _ := json.Marshal(filter.Tag)
+		query = query.Where("tags LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(string(tag))+"%")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > MaxAnnotations {
+		limit = MaxAnnotations
+	}
+
+	annotations := []Annotation{}
+	if err := query.Limit(limit).Find(&annotations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get annotations: %w", err)
+	}
+	return annotations, nil
+}
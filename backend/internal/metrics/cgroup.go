@@ -0,0 +1,392 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// CgroupMode selects between host and container (cgroup) CPU and memory metrics
+type CgroupMode string
+
+const (
+	// CgroupAuto uses cgroup metrics for resources limited below host capacity
+	CgroupAuto CgroupMode = "auto"
+	// CgroupHost always reports host metrics
+	CgroupHost CgroupMode = "host"
+	// CgroupContainer always reports cgroup metrics when they can be read
+	CgroupContainer CgroupMode = "container"
+)
+
+// ParseCgroupMode validates a cgroup mode name, defaulting to auto when empty
+func ParseCgroupMode(name string) (CgroupMode, error) {
+	switch CgroupMode(name) {
+	case "":
+		return CgroupAuto, nil
+	case CgroupAuto, CgroupHost, CgroupContainer:
+		return CgroupMode(name), nil
+	default:
+		return "", fmt.Errorf("unknown cgroup mode %q (expected auto, host or container)", name)
+	}
+}
+
+// cgroupRoot is where the cgroup filesystem is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupV1Unlimited is the lower bound of the page-aligned near-max value
+// cgroup v1 reports for an unlimited memory limit
+const cgroupV1Unlimited = 1 << 62
+
+// cgroupReader reads CPU and memory usage and limits of the process's cgroup.
+// A zero limit means host metrics are used for that resource.
+type cgroupReader struct {
+	version int
+
+	// cpuDir, cpuacctDir and memoryDir are the controller directories; with
+	// cgroup v2 they are all the unified cgroup directory
+	cpuDir     string
+	cpuacctDir string
+	memoryDir  string
+
+	cpuLimit    float64 // cores
+	memoryLimit uint64  // bytes
+}
+
+// detectCgroup locates the cgroup v2 or v1 controllers and reads their limits
+func detectCgroup() (*cgroupReader, error) {
+	var reader *cgroupReader
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		dir := filepath.Join(cgroupRoot, cgroupV2Path())
+		if _, err := os.Stat(filepath.Join(dir, "cpu.stat")); err != nil {
+			dir = cgroupRoot
+		}
+		reader = &cgroupReader{version: 2, cpuDir: dir, cpuacctDir: dir, memoryDir: dir}
+	} else {
+		reader = &cgroupReader{
+			version:    1,
+			cpuDir:     filepath.Join(cgroupRoot, "cpu"),
+			cpuacctDir: filepath.Join(cgroupRoot, "cpuacct"),
+			memoryDir:  filepath.Join(cgroupRoot, "memory"),
+		}
+	}
+
+	if _, err := reader.cpuUsage(); err != nil {
+		return nil, err
+	}
+	if _, err := reader.memoryUsage(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if reader.cpuLimit, err = reader.readCPULimit(); err != nil {
+		return nil, err
+	}
+	if reader.memoryLimit, err = reader.readMemoryLimit(); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// cgroupV2Path returns the process's cgroup v2 path from /proc/self/cgroup
+func cgroupV2Path() string {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "/"
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path
+		}
+	}
+	return "/"
+}
+
+// readCPULimit returns the CPU quota in cores, or 0 when unlimited
+func (r *cgroupReader) readCPULimit() (float64, error) {
+	var quota, period string
+	if r.version == 2 {
+		fields := strings.Fields(readCgroupString(filepath.Join(r.cpuDir, "cpu.max")))
+		if len(fields) != 2 {
+			return 0, nil
+		}
+		quota, period = fields[0], fields[1]
+	} else {
+		quota = readCgroupString(filepath.Join(r.cpuDir, "cpu.cfs_quota_us"))
+		period = readCgroupString(filepath.Join(r.cpuDir, "cpu.cfs_period_us"))
+	}
+
+	if quota == "" || quota == "max" || quota == "-1" {
+		return 0, nil
+	}
+	quotaUs, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cgroup CPU quota %q: %w", quota, err)
+	}
+	periodUs, err := strconv.ParseFloat(period, 64)
+	if err != nil || periodUs <= 0 {
+		return 0, fmt.Errorf("invalid cgroup CPU period %q", period)
+	}
+	return quotaUs / periodUs, nil
+}
+
+// readMemoryLimit returns the memory limit in bytes, or 0 when unlimited
+func (r *cgroupReader) readMemoryLimit() (uint64, error) {
+	file := "memory.max"
+	if r.version == 1 {
+		file = "memory.limit_in_bytes"
+	}
+
+	value := readCgroupString(filepath.Join(r.memoryDir, file))
+	if value == "" || value == "max" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cgroup memory limit %q: %w", value, err)
+	}
+	if limit >= cgroupV1Unlimited {
+		return 0, nil
+	}
+	return limit, nil
+}
+
+// cpuUsage returns the cgroup's cumulative CPU time
+func (r *cgroupReader) cpuUsage() (time.Duration, error) {
+	if r.version == 2 {
+		usec, err := readCgroupStat(filepath.Join(r.cpuacctDir, "cpu.stat"), "usage_usec")
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(usec) * time.Microsecond, nil
+	}
+
+	nsec, err := readCgroupUint(filepath.Join(r.cpuacctDir, "cpuacct.usage"))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(nsec), nil
+}
+
+// memoryUsage returns the cgroup's memory usage excluding reclaimable page
+// cache, matching what docker stats reports
+func (r *cgroupReader) memoryUsage() (uint64, error) {
+	usageFile, inactiveKey := "memory.current", "inactive_file"
+	if r.version == 1 {
+		usageFile, inactiveKey = "memory.usage_in_bytes", "total_inactive_file"
+	}
+
+	usage, err := readCgroupUint(filepath.Join(r.memoryDir, usageFile))
+	if err != nil {
+		return 0, err
+	}
+	inactive, err := readCgroupStat(filepath.Join(r.memoryDir, "memory.stat"), inactiveKey)
+	if err == nil && inactive < usage {
+		usage -= inactive
+	}
+	return usage, nil
+}
+
+// readCgroupString returns the trimmed contents of a cgroup file, or "" if it
+// cannot be read
+func readCgroupString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readCgroupUint reads a cgroup file holding a single number
+func readCgroupUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s: %w", path, err)
+	}
+	return value, nil
+}
+
+// readCgroupStat reads one key from a flat-keyed cgroup file such as cpu.stat
+func readCgroupStat(path, key string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%s not found in %s", key, path)
+}
+
+// cgroupCPUBaseline measures cgroup CPU usage between successive calls, like
+// cpuBaseline does for host CPU times
+type cgroupCPUBaseline struct {
+	mu        sync.Mutex
+	lastUsage time.Duration
+	lastAt    time.Time
+}
+
+// reset records the current cgroup CPU usage as the baseline
+func (b *cgroupCPUBaseline) reset(reader *cgroupReader) error {
+	usage, err := reader.cpuUsage()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.lastUsage, b.lastAt = usage, time.Now()
+	b.mu.Unlock()
+	return nil
+}
+
+// percent returns usage relative to the CPU limit since the previous call and
+// advances the baseline. The boolean is false if there was no baseline yet.
+func (b *cgroupCPUBaseline) percent(reader *cgroupReader) (float64, bool, error) {
+	usage, err := reader.cpuUsage()
+	if err != nil {
+		return 0, false, err
+	}
+	now := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prevUsage, prevAt := b.lastUsage, b.lastAt
+	b.lastUsage, b.lastAt = usage, now
+	if prevAt.IsZero() {
+		return 0, false, nil
+	}
+
+	return cgroupCPUPercent(usage-prevUsage, now.Sub(prevAt), reader.cpuLimit), true, nil
+}
+
+// cgroupCPUPercent converts CPU time used over elapsed wall time into a
+// percentage of limit cores
+func cgroupCPUPercent(used, elapsed time.Duration, limit float64) float64 {
+	if elapsed <= 0 || limit <= 0 || used <= 0 {
+		return 0
+	}
+	percent := used.Seconds() / (elapsed.Seconds() * limit) * 100
+	if percent > 100 {
+		return 100
+	}
+	return percent
+}
+
+// SetCgroupMode chooses between host and container CPU and memory metrics.
+// In auto mode cgroup metrics are used for each resource limited below the
+// host's capacity; in container mode they are always used, relative to host
+// capacity when a resource has no limit. Both fall back to host metrics when
+// the cgroup files cannot be read. Call it before Start.
+func (c *Collector) SetCgroupMode(mode CgroupMode) {
+	c.cgroup = nil
+	if mode == CgroupHost {
+		return
+	}
+
+	reader, err := detectCgroup()
+	if err != nil {
+		log.Printf("Cgroup metrics unavailable, using host metrics: %v", err)
+		return
+	}
+
+	hostCores := float64(runtime.NumCPU())
+	var hostMemory uint64
+	if memInfo, err := mem.VirtualMemory(); err == nil {
+		hostMemory = memInfo.Total
+	}
+
+	if mode == CgroupAuto {
+		if reader.cpuLimit >= hostCores {
+			reader.cpuLimit = 0
+		}
+		if hostMemory > 0 && reader.memoryLimit >= hostMemory {
+			reader.memoryLimit = 0
+		}
+		if reader.cpuLimit == 0 && reader.memoryLimit == 0 {
+			log.Println("No cgroup limits found, using host metrics")
+			return
+		}
+	} else {
+		if reader.cpuLimit == 0 || reader.cpuLimit > hostCores {
+			reader.cpuLimit = hostCores
+		}
+		if reader.memoryLimit == 0 || reader.memoryLimit > hostMemory {
+			reader.memoryLimit = hostMemory
+		}
+	}
+
+	c.cgroup = reader
+	log.Printf("Using cgroup v%d metrics (CPU limit: %.2f cores, memory limit: %d bytes; 0 = host)",
+		reader.version, reader.cpuLimit, reader.memoryLimit)
+}
+
+// cgroupCPU returns the reader if CPU usage should come from the cgroup
+func (c *Collector) cgroupCPU() *cgroupReader {
+	if c.cgroup == nil || c.cgroup.cpuLimit == 0 {
+		return nil
+	}
+	return c.cgroup
+}
+
+// sampleCgroupCPU blocks for the configured sample interval and returns
+// cgroup CPU usage relative to its limit
+func (c *Collector) sampleCgroupCPU(ctx context.Context, reader *cgroupReader) (float64, error) {
+	before, err := reader.cpuUsage()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cgroup CPU usage: %w", err)
+	}
+	start := time.Now()
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-time.After(c.cpuSampleInterval):
+	}
+
+	after, err := reader.cpuUsage()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cgroup CPU usage: %w", err)
+	}
+	return cgroupCPUPercent(after-before, time.Since(start), reader.cpuLimit), nil
+}
+
+// memoryPercent returns used memory relative to the cgroup limit when cgroup
+// metrics are in use, or used host virtual memory otherwise
+func (c *Collector) memoryPercent(ctx context.Context) (float64, error) {
+	if c.cgroup != nil && c.cgroup.memoryLimit > 0 {
+		usage, err := c.cgroup.memoryUsage()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get cgroup memory usage: %w", err)
+		}
+		return float64(usage) / float64(c.cgroup.memoryLimit) * 100, nil
+	}
+
+	memInfo, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get memory usage: %w", err)
+	}
+	return memInfo.UsedPercent, nil
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/clock"
 	"github.com/shirou/gopsutil/v3/mem"
 	"gorm.io/gorm"
 )
@@ -16,137 +19,585 @@ type Collector struct {
 	db       *gorm.DB
 	interval time.Duration
 	stopCh   chan struct{}
+
+	// jitter is the fraction of interval each cycle may move by
+	jitter float64
+
+	// startDelay postpones the first collection after Start
+	startDelay time.Duration
+
+	// clock timestamps samples and tells cache freshness, collection health
+	// and retention cutoffs; tickers and rate measurements use real time
+	clock clock.Clock
+
+	// host and labels are recorded on the metrics this collector gathers
+	host   string
+	labels Labels
+
+	// mu guards the cached state below, which is written by the collection
+	// loop and read concurrently by HTTP handlers
+	mu            sync.RWMutex
+	lastMetrics   *SystemMetrics
+	lastCollected time.Time
+
+	retention RetentionPolicy
+
+	// cycleMu is held for the duration of a collection cycle; ticks that find
+	// it locked are skipped and counted in skippedCycles
+	cycleMu       sync.Mutex
+	skippedCycles atomic.Int64
+
+	// statusMu guards the collection loop's status below
+	statusMu          sync.Mutex
+	running           bool
+	startedAt         time.Time
+	pausedAt          time.Time
+	resumedAt         time.Time
+	lastSuccess       time.Time
+	lastError         string
+	collectionsTotal  int64
+	collectionsFailed int64
+
+	// cpuSampleInterval is the blocking window for on-demand CPU samples;
+	// the collection loop measures against cpuBaseline instead
+	cpuSampleInterval time.Duration
+	cpuBaseline       cpuBaseline
+
+	// cgroup is set when CPU or memory is reported relative to container
+	// limits; cgroupBaseline then plays the role of cpuBaseline
+	cgroup         *cgroupReader
+	cgroupBaseline cgroupCPUBaseline
+
+	// sources run concurrently each collection cycle
+	sources       []Source
+	sourceTimeout time.Duration
+
+	// recent keeps the latest samples per type in memory for fast reads
+	recent *recentMetrics
+
+	disk    *diskSource
+	network *networkSource
+	// cpuModes is set when the per-mode CPU breakdown is collected
+	cpuModes *cpuModeSource
+
+	// onCollect, if set, is called with the metrics of every successful cycle
+	onCollect func(*SystemMetrics)
+
+	// ingestMode and ingestMaxBatch govern externally submitted samples
+	ingestMode     IngestMode
+	ingestMaxBatch int
+
+	// outOfRange decides what happens to collected samples outside their
+	// type's registered range
+	outOfRange OutOfRangePolicy
 }
 
 // NewCollector creates a new metrics collector
 func NewCollector(db *gorm.DB, interval time.Duration) *Collector {
-	return &Collector{
-		db:       db,
-		interval: interval,
-		stopCh:   make(chan struct{}),
+	c := &Collector{
+		db:                db,
+		interval:          interval,
+		stopCh:            make(chan struct{}),
+		clock:             clock.Real{},
+		host:              localHostname(),
+		cpuSampleInterval: DefaultCPUSampleInterval,
+		sourceTimeout:     DefaultSourceTimeout,
+		recent:            newRecentMetrics(DefaultRecentBufferSize),
+		disk:              newDiskSource([]string{"/"}),
+		network:           newNetworkSource(),
+		ingestMode:        IngestAtomic,
+		ingestMaxBatch:    DefaultIngestMaxBatch,
+		outOfRange:        OutOfRangeClamp,
+	}
+	c.RegisterSource(cpuSource{collector: c})
+	c.RegisterSource(memorySource{collector: c})
+	c.RegisterSource(c.disk)
+	c.RegisterSource(c.network)
+	return c
+}
+
+// SetClock sets the clock used for sample timestamps, cache freshness,
+// collection health and retention cutoffs, so tests can control time
+func (c *Collector) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// localHostname returns the machine's hostname, or "unknown" if it cannot be read
+func localHostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
 	}
+	return name
 }
 
-// Start begins collecting metrics at regular intervals
+// Start begins collecting metrics at regular intervals, after the start
+// delay if one is set
 func (c *Collector) Start(ctx context.Context) {
-	ticker := time.NewTicker(c.interval)
-	defer ticker.Stop()
+	if !c.waitStartDelay(ctx) {
+		return
+	}
+
+	timer := time.NewTimer(c.nextDelay())
+	defer timer.Stop()
+
+	if c.jitter > 0 {
+		log.Printf("Starting metrics collection with interval: %v (jitter %.0f%%)", c.interval, c.jitter*100)
+	} else {
+		log.Printf("Starting metrics collection with interval: %v", c.interval)
+	}
+
+	c.setRunning(true)
+	defer c.setRunning(false)
 
-	log.Printf("Starting metrics collection with interval: %v", c.interval)
+	// Prime the CPU baseline so the first tick measures the whole interval
+	c.primeCPUBaselines()
 
 	for {
 		select {
 		case <-ctx.Done():
+			c.waitForCycle()
 			log.Println("Metrics collection stopped by context")
 			return
 		case <-c.stopCh:
+			c.waitForCycle()
 			log.Println("Metrics collection stopped")
 			return
-		case <-ticker.C:
-			if err := c.collectMetrics(); err != nil {
-				log.Printf("Error collecting metrics: %v", err)
-			}
+		case <-timer.C:
+			timer.Reset(c.nextDelay())
+			go c.runCycle(ctx)
 		}
 	}
 }
 
-// Stop stops the metrics collection
-func (c *Collector) Stop() {
-	close(c.stopCh)
+// primeCPUBaselines records the current CPU times, so the next cycle
+// measures CPU usage from now
+func (c *Collector) primeCPUBaselines() {
+	if err := c.cpuBaseline.reset(); err != nil {
+		log.Printf("Failed to read initial CPU times: %v", err)
+	}
+	if reader := c.cgroupCPU(); reader != nil {
+		if err := c.cgroupBaseline.reset(reader); err != nil {
+			log.Printf("Failed to read initial cgroup CPU usage: %v", err)
+		}
+	}
+	if c.cpuModes != nil {
+		if err := c.cpuModes.reset(); err != nil {
+			log.Printf("Failed to read initial CPU mode times: %v", err)
+		}
+	}
 }
 
-// collectMetrics collects current system metrics
-func (c *Collector) collectMetrics() error {
-	now := time.Now()
+// runCycle runs one collection cycle unless collection is paused or the
+// previous cycle is still in progress, for example because the database is
+// slow. Overlapping cycles are skipped rather than queued so goroutines and
+// connections cannot pile up.
+func (c *Collector) runCycle(ctx context.Context) {
+	if c.Paused() {
+		return
+	}
+	if !c.cycleMu.TryLock() {
+		skipped := c.skippedCycles.Add(1)
+		log.Printf("Warning: skipping metrics collection cycle, previous cycle still running (%d skipped so far)", skipped)
+		return
+	}
+	defer c.cycleMu.Unlock()
 
-	// Collect CPU usage
-	cpuPercent, err := cpu.Percent(time.Second, false)
+	err := c.collectMetrics(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get CPU usage: %w", err)
+		log.Printf("Error collecting metrics: %v", err)
 	}
+	c.recordCycle(err)
+}
 
-	if len(cpuPercent) > 0 {
-		cpuMetric := Metric{
-			Type:      CPUUsage,
-			Value:     cpuPercent[0],
-			Unit:      "%",
-			Timestamp: now,
-		}
+// setRunning records whether the collection loop is running
+func (c *Collector) setRunning(running bool) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
 
-		if err := c.db.Create(&cpuMetric).Error; err != nil {
-			log.Printf("Failed to save CPU metric: %v", err)
-		}
+	c.running = running
+	if running {
+		c.startedAt = c.clock.Now()
 	}
+}
+
+// recordCycle updates the collection counters with a cycle's outcome
+func (c *Collector) recordCycle(err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
 
-	// Collect Memory usage
-	memInfo, err := mem.VirtualMemory()
+	c.collectionsTotal++
 	if err != nil {
-		return fmt.Errorf("failed to get memory usage: %w", err)
+		c.collectionsFailed++
+		c.lastError = err.Error()
+		return
 	}
+	c.lastSuccess = c.clock.Now()
+	c.lastError = ""
+}
 
-	memoryMetric := Metric{
-		Type:      MemoryUsage,
-		Value:     memInfo.UsedPercent,
-		Unit:      "%",
-		Timestamp: now,
+// waitForCycle blocks until an in-flight collection cycle has finished
+func (c *Collector) waitForCycle() {
+	c.cycleMu.Lock()
+	defer c.cycleMu.Unlock()
+}
+
+// staleCollectionIntervals is how many intervals may pass without a
+// successful collection before the collector is reported unhealthy
+const staleCollectionIntervals = 3
+
+// CollectorStats reports on the collector's own behaviour
+type CollectorStats struct {
+	// Healthy is false when the loop is not running or has not completed a
+	// collection within staleCollectionIntervals intervals
+	Healthy  bool   `json:"healthy"`
+	Running  bool   `json:"running"`
+	Interval string `json:"interval"`
+
+	// Paused is true between Pause and Resume; a paused collector counts as
+	// healthy as long as its loop is running
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+
+	// LastCollectionTime is when the last successful cycle finished;
+	// LastCollectionError is the latest cycle's error, empty if it succeeded
+	LastCollectionTime  *time.Time `json:"last_collection_time,omitempty"`
+	LastCollectionError string     `json:"last_collection_error,omitempty"`
+	CollectionsTotal    int64      `json:"collections_total"`
+	CollectionsFailed   int64      `json:"collections_failed"`
+
+	// SkippedCycles counts collection cycles skipped because the previous
+	// cycle had not finished; a growing value means the interval is too short
+	SkippedCycles int64 `json:"skipped_cycles"`
+}
+
+// Stats returns the collector's self-monitoring counters and health
+func (c *Collector) Stats() CollectorStats {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	stats := CollectorStats{
+		Running:             c.running,
+		Interval:            c.interval.String(),
+		LastCollectionError: c.lastError,
+		CollectionsTotal:    c.collectionsTotal,
+		CollectionsFailed:   c.collectionsFailed,
+		SkippedCycles:       c.skippedCycles.Load(),
 	}
 
-	if err := c.db.Create(&memoryMetric).Error; err != nil {
-		log.Printf("Failed to save memory metric: %v", err)
+	// Before the first success, measure staleness from when the loop started;
+	// after a pause, from when collection resumed
+	since := c.startedAt
+	if !c.lastSuccess.IsZero() {
+		lastSuccess := c.lastSuccess
+		stats.LastCollectionTime = &lastSuccess
+		since = lastSuccess
 	}
+	if c.resumedAt.After(since) {
+		since = c.resumedAt
+	}
+	stats.Healthy = c.running && c.clock.Now().Sub(since) <= staleCollectionIntervals*c.interval
+
+	if !c.pausedAt.IsZero() {
+		pausedAt := c.pausedAt
+		stats.Paused = true
+		stats.PausedAt = &pausedAt
+		stats.Healthy = c.running
+	}
+
+	return stats
+}
+
+// Interval returns how often metrics are collected
+func (c *Collector) Interval() time.Duration {
+	return c.interval
+}
+
+// SetCollectHook registers fn to run after every successful collection cycle,
+// in the collection goroutine. Call it before Start.
+func (c *Collector) SetCollectHook(fn func(*SystemMetrics)) {
+	c.onCollect = fn
+}
+
+// Stop stops the metrics collection
+func (c *Collector) Stop() {
+	close(c.stopCh)
+}
+
+// collectMetrics runs all sources concurrently and saves their samples. A
+// failing source is logged and skipped, and the types only it reports are
+// listed as missing rather than filled in from an earlier cycle; an error is
+// returned only when every source failed.
+func (c *Collector) collectMetrics(ctx context.Context) error {
+	now := c.clock.Now()
+	results := c.runSources(ctx)
+	logSourceTimings(results)
+
+	current := SystemMetrics{Timestamp: now, DiskUsage: make(map[string]float64)}
+	reported := make(map[MetricType]bool, len(systemMetricTypes))
+
+	var failed int
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+			log.Printf("Collector %s failed: %v", result.name, result.err)
+			continue
+		}
+
+		for _, sample := range result.samples {
+			value, ok := c.validateSample(result.name, sample)
+			if !ok {
+				continue
+			}
+			sample.Value = value
+
+			metric := Metric{
+				Type:      sample.Type,
+				Value:     sample.Value,
+				Unit:      sample.Unit,
+				Mount:     sample.Mount,
+				Host:      c.host,
+				Labels:    c.labels,
+				Timestamp: now,
+			}
+			if err := c.db.Create(&metric).Error; err != nil {
+				log.Printf("Failed to save %s metric: %v", sample.Type, err)
+			}
+			c.recent.add(metric)
+			reported[sample.Type] = true
+
+			switch sample.Type {
+			case CPUUsage:
+				current.CPUUsage = sample.Value
+			case MemoryUsage:
+				current.MemoryUsage = sample.Value
+			case MemoryAvailable:
+				available := sample.Value
+				current.MemoryAvailable = &available
+			case DiskUsage:
+				current.DiskUsage[sample.Mount] = sample.Value
+			case NetworkRxRate:
+				current.NetworkRx = sample.Value
+			case NetworkTxRate:
+				current.NetworkTx = sample.Value
+			}
+		}
+	}
+
+	if len(results) > 0 && failed == len(results) {
+		return fmt.Errorf("all %d collectors failed", failed)
+	}
+	for _, metricType := range systemMetricTypes {
+		if !reported[metricType] {
+			current.Missing = append(current.Missing, metricType)
+		}
+	}
+	current.NetworkInterfaces = c.network.interfaceRates()
+
+	c.storeMetrics(&current)
 
 	log.Printf("Collected metrics - CPU: %.2f%%, Memory: %.2f%%",
-		cpuPercent[0], memInfo.UsedPercent)
+		current.CPUUsage, current.MemoryUsage)
+
+	if c.onCollect != nil {
+		c.onCollect(&current)
+	}
 
 	return nil
 }
 
-// GetCurrentMetrics returns the latest system metrics
+// storeMetrics caches the most recent sample for fast reads
+func (c *Collector) storeMetrics(m *SystemMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastMetrics = m
+	c.lastCollected = c.clock.Now()
+}
+
+// cachedMetrics returns a copy of the cached sample if it is younger than one
+// collection interval, or nil otherwise
+func (c *Collector) cachedMetrics() *SystemMetrics {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.lastMetrics == nil || c.clock.Now().Sub(c.lastCollected) > c.interval {
+		return nil
+	}
+
+	cached := *c.lastMetrics
+	return &cached
+}
+
+// GetCurrentMetrics returns the latest system metrics, served from the
+// collector's cache when a sample from the current interval is available
 func (c *Collector) GetCurrentMetrics() (*SystemMetrics, error) {
+	if cached := c.cachedMetrics(); cached != nil {
+		return cached, nil
+	}
+
 	// Get CPU usage
-	cpuPercent, err := cpu.Percent(time.Second, false)
+	cpuUsage, err := c.sampleCPU(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get CPU usage: %w", err)
+		return nil, err
 	}
 
 	// Get Memory usage
-	memInfo, err := mem.VirtualMemory()
+	memoryUsage, err := c.memoryPercent(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get memory usage: %w", err)
+		return nil, err
 	}
 
-	var cpuUsage float64
-	if len(cpuPercent) > 0 {
-		cpuUsage = cpuPercent[0]
+	current := &SystemMetrics{
+		CPUUsage:    cpuUsage,
+		MemoryUsage: memoryUsage,
+		DiskUsage:   c.sampleDisks(context.Background()),
+		Timestamp:   c.clock.Now(),
+	}
+	if info, err := mem.VirtualMemory(); err == nil {
+		current.MemoryAvailable = availablePercent(info)
+	}
+	// Rates need two counter readings, so reuse the collection loop's latest
+	c.mu.RLock()
+	for _, metricType := range []MetricType{NetworkRxRate, NetworkTxRate} {
+		if c.lastMetrics == nil || !c.lastMetrics.Reported(metricType) {
+			current.Missing = append(current.Missing, metricType)
+		}
 	}
+	if c.lastMetrics != nil {
+		current.NetworkRx = c.lastMetrics.NetworkRx
+		current.NetworkTx = c.lastMetrics.NetworkTx
+	}
+	c.mu.RUnlock()
+	current.NetworkInterfaces = c.network.interfaceRates()
+	c.storeMetrics(current)
 
-	return &SystemMetrics{
-		CPUUsage:    cpuUsage,
-		MemoryUsage: memInfo.UsedPercent,
-		Timestamp:   time.Now(),
-	}, nil
+	result := *current
+	return &result, nil
 }
 
-// GetMetricHistory returns historical metrics for a specific type
-func (c *Collector) GetMetricHistory(metricType MetricType, limit int) ([]Metric, error) {
-	var metrics []Metric
+// GetMetricHistory returns historical metrics for a specific type carrying
+// the selector's labels
+func (c *Collector) GetMetricHistory(metricType MetricType, limit int, selector LabelSelector) ([]Metric, error) {
+	metrics, _, err := c.GetMetricHistoryPage(metricType, limit, selector, HistoryPage{})
+	return metrics, err
+}
 
-	query := c.db.Where("metric_type = ?", metricType).
-		Order("timestamp DESC")
+// StreamMetricHistory calls fn with each metric GetMetricHistory would
+// return, in the same order, as rows are read from the database, so a large
+// history is never held in memory at once. It stops at the first error fn
+// returns and returns that error.
+func (c *Collector) StreamMetricHistory(metricType MetricType, limit int, selector LabelSelector, fn func(Metric) error) error {
+	query := selector.apply(c.db.Model(&Metric{}).Where("metric_type = ?", metricType)).
+		Order("timestamp DESC, id DESC")
 
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
 
-	if err := query.Find(&metrics).Error; err != nil {
-		return nil, fmt.Errorf("failed to get metric history: %w", err)
+	rows, err := query.Rows()
+	if err != nil {
+		return fmt.Errorf("failed to get metric history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var metric Metric
+		if err := c.db.ScanRows(rows, &metric); err != nil {
+			return fmt.Errorf("failed to scan metric: %w", err)
+		}
+		if err := fn(metric); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read metric history: %w", err)
+	}
+	return nil
+}
+
+// GetMetricHistoryAggregated returns history downsampled into fixed-size time buckets.
+// Only the most recent limit buckets are considered, newest first. Ranges that
+// reach past the raw retention window are served from rollups transparently,
+// except with a non-empty selector, as rollups carry no labels. Per-mount
+// types such as DiskUsage must name the mount to aggregate.
+func (c *Collector) GetMetricHistoryAggregated(metricType MetricType, mount string, bucket time.Duration, agg Aggregation, limit int, selector LabelSelector) ([]AggregatedMetric, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("bucket must be positive")
+	}
+	if metricType == DiskUsage && mount == "" {
+		return nil, ErrMountRequired
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	since := c.clock.Now().Add(-bucket * time.Duration(limit)).Truncate(bucket)
+
+	samples, err := c.historySamples(metricType, mount, since, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var unit string
+	if info, ok := LookupType(metricType); ok {
+		unit = info.Unit
 	}
 
-	return metrics, nil
+	points := make([]AggregatedMetric, 0)
+	var sum float64
+	for _, sample := range samples {
+		start := sample.Timestamp.Truncate(bucket)
+		last := len(points) - 1
+
+		if last < 0 || !points[last].Timestamp.Equal(start) {
+			if last >= 0 && agg == AggregationAvg {
+				points[last].Value = sum / float64(points[last].Count)
+			}
+			points = append(points, AggregatedMetric{
+				Type:      metricType,
+				Value:     sample.value(agg),
+				Unit:      unit,
+				Agg:       agg,
+				Timestamp: start,
+			})
+			sum = 0
+			last++
+		}
+
+		point := &points[last]
+		point.Count += sample.Count
+		sum += sample.Sum
+
+		switch agg {
+		case AggregationMin:
+			if sample.Min < point.Value {
+				point.Value = sample.Min
+			}
+		case AggregationMax:
+			if sample.Max > point.Value {
+				point.Value = sample.Max
+			}
+		}
+	}
+	if len(points) > 0 && agg == AggregationAvg {
+		points[len(points)-1].Value = sum / float64(points[len(points)-1].Count)
+	}
+
+	// Newest first, matching GetMetricHistory
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	if len(points) > limit {
+		points = points[:limit]
+	}
+
+	return points, nil
 }
 
 // GetMetricSummary returns aggregated metrics for the last N readings
-func (c *Collector) GetMetricSummary(metricType MetricType, limit int) (*MetricSummary, error) {
+// carrying the selector's labels
+func (c *Collector) GetMetricSummary(metricType MetricType, limit int, selector LabelSelector) (*MetricSummary, error) {
 	var result struct {
 		Average float64
 		Min     float64
@@ -154,15 +605,15 @@ func (c *Collector) GetMetricSummary(metricType MetricType, limit int) (*MetricS
 		Count   int64
 	}
 
-	query := c.db.Model(&Metric{}).
+	query := selector.apply(c.db.Model(&Metric{}).
 		Select("AVG(value) as average, MIN(value) as min, MAX(value) as max, COUNT(*) as count").
-		Where("metric_type = ?", metricType)
+		Where("metric_type = ?", metricType))
 
 	if limit > 0 {
 		// Get the last N records by timestamp
-		subQuery := c.db.Model(&Metric{}).
+		subQuery := selector.apply(c.db.Model(&Metric{}).
 			Select("id").
-			Where("metric_type = ?", metricType).
+			Where("metric_type = ?", metricType)).
 			Order("timestamp DESC").
 			Limit(limit)
 
@@ -187,6 +638,10 @@ func (c *Collector) InitializeThresholds() error {
 	thresholds := []MetricThreshold{
 		{Type: CPUUsage, Threshold: 80.0, Enabled: true},
 		{Type: MemoryUsage, Threshold: 75.0, Enabled: true},
+		{Type: DiskUsage, Threshold: 90.0, Enabled: true},
+		// Little available memory means pressure even when usage, which
+		// counts cache, looks high anyway
+		{Type: MemoryAvailable, Threshold: 10.0, Direction: DirectionBelow, Enabled: true},
 	}
 
 	for _, threshold := range thresholds {
@@ -198,11 +653,18 @@ func (c *Collector) InitializeThresholds() error {
 		}
 
 		if count == 0 {
-			// Create new threshold using raw SQL
+			direction := threshold.Direction
+			if direction == "" {
+				direction = DirectionAbove
+			}
+
+			// Create new threshold using raw SQL; timestamps are bound rather
+			// than using NOW() so the statement also runs on SQLite
+			now := c.clock.Now()
 			err := c.db.Exec(`
-				INSERT INTO metric_thresholds (metric_type, threshold, enabled, created_at, updated_at) 
-				VALUES (?, ?, ?, NOW(), NOW())
-			`, threshold.Type, threshold.Threshold, threshold.Enabled).Error
+				INSERT INTO metric_thresholds (metric_type, threshold, direction, enabled, created_at, updated_at) 
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, threshold.Type, threshold.Threshold, direction, threshold.Enabled, now, now).Error
 
 			if err != nil {
 				return fmt.Errorf("failed to create threshold for %s: %w", threshold.Type, err)
@@ -213,3 +675,116 @@ func (c *Collector) InitializeThresholds() error {
 
 	return nil
 }
+
+// GetMetricSummaryRange returns aggregated metrics between from (inclusive)
+// and to (exclusive), including rolled-up history. Per-mount types are
+// summarized over every mount point.
+func (c *Collector) GetMetricSummaryRange(metricType MetricType, from, to time.Time) (*MetricSummary, error) {
+	samples, err := c.historySamples(metricType, "", from, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &MetricSummary{Type: metricType}
+	var sum float64
+	for _, sample := range samples {
+		if !sample.Timestamp.Before(to) {
+			break
+		}
+		if summary.Count == 0 || sample.Min < summary.Min {
+			summary.Min = sample.Min
+		}
+		if summary.Count == 0 || sample.Max > summary.Max {
+			summary.Max = sample.Max
+		}
+		sum += sample.Sum
+		summary.Count += sample.Count
+	}
+	if summary.Count > 0 {
+		summary.Average = sum / float64(summary.Count)
+	}
+
+	return summary, nil
+}
+
+// CompareWindows compares the summary for the window ending now with the
+// equal-length window before it. Increases in average or max beyond
+// thresholdPercent are reported as regressions.
+func (c *Collector) CompareWindows(metricType MetricType, window time.Duration, thresholdPercent float64) (*MetricComparison, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+
+	now := c.clock.Now()
+	current, err := c.GetMetricSummaryRange(metricType, now.Add(-window), now)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := c.GetMetricSummaryRange(metricType, now.Add(-2*window), now.Add(-window))
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &MetricComparison{
+		Type:        metricType,
+		Window:      window.String(),
+		Current:     current,
+		Previous:    previous,
+		Regressions: []string{},
+	}
+
+	if current.Count > 0 && previous.Count > 0 {
+		comparison.AverageChange = percentChange(previous.Average, current.Average)
+		comparison.MaxChange = percentChange(previous.Max, current.Max)
+	}
+	if comparison.AverageChange != nil && *comparison.AverageChange > thresholdPercent {
+		comparison.Regressions = append(comparison.Regressions, "average")
+	}
+	if comparison.MaxChange != nil && *comparison.MaxChange > thresholdPercent {
+		comparison.Regressions = append(comparison.Regressions, "max")
+	}
+	comparison.Regressed = len(comparison.Regressions) > 0
+
+	return comparison, nil
+}
+
+// percentChange returns the change from before to after as a percentage of
+// before, or nil when before is zero
+func percentChange(before, after float64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	change := (after - before) / before * 100
+	return &change
+}
+
+// ResetMetrics deletes all stored samples and rollups and clears the cached
+// and buffered samples, returning the rows deleted per table. It backs the
+// admin reset endpoint used in development and tests.
+func (c *Collector) ResetMetrics() (map[string]int64, error) {
+	deleted := make(map[string]int64)
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("1 = 1").Delete(&Metric{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["metrics"] = result.RowsAffected
+
+		result = tx.Where("1 = 1").Delete(&MetricRollup{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["metric_rollups"] = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset metrics: %w", err)
+	}
+
+	c.recent.clear()
+	c.mu.Lock()
+	c.lastMetrics = nil
+	c.mu.Unlock()
+
+	return deleted, nil
+}
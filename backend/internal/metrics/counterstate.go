@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CounterState is the last raw counter reading of a delta-based source such
+// as network, so the first rate after a restart can be computed against the
+// reading taken before it
+type CounterState struct {
+	Host      string    `json:"host" gorm:"primaryKey"`
+	Source    string    `json:"source" gorm:"primaryKey"`
+	Counters  string    `json:"counters" gorm:"type:text;not null"`
+	SampledAt time.Time `json:"sampled_at" gorm:"not null"`
+}
+
+// counterStore persists counter readings per host and source. A reading
+// older than maxGap is not bridged: rates over a long gap would smear a
+// burst across it or hide a counter reset.
+type counterStore struct {
+	db     *gorm.DB
+	host   string
+	maxGap time.Duration
+}
+
+// load decodes the stored reading of source into counters and returns when
+// it was taken. It reports false when there is none or it is too old to use.
+func (s *counterStore) load(source string, counters interface{}, now time.Time) (time.Time, bool) {
+	if s == nil {
+		return time.Time{}, false
+	}
+
+	var state CounterState
+	err := s.db.Where("host = ? AND source = ?", s.host, source).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, false
+	}
+	if err != nil {
+		log.Printf("Failed to load %s counter state: %v", source, err)
+		return time.Time{}, false
+	}
+
+	gap := now.Sub(state.SampledAt)
+	if gap <= 0 || gap > s.maxGap {
+		log.Printf("Not bridging %s rates across a %s gap (max %s)", source, gap.Round(time.Second), s.maxGap)
+		return time.Time{}, false
+	}
+	if err := json.Unmarshal([]byte(state.Counters), counters); err != nil {
+		log.Printf("Failed to decode %s counter state: %v", source, err)
+		return time.Time{}, false
+	}
+	return state.SampledAt, true
+}
+
+// save stores counters as the latest reading of source. Failures are logged;
+// they only cost continuity after the next restart.
+func (s *counterStore) save(source string, counters interface{}, sampledAt time.Time) {
+	if s == nil {
+		return
+	}
+
+	encoded, err := json.Marshal(counters)
+	if err != nil {
+		log.Printf("Failed to encode %s counter state: %v", source, err)
+		return
+	}
+	state := CounterState{Host: s.host, Source: source, Counters: string(encoded), SampledAt: sampledAt}
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "host"}, {Name: "source"}},
+		DoUpdates: clause.AssignmentColumns([]string{"counters", "sampled_at"}),
+	}).Create(&state).Error
+	if err != nil {
+		log.Printf("Failed to save %s counter state: %v", source, err)
+	}
+}
+
+// SetCounterPersistence stores the raw counters behind rate metrics such as
+// network throughput, so after a restart of at most maxGap the first rate is
+// computed against the last reading before it instead of starting over. A
+// longer gap is skipped cleanly. A non-positive maxGap disables persistence.
+func (c *Collector) SetCounterPersistence(maxGap time.Duration) {
+	var store *counterStore
+	if maxGap > 0 {
+		store = &counterStore{db: c.db, host: c.host, maxGap: maxGap}
+	}
+	c.network.setCounterStore(store)
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// DefaultCPUSampleInterval is the blocking CPU sampling window used when none is configured
+const DefaultCPUSampleInterval = time.Second
+
+// cpuBaseline measures CPU usage between successive calls without blocking,
+// by keeping the CPU times from the previous call. The collection loop owns
+// one so that each tick reports usage over the whole interval since the last
+// tick instead of a short blocking window.
+type cpuBaseline struct {
+	mu   sync.Mutex
+	last *cpu.TimesStat
+}
+
+// reset records the current CPU times as the baseline
+func (b *cpuBaseline) reset() error {
+	times, err := cpuTimes()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.last = &times
+	b.mu.Unlock()
+	return nil
+}
+
+// percent returns CPU usage since the previous call and advances the baseline.
+// The boolean is false if there was no baseline yet.
+func (b *cpuBaseline) percent() (float64, bool, error) {
+	times, err := cpuTimes()
+	if err != nil {
+		return 0, false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prev := b.last
+	b.last = &times
+	if prev == nil {
+		return 0, false, nil
+	}
+
+	return busyPercent(*prev, times), true, nil
+}
+
+// cpuTimes returns the aggregate CPU times for all cores
+func cpuTimes() (cpu.TimesStat, error) {
+	times, err := cpu.Times(false)
+	if err != nil {
+		return cpu.TimesStat{}, err
+	}
+	if len(times) == 0 {
+		return cpu.TimesStat{}, fmt.Errorf("no CPU times reported")
+	}
+	return times[0], nil
+}
+
+// busyPercent returns the share of non-idle time between two CPU time samples
+func busyPercent(t1, t2 cpu.TimesStat) float64 {
+	t1All, t1Busy := busyTimes(t1)
+	t2All, t2Busy := busyTimes(t2)
+
+	if t2Busy <= t1Busy {
+		return 0
+	}
+	if t2All <= t1All {
+		return 100
+	}
+	return math.Min(100, math.Max(0, (t2Busy-t1Busy)/(t2All-t1All)*100))
+}
+
+// busyTimes returns total and busy CPU time, matching gopsutil's accounting
+func busyTimes(t cpu.TimesStat) (float64, float64) {
+	total := t.Total()
+	if runtime.GOOS == "linux" {
+		// Guest time is already counted in user time on Linux
+		total -= t.Guest + t.GuestNice
+	}
+	return total, total - t.Idle - t.Iowait
+}
+
+// SetCPUSampleInterval sets the blocking window used for on-demand CPU samples.
+// Shorter windows make uncached current-metrics requests faster but noisier.
+func (c *Collector) SetCPUSampleInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCPUSampleInterval
+	}
+	c.cpuSampleInterval = interval
+}
+
+// sampleCPU blocks for the configured sample interval and returns CPU usage
+func (c *Collector) sampleCPU(ctx context.Context) (float64, error) {
+	if reader := c.cgroupCPU(); reader != nil {
+		return c.sampleCgroupCPU(ctx, reader)
+	}
+
+	cpuPercent, err := cpu.PercentWithContext(ctx, c.cpuSampleInterval, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get CPU usage: %w", err)
+	}
+	if len(cpuPercent) == 0 {
+		return 0, nil
+	}
+	return cpuPercent[0], nil
+}
+
+// collectCPU returns CPU usage since the previous collection tick, falling
+// back to a blocking sample when there is no baseline yet
+func (c *Collector) collectCPU(ctx context.Context) (float64, error) {
+	if reader := c.cgroupCPU(); reader != nil {
+		usage, ok, err := c.cgroupBaseline.percent(reader)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get cgroup CPU usage: %w", err)
+		}
+		if ok {
+			return usage, nil
+		}
+		return c.sampleCPU(ctx)
+	}
+
+	usage, ok, err := c.cpuBaseline.percent()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get CPU usage: %w", err)
+	}
+	if ok {
+		return usage, nil
+	}
+	return c.sampleCPU(ctx)
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// ErrCPUModesDisabled is returned when the CPU mode breakdown is not collected
+var ErrCPUModesDisabled = errors.New("CPU mode breakdown is not enabled")
+
+// CPUModes is the share of CPU time spent in each mode between two
+// collection cycles, keyed by mode name (user, system, iowait, idle). Modes
+// the platform does not report are left out. Other modes such as irq and
+// steal are not broken out, so the shares need not add up to 100.
+type CPUModes struct {
+	Modes     map[string]float64 `json:"modes"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// cpuMode maps a mode name to its metric type and its time in a sample
+type cpuMode struct {
+	name       string
+	metricType MetricType
+	time       func(cpu.TimesStat) float64
+}
+
+// cpuModes returns the modes this platform reports. Only Linux accounts for
+// iowait; elsewhere gopsutil reports it as zero.
+func cpuModes() []cpuMode {
+	modes := []cpuMode{
+		{"user", CPUUser, func(t cpu.TimesStat) float64 { return t.User }},
+		{"system", CPUSystem, func(t cpu.TimesStat) float64 { return t.System }},
+	}
+	if runtime.GOOS == "linux" {
+		modes = append(modes, cpuMode{"iowait", CPUIowait, func(t cpu.TimesStat) float64 { return t.Iowait }})
+	}
+	return append(modes, cpuMode{"idle", CPUIdle, func(t cpu.TimesStat) float64 { return t.Idle }})
+}
+
+// cpuModeSource reports the share of CPU time per mode. Shares are computed
+// from the CPU time deltas since the previous cycle, or since Start primed
+// the baseline.
+type cpuModeSource struct {
+	modes []cpuMode
+
+	mu       sync.Mutex
+	previous *cpu.TimesStat
+	latest   *CPUModes
+}
+
+func newCPUModeSource() *cpuModeSource {
+	return &cpuModeSource{modes: cpuModes()}
+}
+
+// reset records the current CPU times as the baseline
+func (s *cpuModeSource) reset() error {
+	times, err := cpuTimes()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.previous = &times
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *cpuModeSource) Name() string {
+	return "cpu_modes"
+}
+
+func (s *cpuModeSource) Collect(ctx context.Context) ([]Sample, error) {
+	times, err := cpuTimes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CPU times: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.previous
+	s.previous = &times
+	if previous == nil {
+		return nil, nil
+	}
+
+	// Measure against the same total as busyPercent, so the idle share
+	// matches what CPU usage leaves over
+	beforeTotal, _ := busyTimes(*previous)
+	afterTotal, _ := busyTimes(times)
+	elapsed := afterTotal - beforeTotal
+	if elapsed <= 0 {
+		return nil, nil
+	}
+
+	breakdown := &CPUModes{Modes: make(map[string]float64, len(s.modes)), Timestamp: time.Now()}
+	samples := make([]Sample, 0, len(s.modes))
+	for _, mode := range s.modes {
+		delta := mode.time(times) - mode.time(*previous)
+		if delta < 0 {
+			// The counter went backwards, e.g. after a CPU went offline
+			delta = 0
+		}
+		share := percentRange.Clamp(delta / elapsed * 100)
+		breakdown.Modes[mode.name] = share
+		samples = append(samples, Sample{Type: mode.metricType, Value: share, Unit: UnitPercent})
+	}
+	s.latest = breakdown
+
+	return samples, nil
+}
+
+// breakdown returns a copy of the latest breakdown, or nil if there is none yet
+func (s *cpuModeSource) breakdown() *CPUModes {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.latest == nil {
+		return nil
+	}
+	modes := make(map[string]float64, len(s.latest.Modes))
+	for name, share := range s.latest.Modes {
+		modes[name] = share
+	}
+	return &CPUModes{Modes: modes, Timestamp: s.latest.Timestamp}
+}
+
+// EnableCPUModes collects the per-mode CPU breakdown every cycle, stored as
+// the cpu_user, cpu_system, cpu_iowait and cpu_idle metric types. The
+// breakdown is host-wide, even when CPU usage is reported against container
+// limits. Must be called before Start.
+func (c *Collector) EnableCPUModes() {
+	if c.cpuModes != nil {
+		return
+	}
+	c.cpuModes = newCPUModeSource()
+	c.RegisterSource(c.cpuModes)
+}
+
+// GetCPUModes returns the latest per-mode CPU breakdown. It returns
+// ErrCPUModesDisabled if the breakdown is not collected, and nil before the
+// first cycle has run.
+func (c *Collector) GetCPUModes() (*CPUModes, error) {
+	if c.cpuModes == nil {
+		return nil, ErrCPUModesDisabled
+	}
+	return c.cpuModes.breakdown(), nil
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a history cursor that could not be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// HistoryCursor marks the last metric of a history page. History is ordered
+// newest first by timestamp then id, so the next page starts strictly after
+// this position no matter how many metrics were inserted in the meantime.
+type HistoryCursor struct {
+	Timestamp time.Time
+	ID        uint
+}
+
+// Encode returns the cursor as an opaque token for clients to pass back
+func (c HistoryCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.Timestamp.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseHistoryCursor decodes a token returned by Encode
+func ParseHistoryCursor(token string) (*HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	metricID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &HistoryCursor{Timestamp: time.Unix(0, unixNano), ID: uint(metricID)}, nil
+}
+
+// HistoryPage selects where a page of history starts: after Cursor, or
+// after skipping Offset metrics. The zero value starts at the newest metric.
+type HistoryPage struct {
+	Cursor *HistoryCursor
+	Offset int
+}
+
+// GetMetricHistoryPage returns up to limit metrics of a type carrying the
+// selector's labels, starting where page says, along with the cursor for the
+// next page. The next cursor is nil when there are no more metrics or limit
+// is not positive. Cursors stay consistent while metrics are being written;
+// offsets shift as new metrics arrive and get slower the deeper they go.
+func (c *Collector) GetMetricHistoryPage(metricType MetricType, limit int, selector LabelSelector, page HistoryPage) ([]Metric, *HistoryCursor, error) {
+	var metrics []Metric
+
+	query := selector.apply(c.db.Where("metric_type = ?", metricType)).
+		Order("timestamp DESC, id DESC")
+
+	if page.Cursor != nil {
+		query = query.Where("timestamp < ? OR (timestamp = ? AND id < ?)",
+			page.Cursor.Timestamp, page.Cursor.Timestamp, page.Cursor.ID)
+	}
+	if page.Offset > 0 {
+		query = query.Offset(page.Offset)
+	}
+	// One extra row tells whether there is a next page
+	if limit > 0 {
+		query = query.Limit(limit + 1)
+	}
+
+	if err := query.Find(&metrics).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get metric history: %w", err)
+	}
+
+	if limit <= 0 || len(metrics) <= limit {
+		return metrics, nil, nil
+	}
+	metrics = metrics[:limit]
+	last := metrics[limit-1]
+	return metrics, &HistoryCursor{Timestamp: last.Timestamp, ID: last.ID}, nil
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskSource reports used space for each configured mount point
+type diskSource struct {
+	mu      sync.Mutex
+	mounts  []string
+	missing map[string]bool
+}
+
+func newDiskSource(mounts []string) *diskSource {
+	return &diskSource{mounts: mounts, missing: make(map[string]bool)}
+}
+
+func (s *diskSource) Name() string {
+	return "disk"
+}
+
+// Collect samples every mount. Mounts that cannot be read, for example because
+// they were unmounted, are skipped and logged once until they come back.
+func (s *diskSource) Collect(ctx context.Context) ([]Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := make([]Sample, 0, len(s.mounts))
+	for _, mount := range s.mounts {
+		usage, err := disk.UsageWithContext(ctx, mount)
+		if err != nil {
+			if !s.missing[mount] {
+				log.Printf("Disk mount %s unavailable, skipping: %v", mount, err)
+				s.missing[mount] = true
+			}
+			continue
+		}
+		if s.missing[mount] {
+			log.Printf("Disk mount %s available again", mount)
+			delete(s.missing, mount)
+		}
+
+		samples = append(samples, Sample{
+			Type:  DiskUsage,
+			Value: usage.UsedPercent,
+			Unit:  UnitPercent,
+			Mount: mount,
+		})
+	}
+
+	if len(samples) == 0 && len(s.mounts) > 0 {
+		return nil, fmt.Errorf("no disk mounts could be read")
+	}
+
+	return samples, nil
+}
+
+// setMounts replaces the monitored mounts
+func (s *diskSource) setMounts(mounts []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mounts = mounts
+	s.missing = make(map[string]bool)
+}
+
+// mountList returns a copy of the monitored mounts
+func (s *diskSource) mountList() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.mounts...)
+}
+
+// SetDiskMounts sets the mount points whose disk usage is collected
+func (c *Collector) SetDiskMounts(mounts []string) {
+	c.disk.setMounts(mounts)
+}
+
+// sampleDisks returns current disk usage per mount, omitting unreadable mounts
+func (c *Collector) sampleDisks(ctx context.Context) map[string]float64 {
+	samples, _ := c.disk.Collect(ctx)
+
+	usage := make(map[string]float64, len(samples))
+	for _, sample := range samples {
+		usage[sample.Mount] = sample.Value
+	}
+	return usage
+}
@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxFleetBuckets caps the number of time buckets a fleet query may span
+const MaxFleetBuckets = 1440
+
+// FleetPoint is one metric type aggregated across hosts over one time bucket
+type FleetPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	// Hosts is how many hosts reported in the bucket
+	Hosts int `json:"hosts"`
+	// Host is the host that had the value, for min and max
+	Host string `json:"host,omitempty"`
+}
+
+// FleetAggregate is a metric type aggregated across hosts over a time range
+type FleetAggregate struct {
+	Type   MetricType   `json:"type"`
+	Unit   string       `json:"unit,omitempty"`
+	Agg    Aggregation  `json:"agg"`
+	Bucket string       `json:"bucket"`
+	From   time.Time    `json:"from"`
+	To     time.Time    `json:"to"`
+	Points []FleetPoint `json:"points"`
+}
+
+// fleetKey identifies one host's samples within one bucket
+type fleetKey struct {
+	start time.Time
+	host  string
+}
+
+// fleetAccumulator aggregates one host's samples within one bucket
+type fleetAccumulator struct {
+	sum, min, max float64
+	count         int64
+}
+
+// add accumulates count samples with the given sum, min and max
+func (a *fleetAccumulator) add(sum, min, max float64, count int64) {
+	if a.count == 0 || min < a.min {
+		a.min = min
+	}
+	if a.count == 0 || max > a.max {
+		a.max = max
+	}
+	a.sum += sum
+	a.count += count
+}
+
+// value returns the accumulated samples reduced with agg
+func (a *fleetAccumulator) value(agg Aggregation) float64 {
+	switch agg {
+	case AggregationMin:
+		return a.min
+	case AggregationMax:
+		return a.max
+	default:
+		return a.sum / float64(a.count)
+	}
+}
+
+// GetFleetAggregate aggregates samples between from (inclusive) and to
+// (exclusive) across hosts. Hosts report at slightly different times, so
+// each host's samples are first reduced with agg within bucket-sized time
+// buckets, and the per-host values of each bucket are then reduced with agg
+// again: avg weighs every host equally however often it reports. Points are
+// oldest first; buckets no host reported in are omitted.
+//
+// Ranges reaching past the raw retention window include each host's
+// rollups. A rollup counts whole towards the bucket its start falls in, so
+// there the series is no finer than the rollup resolution.
+func (c *Collector) GetFleetAggregate(metricType MetricType, agg Aggregation, from, to time.Time, bucket time.Duration) (*FleetAggregate, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("bucket must be positive")
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from)/bucket > MaxFleetBuckets {
+		return nil, fmt.Errorf("range spans more than %d buckets; use a larger bucket", MaxFleetBuckets)
+	}
+
+	rows, err := c.db.Model(&Metric{}).
+		Select("host, value, timestamp").
+		Where("metric_type = ? AND timestamp >= ? AND timestamp < ?", metricType, from, to).
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fleet metrics: %w", err)
+	}
+	defer rows.Close()
+
+	hosts := make(map[fleetKey]*fleetAccumulator)
+	accumulatorAt := func(at time.Time, host string) *fleetAccumulator {
+		// UTC so equal instants make equal map keys
+		key := fleetKey{start: at.Truncate(bucket).UTC(), host: host}
+		acc, ok := hosts[key]
+		if !ok {
+			acc = &fleetAccumulator{}
+			hosts[key] = acc
+		}
+		return acc
+	}
+
+	for rows.Next() {
+		var metric Metric
+		if err := c.db.ScanRows(rows, &metric); err != nil {
+			return nil, fmt.Errorf("failed to scan fleet metric: %w", err)
+		}
+		accumulatorAt(metric.Timestamp, metric.Host).add(metric.Value, metric.Value, metric.Value, 1)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read fleet metrics: %w", err)
+	}
+
+	// Raw rows are deleted when they are rolled up, so the two never overlap
+	var rollups []MetricRollup
+	if err := c.db.Where("metric_type = ? AND bucket_start >= ? AND bucket_start < ?", metricType, from, to).
+		Find(&rollups).Error; err != nil {
+		return nil, fmt.Errorf("failed to get fleet rollups: %w", err)
+	}
+	for _, rollup := range rollups {
+		accumulatorAt(rollup.BucketStart, rollup.Host).
+			add(rollup.Average*float64(rollup.Count), rollup.Min, rollup.Max, rollup.Count)
+	}
+
+	points := make(map[time.Time]*FleetPoint)
+	sums := make(map[time.Time]float64)
+	for key, acc := range hosts {
+		value := acc.value(agg)
+		point, ok := points[key.start]
+		if !ok {
+			point = &FleetPoint{Timestamp: key.start, Value: value, Host: key.host}
+			points[key.start] = point
+		}
+		point.Hosts++
+		sums[key.start] += value
+
+		// Ties go to the first host by name so results are stable
+		switch agg {
+		case AggregationMin:
+			if value < point.Value || (value == point.Value && key.host < point.Host) {
+				point.Value, point.Host = value, key.host
+			}
+		case AggregationMax:
+			if value > point.Value || (value == point.Value && key.host < point.Host) {
+				point.Value, point.Host = value, key.host
+			}
+		}
+	}
+
+	result := &FleetAggregate{
+		Type:   metricType,
+		Agg:    agg,
+		Bucket: bucket.String(),
+		From:   from,
+		To:     to,
+		Points: make([]FleetPoint, 0, len(points)),
+	}
+	if info, ok := LookupType(metricType); ok {
+		result.Unit = info.Unit
+	}
+	for start, point := range points {
+		if agg == AggregationAvg {
+			point.Value = sums[start] / float64(point.Hosts)
+			point.Host = ""
+		}
+		result.Points = append(result.Points, *point)
+	}
+	sort.Slice(result.Points, func(i, j int) bool {
+		return result.Points[i].Timestamp.Before(result.Points[j].Timestamp)
+	})
+
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// GapIntervalFactor is how many expected intervals may pass between two
+// samples before the stretch between them counts as a gap
+const GapIntervalFactor = 2
+
+// MaxGapRange is the longest range GetMetricGaps scans
+const MaxGapRange = 31 * 24 * time.Hour
+
+// MetricGap is a stretch of time with no samples of a metric type
+type MetricGap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Duration is End minus Start, e.g. "12m30s"
+	Duration string `json:"duration"`
+}
+
+// GetMetricGaps returns the stretches between from and to where no sample of
+// metricType was recorded for more than GapIntervalFactor times
+// expectedInterval, oldest first. The edges of the range count as samples
+// would, so a collector that stopped before to, or started after from, shows
+// as a gap at that end; a range without samples is one gap. Samples from
+// every host count, and raw metrics removed by retention read as gaps.
+func (c *Collector) GetMetricGaps(metricType MetricType, from, to time.Time, expectedInterval time.Duration) ([]MetricGap, error) {
+	if expectedInterval <= 0 {
+		return nil, fmt.Errorf("expected interval must be positive")
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from) > MaxGapRange {
+		return nil, fmt.Errorf("range must not exceed %v", MaxGapRange)
+	}
+
+	var timestamps []time.Time
+	err := c.db.Model(&Metric{}).
+		Distinct("timestamp").
+		Where("metric_type = ? AND timestamp >= ? AND timestamp <= ?", metricType, from, to).
+		Order("timestamp").
+		Pluck("timestamp", &timestamps).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metric timestamps: %w", err)
+	}
+
+	maxSpacing := GapIntervalFactor * expectedInterval
+	gaps := []MetricGap{}
+	previous := from
+	for _, timestamp := range append(timestamps, to) {
+		if timestamp.Sub(previous) > maxSpacing {
+			gaps = append(gaps, MetricGap{Start: previous, End: timestamp, Duration: timestamp.Sub(previous).String()})
+		}
+		previous = timestamp
+	}
+
+	return gaps, nil
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// MaxHistogramBuckets caps the number of buckets a histogram may request
+const MaxHistogramBuckets = 100
+
+// HistogramBucket counts samples with Lower <= value < Upper; the last bucket
+// also includes its upper bound
+type HistogramBucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int64   `json:"count"`
+}
+
+// MetricHistogram is the distribution of a metric's values over a time range
+type MetricHistogram struct {
+	Type    MetricType        `json:"type"`
+	From    time.Time         `json:"from"`
+	To      time.Time         `json:"to"`
+	Min     float64           `json:"min"`
+	Max     float64           `json:"max"`
+	Count   int64             `json:"count"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+// GetMetricHistogram counts raw samples between from (inclusive) and to
+// (exclusive) into equal-width value buckets. Percent metrics use fixed 0–100
+// bounds so histograms are comparable; other metrics span the observed range.
+func (c *Collector) GetMetricHistogram(metricType MetricType, from, to time.Time, buckets int) (*MetricHistogram, error) {
+	if buckets <= 0 || buckets > MaxHistogramBuckets {
+		return nil, fmt.Errorf("buckets must be between 1 and %d", MaxHistogramBuckets)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	var values []float64
+	err := c.db.Model(&Metric{}).
+		Where("metric_type = ? AND timestamp >= ? AND timestamp < ?", metricType, from, to).
+		Pluck("value", &values).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metric values: %w", err)
+	}
+
+	histogram := &MetricHistogram{
+		Type:    metricType,
+		From:    from,
+		To:      to,
+		Count:   int64(len(values)),
+		Buckets: []HistogramBucket{},
+	}
+	for i, value := range values {
+		if i == 0 || value < histogram.Min {
+			histogram.Min = value
+		}
+		if i == 0 || value > histogram.Max {
+			histogram.Max = value
+		}
+	}
+
+	lower, upper := histogram.Min, histogram.Max
+	if info, ok := LookupType(metricType); ok && info.Unit == UnitPercent {
+		lower, upper = 0, 100
+	} else if len(values) == 0 {
+		return histogram, nil
+	}
+	if upper <= lower {
+		// Every sample has the same value; give the buckets a nonzero width
+		upper = lower + 1
+	}
+
+	width := (upper - lower) / float64(buckets)
+	histogram.Buckets = make([]HistogramBucket, buckets)
+	for i := range histogram.Buckets {
+		histogram.Buckets[i] = HistogramBucket{
+			Lower: lower + float64(i)*width,
+			Upper: lower + float64(i+1)*width,
+		}
+	}
+	histogram.Buckets[buckets-1].Upper = upper
+
+	for _, value := range values {
+		// Values outside fixed bounds are counted in the nearest edge bucket
+		index := int(math.Floor((value - lower) / width))
+		if index < 0 {
+			index = 0
+		} else if index >= buckets {
+			index = buckets - 1
+		}
+		histogram.Buckets[index].Count++
+	}
+
+	return histogram, nil
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"fmt"
+)
+
+// MaxHistoryRows caps the rows returned by one multi-type history query
+const MaxHistoryRows = 5000
+
+// ParseHistoryTypes validates a list of metric types, dropping duplicates. An
+// empty list selects every registered type.
+func ParseHistoryTypes(names []string) ([]MetricType, error) {
+	if len(names) == 0 {
+		registered := RegisteredTypes()
+		types := make([]MetricType, len(registered))
+		for i, info := range registered {
+			types[i] = info.Type
+		}
+		return types, nil
+	}
+
+	seen := make(map[MetricType]bool, len(names))
+	types := make([]MetricType, 0, len(names))
+	for _, name := range names {
+		metricType := MetricType(name)
+		if _, ok := LookupType(metricType); !ok {
+			return nil, fmt.Errorf("unknown metric type %q", name)
+		}
+		if !seen[metricType] {
+			seen[metricType] = true
+			types = append(types, metricType)
+		}
+	}
+	return types, nil
+}
+
+// GetMetricHistories returns the latest limit samples of each type, newest
+// first, in a single query. Every requested type has an entry, empty if it
+// has no samples.
+func (c *Collector) GetMetricHistories(types []MetricType, limit int) (map[MetricType][]Metric, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	ranked := c.db.Model(&Metric{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY metric_type ORDER BY timestamp DESC) AS row_rank").
+		Where("metric_type IN ?", types)
+
+	var rows []Metric
+	if err := c.db.Table("(?) AS ranked", ranked).
+		Where("row_rank <= ?", limit).
+		Order("metric_type, timestamp DESC").
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get metric history: %w", err)
+	}
+
+	histories := make(map[MetricType][]Metric, len(types))
+	for _, metricType := range types {
+		histories[metricType] = []Metric{}
+	}
+	for _, row := range rows {
+		histories[row.Type] = append(histories[row.Type], row)
+	}
+	return histories, nil
+}
@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultIngestMaxBatch is the default maximum number of records per ingest request
+const DefaultIngestMaxBatch = 1000
+
+// MaxHostLength bounds the host name of an ingested record
+const MaxHostLength = 255
+
+// IngestMode controls how a batch containing invalid records is handled
+type IngestMode string
+
+const (
+	// IngestAtomic rejects the whole batch if any record is invalid
+	IngestAtomic IngestMode = "atomic"
+	// IngestPartial stores the valid records and reports the invalid ones
+	IngestPartial IngestMode = "partial"
+)
+
+var (
+	ErrEmptyBatch     = errors.New("ingest batch is empty")
+	ErrBatchTooLarge  = errors.New("ingest batch is too large")
+	ErrIngestRejected = errors.New("ingest batch rejected: one or more records are invalid")
+)
+
+// ParseIngestMode validates an ingest mode name, defaulting to atomic when empty
+func ParseIngestMode(name string) (IngestMode, error) {
+	switch IngestMode(name) {
+	case "":
+		return IngestAtomic, nil
+	case IngestAtomic, IngestPartial:
+		return IngestMode(name), nil
+	default:
+		return "", fmt.Errorf("unknown ingest mode %q (expected atomic or partial)", name)
+	}
+}
+
+// IngestRecord is one externally submitted metric sample
+type IngestRecord struct {
+	Type      string   `json:"type"`
+	Value     *float64 `json:"value"`
+	Unit      string   `json:"unit,omitempty"`
+	Mount     string   `json:"mount,omitempty"`
+	Host      string   `json:"host,omitempty"`
+	Labels    Labels   `json:"labels,omitempty"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// IngestError describes why one record of a batch was rejected
+type IngestError struct {
+	Index int    `json:"index"`
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// IngestResult reports the outcome of an ingest request
+type IngestResult struct {
+	Mode     IngestMode    `json:"mode"`
+	Accepted int           `json:"accepted"`
+	Rejected int           `json:"rejected"`
+	Errors   []IngestError `json:"errors"`
+}
+
+// ValidateIngestBatch checks every record against the metric type registry and
+// returns the valid records as metrics together with an error per invalid
+// record. Types must be registered, values must be within the type's range
+// (0-100 for percentages, non-negative otherwise) and timestamps must be
+// RFC 3339. An empty or oversized batch fails as a whole.
+func ValidateIngestBatch(records []IngestRecord, maxBatch int) ([]Metric, []IngestError, error) {
+	if len(records) == 0 {
+		return nil, nil, ErrEmptyBatch
+	}
+	if maxBatch > 0 && len(records) > maxBatch {
+		return nil, nil, fmt.Errorf("%w: %d records (max %d)", ErrBatchTooLarge, len(records), maxBatch)
+	}
+
+	valid := make([]Metric, 0, len(records))
+	errs := []IngestError{}
+	for i, record := range records {
+		metric, field, err := validateIngestRecord(record)
+		if err != nil {
+			errs = append(errs, IngestError{Index: i, Field: field, Error: err.Error()})
+			continue
+		}
+		valid = append(valid, metric)
+	}
+
+	return valid, errs, nil
+}
+
+// validateIngestRecord converts one record to a metric, returning the name of
+// the offending field when it is invalid
+func validateIngestRecord(record IngestRecord) (Metric, string, error) {
+	info, ok := LookupType(MetricType(record.Type))
+	if !ok {
+		return Metric{}, "type", fmt.Errorf("unknown metric type %q", record.Type)
+	}
+
+	if record.Unit != "" && record.Unit != info.Unit {
+		return Metric{}, "unit", fmt.Errorf("unit must be %q for %s", info.Unit, info.Type)
+	}
+
+	if record.Value == nil {
+		return Metric{}, "value", errors.New("value is required")
+	}
+	value := *record.Value
+	if err := info.Range.Check(value); err != nil {
+		return Metric{}, "value", fmt.Errorf("%v for %s", err, info.Type)
+	}
+
+	if record.Mount != "" && info.Type != DiskUsage {
+		return Metric{}, "mount", fmt.Errorf("mount is only valid for %s", DiskUsage)
+	}
+	if record.Mount == "" && info.Type == DiskUsage {
+		return Metric{}, "mount", fmt.Errorf("mount is required for %s", DiskUsage)
+	}
+
+	if len(record.Host) > MaxHostLength {
+		return Metric{}, "host", fmt.Errorf("host must be at most %d characters", MaxHostLength)
+	}
+
+	if err := record.Labels.Validate(); err != nil {
+		return Metric{}, "labels", err
+	}
+
+	if record.Timestamp == "" {
+		return Metric{}, "timestamp", errors.New("timestamp is required")
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, record.Timestamp)
+	if err != nil {
+		return Metric{}, "timestamp", fmt.Errorf("timestamp %q is not RFC 3339", record.Timestamp)
+	}
+
+	return Metric{
+		Type:      info.Type,
+		Value:     value,
+		Unit:      info.Unit,
+		Mount:     record.Mount,
+		Host:      record.Host,
+		Labels:    record.Labels,
+		Timestamp: timestamp,
+	}, "", nil
+}
+
+// SetIngestPolicy sets the default ingest mode and the maximum batch size
+func (c *Collector) SetIngestPolicy(mode IngestMode, maxBatch int) {
+	if maxBatch <= 0 {
+		maxBatch = DefaultIngestMaxBatch
+	}
+	c.ingestMode = mode
+	c.ingestMaxBatch = maxBatch
+}
+
+// Ingest validates and stores externally submitted samples. An empty mode
+// uses the configured default. In atomic mode nothing is stored if any record
+// is invalid and ErrIngestRejected is returned alongside the report.
+func (c *Collector) Ingest(records []IngestRecord, mode IngestMode) (*IngestResult, error) {
+	if mode == "" {
+		mode = c.ingestMode
+	}
+
+	valid, errs, err := ValidateIngestBatch(records, c.ingestMaxBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IngestResult{Mode: mode, Rejected: len(errs), Errors: errs}
+	if mode == IngestAtomic && len(errs) > 0 {
+		result.Rejected = len(records)
+		return result, ErrIngestRejected
+	}
+	if len(valid) == 0 {
+		return result, nil
+	}
+
+	if err := c.db.CreateInBatches(valid, 100).Error; err != nil {
+		return nil, fmt.Errorf("failed to store ingested metrics: %w", err)
+	}
+	for _, metric := range valid {
+		c.recent.add(metric)
+	}
+
+	result.Accepted = len(valid)
+	return result, nil
+}
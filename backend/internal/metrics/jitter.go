@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+)
+
+// SetCollectionJitter spreads collection cycles by up to fraction of the
+// interval either side of each tick, so agents started together don't all
+// write on the same boundary. Zero disables jitter; fraction must be below 1.
+func (c *Collector) SetCollectionJitter(fraction float64) error {
+	if fraction < 0 || fraction >= 1 {
+		return fmt.Errorf("collection jitter must be at least 0 and below 1, got %g", fraction)
+	}
+	c.jitter = fraction
+	return nil
+}
+
+// nextDelay returns how long to wait before the next collection cycle: the
+// interval, moved by a random offset within the jitter. Offsets are spread
+// evenly either side, so the average interval is unchanged.
+func (c *Collector) nextDelay() time.Duration {
+	if c.jitter <= 0 {
+		return c.interval
+	}
+	offset := (rand.Float64()*2 - 1) * c.jitter * float64(c.interval)
+	return c.interval + time.Duration(offset)
+}
+
+// SetStartDelay postpones the first collection by delay after Start, so the
+// first sample is not taken while the server is still busy starting up. The
+// collector reports itself as not running until the delay has passed. Zero
+// or negative collects on the usual schedule.
+func (c *Collector) SetStartDelay(delay time.Duration) {
+	if delay < 0 {
+		delay = 0
+	}
+	c.startDelay = delay
+}
+
+// waitStartDelay waits out the start delay, returning false if the collector
+// was stopped in the meantime
+func (c *Collector) waitStartDelay(ctx context.Context) bool {
+	if c.startDelay <= 0 {
+		return true
+	}
+
+	log.Printf("Delaying metrics collection start by %v", c.startDelay)
+	timer := time.NewTimer(c.startDelay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		log.Println("Metrics collection stopped by context before starting")
+		return false
+	case <-c.stopCh:
+		log.Println("Metrics collection stopped before starting")
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Label limits
+const (
+	MaxLabels          = 16
+	MaxLabelValueBytes = 255
+)
+
+// labelNamePattern is what label names may look like, as in Prometheus
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Labels are arbitrary name/value pairs attached to a metric, such as
+// env=prod. They are stored as a JSON object; no labels are stored as "".
+type Labels map[string]string
+
+// Value encodes the labels for storage
+func (l Labels) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	// Keys are encoded sorted, which label selectors rely on
+	encoded, err := json.Marshal(map[string]string(l))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode labels: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// Scan decodes stored labels
+func (l *Labels) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into labels", src)
+	}
+
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]string)(l))
+}
+
+// Validate checks label names and values and the number of labels
+func (l Labels) Validate() error {
+	if len(l) > MaxLabels {
+		return fmt.Errorf("at most %d labels are allowed", MaxLabels)
+	}
+	for name, value := range l {
+		if err := validateLabel(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateLabel checks one label name and value
+func validateLabel(name, value string) error {
+	if !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid label name %q (letters, digits and underscores, not starting with a digit)", name)
+	}
+	if value == "" {
+		return fmt.Errorf("label %q has an empty value", name)
+	}
+	if len(value) > MaxLabelValueBytes {
+		return fmt.Errorf("label %q value exceeds %d bytes", name, MaxLabelValueBytes)
+	}
+	return nil
+}
+
+// ParseLabels parses name=value pairs, such as those of METRICS_LABELS
+func ParseLabels(pairs []string) (Labels, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	labels := make(Labels, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q (expected name=value)", pair)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if _, exists := labels[name]; exists {
+			return nil, fmt.Errorf("duplicate label %q", name)
+		}
+		labels[name] = value
+	}
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// LabelMatch requires a metric to carry a label with exactly this value
+type LabelMatch struct {
+	Name  string
+	Value string
+}
+
+// LabelSelector matches metrics carrying all of its labels; an empty
+// selector matches every metric
+type LabelSelector []LabelMatch
+
+// ParseLabelSelector parses name:value matches, such as repeated ?label=
+// query parameters
+func ParseLabelSelector(matches []string) (LabelSelector, error) {
+	selector := make(LabelSelector, 0, len(matches))
+	for _, match := range matches {
+		name, value, ok := strings.Cut(match, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid label selector %q (expected name:value)", match)
+		}
+		if err := validateLabel(name, value); err != nil {
+			return nil, err
+		}
+		selector = append(selector, LabelMatch{Name: name, Value: value})
+	}
+
+	sort.Slice(selector, func(i, j int) bool {
+		if selector[i].Name != selector[j].Name {
+			return selector[i].Name < selector[j].Name
+		}
+		return selector[i].Value < selector[j].Value
+	})
+	return selector, nil
+}
+
+// String returns the selector in name:value form, sorted, e.g. for cache keys
+func (s LabelSelector) String() string {
+	matches := make([]string, len(s))
+	for i, match := range s {
+		matches[i] = match.Name + ":" + match.Value
+	}
+	return strings.Join(matches, ",")
+}
+
+// likeEscaper escapes LIKE wildcards, using ! as the escape character since
+// encoded labels may contain backslashes
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// apply restricts query to metrics matching the selector. Each match looks
+// for its "name":"value" pair in the stored JSON; label names cannot contain
+// quotes, so the pair can only match a whole name and value.
+func (s LabelSelector) apply(query *gorm.DB) *gorm.DB {
+	for _, match := range s {
+		name, _ := json.Marshal(match.Name)
+		value, _ := json.Marshal(match.Value)
+		pattern := "%" + likeEscaper.Replace(string(name)+":"+string(value)) + "%"
+		query = query.Where("labels LIKE ? ESCAPE '!'", pattern)
+	}
+	return query
+}
+
+// SetLabels sets the labels attached to every metric the collector stores
+func (c *Collector) SetLabels(labels Labels) {
+	c.labels = labels
+}
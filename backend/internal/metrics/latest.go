@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoMetricData is returned when a metric type has no stored samples yet
+var ErrNoMetricData = errors.New("no data for metric type")
+
+// GetLatestMetric returns the most recently stored sample of metricType.
+// Unlike GetCurrentMetrics it reads the database rather than sampling the
+// system, so it never blocks on a CPU measurement.
+func (c *Collector) GetLatestMetric(metricType MetricType) (*Metric, error) {
+	var metric Metric
+	err := c.db.Where("metric_type = ?", metricType).
+		Order("timestamp DESC, id DESC").
+		First(&metric).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w %s", ErrNoMetricData, metricType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest metric: %w", err)
+	}
+	return &metric, nil
+}
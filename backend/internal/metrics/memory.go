@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"runtime"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// memoryField maps a memory breakdown metric type to its bytes in a reading
+type memoryField struct {
+	metricType MetricType
+	bytes      func(*mem.VirtualMemoryStat) uint64
+}
+
+// memoryFields are the breakdown of used memory. Used memory on Linux
+// includes the page cache and buffers, which the kernel reclaims under
+// pressure, so available memory is what shows how close the host is to
+// running out.
+var memoryFields = []memoryField{
+	{MemoryAvailable, func(m *mem.VirtualMemoryStat) uint64 { return m.Available }},
+	{MemoryCached, func(m *mem.VirtualMemoryStat) uint64 { return m.Cached }},
+	{MemoryBuffers, func(m *mem.VirtualMemoryStat) uint64 { return m.Buffers }},
+}
+
+// memoryBreakdown returns the memory breakdown as percentages of total
+// memory. Linux reports every field, so zero there is a real reading; other
+// platforms leave the fields they do not track at zero, such as buffers on
+// macOS and cached memory on Windows, and those are left out.
+func memoryBreakdown(info *mem.VirtualMemoryStat) []Sample {
+	if info.Total == 0 {
+		return nil
+	}
+
+	samples := make([]Sample, 0, len(memoryFields))
+	for _, field := range memoryFields {
+		bytes := field.bytes(info)
+		if bytes == 0 && runtime.GOOS != "linux" {
+			continue
+		}
+		share := percentRange.Clamp(float64(bytes) / float64(info.Total) * 100)
+		samples = append(samples, Sample{Type: field.metricType, Value: share, Unit: UnitPercent})
+	}
+	return samples
+}
+
+// availablePercent returns available memory as a percentage of total, or nil
+// if the platform does not report it
+func availablePercent(info *mem.VirtualMemoryStat) *float64 {
+	for _, sample := range memoryBreakdown(info) {
+		if sample.Type == MemoryAvailable {
+			return &sample.Value
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -10,32 +11,146 @@ type MetricType string
 const (
 	CPUUsage    MetricType = "cpu_usage"
 	MemoryUsage MetricType = "memory_usage"
+
+	// LogErrorRate is the percentage of ERROR entries in an analyzed log file
+	LogErrorRate MetricType = "log_error_rate"
+
+	// DiskUsage is the used space on one mount point, stored with its mount
+	DiskUsage MetricType = "disk_usage"
+
+	// NetworkRxRate and NetworkTxRate are bytes per second received and sent,
+	// summed over the monitored interfaces
+	NetworkRxRate MetricType = "network_rx_rate"
+	NetworkTxRate MetricType = "network_tx_rate"
+
+	// CPUUser, CPUSystem, CPUIowait and CPUIdle are the share of CPU time
+	// spent in each mode, collected when the per-mode breakdown is enabled
+	CPUUser   MetricType = "cpu_user"
+	CPUSystem MetricType = "cpu_system"
+	CPUIowait MetricType = "cpu_iowait"
+	CPUIdle   MetricType = "cpu_idle"
+
+	// MemoryAvailable, MemoryCached and MemoryBuffers break memory down as a
+	// percentage of total memory. Available memory is what can be allocated
+	// without swapping, counting reclaimable cache.
+	MemoryAvailable MetricType = "memory_available"
+	MemoryCached    MetricType = "memory_cached"
+	MemoryBuffers   MetricType = "memory_buffers"
 )
 
 // Metric represents a system metric reading
 type Metric struct {
-	ID        uint       `json:"id" gorm:"primaryKey"`
-	Type      MetricType `json:"type" gorm:"column:metric_type"`
-	Value     float64    `json:"value" gorm:"not null"`
-	Unit      string     `json:"unit" gorm:"not null"`
-	Timestamp time.Time  `json:"timestamp" gorm:"not null"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID    uint       `json:"id" gorm:"primaryKey"`
+	Type  MetricType `json:"type" gorm:"column:metric_type"`
+	Value float64    `json:"value" gorm:"not null"`
+	Unit  string     `json:"unit" gorm:"not null"`
+	Mount string     `json:"mount,omitempty" gorm:"not null;default:'';index"`
+	// Host is the machine the sample came from; empty for ingested samples
+	// that did not name one
+	Host string `json:"host,omitempty" gorm:"not null;default:'';index"`
+	// Labels are optional name/value pairs, such as env=prod
+	Labels    Labels    `json:"labels,omitempty" gorm:"type:text;not null;default:''"`
+	Timestamp time.Time `json:"timestamp" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // SystemMetrics represents current system metrics
 type SystemMetrics struct {
-	CPUUsage    float64   `json:"cpu_usage"`
-	MemoryUsage float64   `json:"memory_usage"`
-	Timestamp   time.Time `json:"timestamp"`
+	CPUUsage    float64 `json:"cpu_usage"`
+	MemoryUsage float64 `json:"memory_usage"`
+	// MemoryAvailable is the available share of host memory, or nil where
+	// the platform does not report it
+	MemoryAvailable *float64 `json:"memory_available,omitempty"`
+	// DiskUsage maps each readable mount point to its used percentage
+	DiskUsage map[string]float64 `json:"disk_usage"`
+	// NetworkRx and NetworkTx are in bytes per second; per-interface rates
+	// are included when more than one interface is configured
+	NetworkRx         float64                  `json:"network_rx_rate"`
+	NetworkTx         float64                  `json:"network_tx_rate"`
+	NetworkInterfaces map[string]InterfaceRate `json:"network_interfaces,omitempty"`
+	Timestamp         time.Time                `json:"timestamp"`
+	// Missing lists the types among cpu_usage, memory_usage and the network
+	// rates that were not collected, because their source failed or had no
+	// reading yet. Their fields are left at zero and not checked against
+	// thresholds.
+	Missing []MetricType `json:"missing,omitempty"`
+}
+
+// systemMetricTypes are the types with a field of their own in SystemMetrics
+var systemMetricTypes = []MetricType{CPUUsage, MemoryUsage, NetworkRxRate, NetworkTxRate}
+
+// Reported reports whether metricType was collected, rather than left at zero
+// because its source failed
+func (m *SystemMetrics) Reported(metricType MetricType) bool {
+	for _, missing := range m.Missing {
+		if missing == metricType {
+			return false
+		}
+	}
+	return true
 }
 
 type MetricThreshold struct {
-	ID        uint       `json:"id" gorm:"primaryKey"`
-	Type      MetricType `json:"type" gorm:"column:metric_type;unique"`
-	Threshold float64    `json:"threshold" gorm:"not null"`
-	Enabled   bool       `json:"enabled" gorm:"default:true"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID        uint               `json:"id" gorm:"primaryKey"`
+	Type      MetricType         `json:"type" gorm:"column:metric_type;unique"`
+	Threshold float64            `json:"threshold" gorm:"not null"`
+	Direction ThresholdDirection `json:"direction" gorm:"not null;default:'above'"`
+	Enabled   bool               `json:"enabled" gorm:"default:true"`
+	// ResolveMargin is how far back past the threshold a value must go
+	// before its alert resolves, e.g. 5 resolves an 80% CPU alert below 75%
+	ResolveMargin float64   `json:"resolve_margin" gorm:"not null;default:0"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ThresholdDirection is the side of a threshold that counts as a breach
+type ThresholdDirection string
+
+const (
+	// DirectionAbove alerts when a value rises above the threshold
+	DirectionAbove ThresholdDirection = "above"
+	// DirectionBelow alerts when a value falls below the threshold, for
+	// metrics that should stay high
+	DirectionBelow ThresholdDirection = "below"
+)
+
+// ParseThresholdDirection validates a direction; an empty value means above
+func ParseThresholdDirection(value string) (ThresholdDirection, error) {
+	switch ThresholdDirection(value) {
+	case "", DirectionAbove:
+		return DirectionAbove, nil
+	case DirectionBelow:
+		return DirectionBelow, nil
+	default:
+		return "", fmt.Errorf("invalid threshold direction %q (expected above or below)", value)
+	}
+}
+
+// Breached reports whether value is on the breaching side of threshold.
+// Values equal to the threshold never breach; an empty direction means above.
+func (d ThresholdDirection) Breached(value, threshold float64) bool {
+	return d.BreachedBy(value, threshold, 0)
+}
+
+// BreachedBy reports whether value is beyond threshold by more than margin.
+// Values within margin of the threshold count as equal to it, so floating
+// point noise around the threshold does not breach.
+func (d ThresholdDirection) BreachedBy(value, threshold, margin float64) bool {
+	if d == DirectionBelow {
+		return value < threshold-margin
+	}
+	return value > threshold+margin
+}
+
+// RecoveredBy reports whether value is back past threshold by at least
+// resolveMargin. Values between the two still count as breaching, so a value
+// hovering at the threshold does not resolve and re-raise its alert on every
+// check.
+func (d ThresholdDirection) RecoveredBy(value, threshold, resolveMargin, margin float64) bool {
+	if d == DirectionBelow {
+		return !d.BreachedBy(value, threshold+resolveMargin, margin)
+	}
+	return !d.BreachedBy(value, threshold-resolveMargin, margin)
 }
 
 // MetricSummary represents aggregated metric data
@@ -46,3 +161,54 @@ type MetricSummary struct {
 	Max     float64    `json:"max"`
 	Count   int64      `json:"count"`
 }
+
+// Aggregation represents the function used to combine samples in a history bucket
+type Aggregation string
+
+const (
+	AggregationAvg Aggregation = "avg"
+	AggregationMin Aggregation = "min"
+	AggregationMax Aggregation = "max"
+)
+
+// ParseAggregation validates an aggregation name, defaulting to avg when empty
+func ParseAggregation(name string) (Aggregation, error) {
+	switch Aggregation(name) {
+	case "":
+		return AggregationAvg, nil
+	case AggregationAvg, AggregationMin, AggregationMax:
+		return Aggregation(name), nil
+	default:
+		return "", fmt.Errorf("unknown aggregation %q (expected avg, min or max)", name)
+	}
+}
+
+// AggregatedMetric represents a downsampled history point covering one bucket
+type AggregatedMetric struct {
+	Type      MetricType  `json:"type"`
+	Value     float64     `json:"value"`
+	Unit      string      `json:"unit,omitempty"`
+	Count     int64       `json:"count"`
+	Agg       Aggregation `json:"agg"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// DefaultRegressionThreshold is the percentage increase treated as a significant regression
+const DefaultRegressionThreshold = 10.0
+
+// MetricComparison compares a metric's summary over the latest window with the
+// equal-length window before it
+type MetricComparison struct {
+	Type     MetricType     `json:"type"`
+	Window   string         `json:"window"`
+	Current  *MetricSummary `json:"current"`
+	Previous *MetricSummary `json:"previous"`
+	// AverageChange and MaxChange are percentage changes from the previous
+	// window; nil when the previous window has no data or a zero value
+	AverageChange *float64 `json:"average_change_percent"`
+	MaxChange     *float64 `json:"max_change_percent"`
+	// Regressions lists the fields ("average", "max") that rose by more than
+	// the significance threshold
+	Regressions []string `json:"regressions"`
+	Regressed   bool     `json:"regressed"`
+}
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// InterfaceRate is the throughput of one network interface
+type InterfaceRate struct {
+	RxRate float64 `json:"rx_rate"`
+	TxRate float64 `json:"tx_rate"`
+}
+
+// networkSource reports receive and transmit rates summed over the selected
+// interfaces. Rates are computed from the counter deltas between cycles, so
+// the first cycle only records a baseline unless a persisted one is recent
+// enough; see SetCounterPersistence.
+type networkSource struct {
+	mu sync.Mutex
+	// interfaces restricts collection to the named interfaces; when empty
+	// every interface is summed except loopback, unless includeLoopback
+	interfaces      []string
+	includeLoopback bool
+	missing         map[string]bool
+
+	previous   map[string]psnet.IOCountersStat
+	previousAt time.Time
+	// store persists previous across restarts; nil keeps it in memory only
+	store *counterStore
+
+	// perInterface holds the latest rates per interface when more than one
+	// interface is configured
+	perInterface map[string]InterfaceRate
+}
+
+func newNetworkSource() *networkSource {
+	return &networkSource{missing: make(map[string]bool)}
+}
+
+func (s *networkSource) Name() string {
+	return "network"
+}
+
+// Collect samples the network counters. Configured interfaces that do not
+// exist are skipped and logged once until they appear.
+func (s *networkSource) Collect(ctx context.Context) ([]Sample, error) {
+	counters, err := psnet.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read network counters: %w", err)
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]psnet.IOCountersStat, len(counters))
+	for _, counter := range counters {
+		current[counter.Name] = counter
+	}
+
+	selected := s.selectInterfaces(current)
+	previous, previousAt := s.previous, s.previousAt
+	if previous == nil {
+		var stored map[string]psnet.IOCountersStat
+		if sampledAt, ok := s.store.load(s.Name(), &stored, now); ok {
+			previous, previousAt = stored, sampledAt
+		}
+	}
+	s.previous, s.previousAt = current, now
+	s.store.save(s.Name(), current, now)
+
+	elapsed := now.Sub(previousAt).Seconds()
+	if previous == nil || elapsed <= 0 {
+		return nil, nil
+	}
+
+	var total InterfaceRate
+	perInterface := make(map[string]InterfaceRate, len(selected))
+	for _, name := range selected {
+		before, ok := previous[name]
+		after := current[name]
+		// Skip interfaces that just appeared or whose counters were reset
+		if !ok || after.BytesRecv < before.BytesRecv || after.BytesSent < before.BytesSent {
+			continue
+		}
+
+		rate := InterfaceRate{
+			RxRate: float64(after.BytesRecv-before.BytesRecv) / elapsed,
+			TxRate: float64(after.BytesSent-before.BytesSent) / elapsed,
+		}
+		perInterface[name] = rate
+		total.RxRate += rate.RxRate
+		total.TxRate += rate.TxRate
+	}
+
+	if len(s.interfaces) > 1 {
+		s.perInterface = perInterface
+	} else {
+		s.perInterface = nil
+	}
+
+	return []Sample{
+		{Type: NetworkRxRate, Value: total.RxRate, Unit: UnitBytesPerSecond},
+		{Type: NetworkTxRate, Value: total.TxRate, Unit: UnitBytesPerSecond},
+	}, nil
+}
+
+// selectInterfaces returns the names of the interfaces to sum, logging
+// configured interfaces that are missing once. Callers must hold s.mu.
+func (s *networkSource) selectInterfaces(current map[string]psnet.IOCountersStat) []string {
+	if len(s.interfaces) > 0 {
+		selected := make([]string, 0, len(s.interfaces))
+		for _, name := range s.interfaces {
+			if _, ok := current[name]; !ok {
+				if !s.missing[name] {
+					log.Printf("Network interface %s not found, skipping", name)
+					s.missing[name] = true
+				}
+				continue
+			}
+			if s.missing[name] {
+				log.Printf("Network interface %s available again", name)
+				delete(s.missing, name)
+			}
+			selected = append(selected, name)
+		}
+		return selected
+	}
+
+	loopback := make(map[string]bool)
+	if !s.includeLoopback {
+		if ifaces, err := net.Interfaces(); err == nil {
+			for _, iface := range ifaces {
+				if iface.Flags&net.FlagLoopback != 0 {
+					loopback[iface.Name] = true
+				}
+			}
+		}
+	}
+
+	selected := make([]string, 0, len(current))
+	for name := range current {
+		if !loopback[name] {
+			selected = append(selected, name)
+		}
+	}
+	return selected
+}
+
+// configure replaces the interface selection and resets the rate baseline
+func (s *networkSource) configure(interfaces []string, includeLoopback bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.interfaces = interfaces
+	s.includeLoopback = includeLoopback
+	s.missing = make(map[string]bool)
+	s.previous = nil
+	s.perInterface = nil
+}
+
+// prime records the current counters as the rate baseline, so the next
+// cycle measures throughput from now
+func (s *networkSource) prime() {
+	counters, err := psnet.IOCounters(true)
+	if err != nil {
+		log.Printf("Failed to read network counters: %v", err)
+		return
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]psnet.IOCountersStat, len(counters))
+	for _, counter := range counters {
+		current[counter.Name] = counter
+	}
+	s.previous, s.previousAt = current, now
+}
+
+// setCounterStore persists the rate baseline in store; nil stops persisting
+func (s *networkSource) setCounterStore(store *counterStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store = store
+}
+
+// interfaceRates returns a copy of the latest per-interface rates, or nil
+// when fewer than two interfaces are configured
+func (s *networkSource) interfaceRates() map[string]InterfaceRate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.perInterface == nil {
+		return nil
+	}
+	rates := make(map[string]InterfaceRate, len(s.perInterface))
+	for name, rate := range s.perInterface {
+		rates[name] = rate
+	}
+	return rates
+}
+
+// SetNetworkInterfaces restricts network collection to the named interfaces.
+// An empty list sums every interface, skipping loopback unless
+// includeLoopback is set; named interfaces are always used as given.
+func (c *Collector) SetNetworkInterfaces(interfaces []string, includeLoopback bool) {
+	c.network.configure(interfaces, includeLoopback)
+}
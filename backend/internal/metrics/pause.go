@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"log"
+	"time"
+)
+
+// Pause stops collection cycles, for example during noisy maintenance,
+// without stopping the collection loop. It reports whether collection was
+// running before; pausing twice has no further effect.
+func (c *Collector) Pause() bool {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	if !c.pausedAt.IsZero() {
+		return false
+	}
+	c.pausedAt = c.clock.Now()
+	log.Println("Metrics collection paused")
+	return true
+}
+
+// Resume restarts collection cycles after Pause. It reports whether
+// collection was paused before. Rate baselines are taken again, so the first
+// cycle after resuming does not average over the paused period.
+func (c *Collector) Resume() bool {
+	c.statusMu.Lock()
+	if c.pausedAt.IsZero() {
+		c.statusMu.Unlock()
+		return false
+	}
+	pausedFor := c.clock.Now().Sub(c.pausedAt)
+	c.statusMu.Unlock()
+
+	c.primeCPUBaselines()
+	c.network.prime()
+
+	c.statusMu.Lock()
+	c.pausedAt = time.Time{}
+	c.resumedAt = c.clock.Now()
+	c.statusMu.Unlock()
+
+	log.Printf("Metrics collection resumed after %v", pausedFor.Round(time.Second))
+	return true
+}
+
+// Paused reports whether collection is paused
+func (c *Collector) Paused() bool {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	return !c.pausedAt.IsZero()
+}
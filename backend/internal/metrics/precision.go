@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// MaxPrecision is the most decimal places values can be rounded to
+const MaxPrecision = 10
+
+// FullPrecision leaves values unrounded
+const FullPrecision = -1
+
+// ParsePrecision parses a number of decimal places between 0 and
+// MaxPrecision; empty means FullPrecision
+func ParsePrecision(value string) (int, error) {
+	if value == "" {
+		return FullPrecision, nil
+	}
+	precision, err := strconv.Atoi(value)
+	if err != nil || precision < 0 || precision > MaxPrecision {
+		return 0, fmt.Errorf("precision must be an integer between 0 and %d", MaxPrecision)
+	}
+	return precision, nil
+}
+
+// Round rounds value to precision decimal places; FullPrecision returns it unchanged
+func Round(value float64, precision int) float64 {
+	if precision < 0 {
+		return value
+	}
+	scale := math.Pow(10, float64(precision))
+	return math.Round(value*scale) / scale
+}
+
+// RoundMetrics rounds metric values in place
+func RoundMetrics(metrics []Metric, precision int) {
+	if precision < 0 {
+		return
+	}
+	for i := range metrics {
+		metrics[i].Value = Round(metrics[i].Value, precision)
+	}
+}
+
+// RoundAggregated rounds aggregated values in place
+func RoundAggregated(points []AggregatedMetric, precision int) {
+	if precision < 0 {
+		return
+	}
+	for i := range points {
+		points[i].Value = Round(points[i].Value, precision)
+	}
+}
+
+// Rounded returns a copy of m with every value rounded; the original, which
+// may be shared with the collector's cache, is not modified
+func (m *SystemMetrics) Rounded(precision int) *SystemMetrics {
+	if precision < 0 {
+		return m
+	}
+
+	rounded := *m
+	rounded.CPUUsage = Round(m.CPUUsage, precision)
+	rounded.MemoryUsage = Round(m.MemoryUsage, precision)
+	if m.MemoryAvailable != nil {
+		available := Round(*m.MemoryAvailable, precision)
+		rounded.MemoryAvailable = &available
+	}
+	rounded.NetworkRx = Round(m.NetworkRx, precision)
+	rounded.NetworkTx = Round(m.NetworkTx, precision)
+	if m.DiskUsage != nil {
+		rounded.DiskUsage = make(map[string]float64, len(m.DiskUsage))
+		for mount, usage := range m.DiskUsage {
+			rounded.DiskUsage[mount] = Round(usage, precision)
+		}
+	}
+	if m.NetworkInterfaces != nil {
+		rounded.NetworkInterfaces = make(map[string]InterfaceRate, len(m.NetworkInterfaces))
+		for name, rate := range m.NetworkInterfaces {
+			rounded.NetworkInterfaces[name] = InterfaceRate{
+				RxRate: Round(rate.RxRate, precision),
+				TxRate: Round(rate.TxRate, precision),
+			}
+		}
+	}
+	return &rounded
+}
+
+// Rounded returns a copy of s with its statistics rounded
+func (s *MetricSummary) Rounded(precision int) *MetricSummary {
+	if precision < 0 {
+		return s
+	}
+
+	rounded := *s
+	rounded.Average = Round(s.Average, precision)
+	rounded.Min = Round(s.Min, precision)
+	rounded.Max = Round(s.Max, precision)
+	return &rounded
+}
+
+// Round rounds the fleet values in place
+func (f *FleetAggregate) Round(precision int) {
+	if precision < 0 {
+		return
+	}
+	for i := range f.Points {
+		f.Points[i].Value = Round(f.Points[i].Value, precision)
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import "sync"
+
+// DefaultRecentBufferSize is the number of samples kept in memory per metric type
+const DefaultRecentBufferSize = 120
+
+// ringBuffer holds the most recent metrics of one type, overwriting the oldest
+type ringBuffer struct {
+	items []Metric
+	next  int
+	full  bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{items: make([]Metric, size)}
+}
+
+// add appends a metric, overwriting the oldest once the buffer is full
+func (r *ringBuffer) add(metric Metric) {
+	r.items[r.next] = metric
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// latest returns up to n metrics, newest first
+func (r *ringBuffer) latest(n int) []Metric {
+	count := r.next
+	if r.full {
+		count = len(r.items)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+
+	result := make([]Metric, n)
+	for i := 0; i < n; i++ {
+		idx := (r.next - 1 - i + len(r.items)) % len(r.items)
+		result[i] = r.items[idx]
+	}
+	return result
+}
+
+// recentMetrics keeps a ring buffer per metric type
+type recentMetrics struct {
+	mu      sync.RWMutex
+	size    int
+	buffers map[MetricType]*ringBuffer
+}
+
+func newRecentMetrics(size int) *recentMetrics {
+	if size <= 0 {
+		size = DefaultRecentBufferSize
+	}
+	return &recentMetrics{size: size, buffers: make(map[MetricType]*ringBuffer)}
+}
+
+func (r *recentMetrics) add(metric Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buffer, ok := r.buffers[metric.Type]
+	if !ok {
+		buffer = newRingBuffer(r.size)
+		r.buffers[metric.Type] = buffer
+	}
+	buffer.add(metric)
+}
+
+func (r *recentMetrics) latest(metricType MetricType, n int) []Metric {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	buffer, ok := r.buffers[metricType]
+	if !ok {
+		return []Metric{}
+	}
+	return buffer.latest(n)
+}
+
+// latestCycle returns the buffered samples of metricType from host's most
+// recent collection cycle, i.e. those sharing its newest timestamp
+func (r *recentMetrics) latestCycle(metricType MetricType, host string) []Metric {
+	var cycle []Metric
+	for _, metric := range r.latest(metricType, 0) {
+		if metric.Host != host {
+			continue
+		}
+		if len(cycle) > 0 && !metric.Timestamp.Equal(cycle[0].Timestamp) {
+			break
+		}
+		cycle = append(cycle, metric)
+	}
+	return cycle
+}
+
+// clear discards all buffered samples
+func (r *recentMetrics) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buffers = make(map[MetricType]*ringBuffer)
+}
+
+// SetRecentBufferSize sets how many samples per metric type are kept in
+// memory. It discards samples already buffered, so call it before Start.
+func (c *Collector) SetRecentBufferSize(size int) {
+	c.recent = newRecentMetrics(size)
+}
+
+// GetRecent returns up to n of the latest collected samples for a metric
+// type from memory, newest first. n <= 0 returns the whole buffer.
+func (c *Collector) GetRecent(metricType MetricType, n int) []Metric {
+	return c.recent.latest(metricType, n)
+}
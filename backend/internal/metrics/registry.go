@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// TypeInfo describes a metric type and the base unit its values are stored in
+type TypeInfo struct {
+	Type        MetricType `json:"type"`
+	DisplayName string     `json:"display_name"`
+	Unit        string     `json:"unit"`
+	Description string     `json:"description"`
+	Range       ValueRange `json:"range"`
+}
+
+// ValueRange bounds the valid values of a metric type; a nil end is unbounded
+type ValueRange struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// bound returns a pointer to limit, for declaring ranges
+func bound(limit float64) *float64 {
+	return &limit
+}
+
+// Ranges shared by registered types
+var (
+	percentRange     = ValueRange{Min: bound(0), Max: bound(100)}
+	nonNegativeRange = ValueRange{Min: bound(0)}
+)
+
+// Check returns an error if value is NaN or outside the range
+func (r ValueRange) Check(value float64) error {
+	switch {
+	case math.IsNaN(value):
+		return fmt.Errorf("value is not a number")
+	case r.Min != nil && r.Max != nil && (value < *r.Min || value > *r.Max):
+		return fmt.Errorf("value %g out of range %g-%g", value, *r.Min, *r.Max)
+	case r.Min != nil && value < *r.Min:
+		if *r.Min == 0 {
+			return fmt.Errorf("value %g must not be negative", value)
+		}
+		return fmt.Errorf("value %g must be at least %g", value, *r.Min)
+	case r.Max != nil && value > *r.Max:
+		return fmt.Errorf("value %g must be at most %g", value, *r.Max)
+	}
+	return nil
+}
+
+// Clamp returns value limited to the range
+func (r ValueRange) Clamp(value float64) float64 {
+	if r.Min != nil && value < *r.Min {
+		return *r.Min
+	}
+	if r.Max != nil && value > *r.Max {
+		return *r.Max
+	}
+	return value
+}
+
+// registry holds the known metric types: the built-in ones, merged with any
+// configured through ApplyTypeMetadata
+var (
+	registryMu sync.RWMutex
+	registry   = map[MetricType]TypeInfo{
+		CPUUsage:     {Type: CPUUsage, DisplayName: "CPU Usage", Unit: UnitPercent, Description: "CPU usage across all cores", Range: percentRange},
+		MemoryUsage:  {Type: MemoryUsage, DisplayName: "Memory Usage", Unit: UnitPercent, Description: "Used virtual memory", Range: percentRange},
+		LogErrorRate: {Type: LogErrorRate, DisplayName: "Log Error Rate", Unit: UnitPercent, Description: "Share of ERROR entries in an analyzed log file", Range: percentRange},
+		DiskUsage:    {Type: DiskUsage, DisplayName: "Disk Usage", Unit: UnitPercent, Description: "Used space per mount point", Range: percentRange},
+
+		NetworkRxRate: {Type: NetworkRxRate, DisplayName: "Network Received", Unit: UnitBytesPerSecond, Description: "Bytes received per second on monitored interfaces", Range: nonNegativeRange},
+		NetworkTxRate: {Type: NetworkTxRate, DisplayName: "Network Sent", Unit: UnitBytesPerSecond, Description: "Bytes sent per second on monitored interfaces", Range: nonNegativeRange},
+
+		CPUUser:   {Type: CPUUser, DisplayName: "CPU User", Unit: UnitPercent, Description: "CPU time spent running user code", Range: percentRange},
+		CPUSystem: {Type: CPUSystem, DisplayName: "CPU System", Unit: UnitPercent, Description: "CPU time spent in the kernel", Range: percentRange},
+		CPUIowait: {Type: CPUIowait, DisplayName: "CPU I/O Wait", Unit: UnitPercent, Description: "CPU time spent idle waiting for I/O (Linux only)", Range: percentRange},
+		CPUIdle:   {Type: CPUIdle, DisplayName: "CPU Idle", Unit: UnitPercent, Description: "CPU time spent idle", Range: percentRange},
+
+		MemoryAvailable: {Type: MemoryAvailable, DisplayName: "Memory Available", Unit: UnitPercent, Description: "Memory that can be allocated without swapping, including reclaimable cache", Range: percentRange},
+		MemoryCached:    {Type: MemoryCached, DisplayName: "Memory Cached", Unit: UnitPercent, Description: "Memory used by the page cache", Range: percentRange},
+		MemoryBuffers:   {Type: MemoryBuffers, DisplayName: "Memory Buffers", Unit: UnitPercent, Description: "Memory used by kernel buffers", Range: percentRange},
+	}
+)
+
+// LookupType returns the registry entry for a metric type
+func LookupType(metricType MetricType) (TypeInfo, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	info, ok := registry[metricType]
+	return info, ok
+}
+
+// DisplayName returns the name people see for a metric type, or the type
+// itself when it is not registered
+func DisplayName(metricType MetricType) string {
+	if info, ok := LookupType(metricType); ok && info.DisplayName != "" {
+		return info.DisplayName
+	}
+	return string(metricType)
+}
+
+// RegisteredTypes returns all known metric types sorted by name
+func RegisteredTypes() []TypeInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]TypeInfo, 0, len(registry))
+	for _, info := range registry {
+		types = append(types, info)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Type < types[j].Type
+	})
+	return types
+}
@@ -0,0 +1,347 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrMountRequired is returned when aggregating a per-mount type without
+// naming the mount, which would average mount points together
+var ErrMountRequired = errors.New("mount is required for per-mount metric types")
+
+// RollupResolution identifies the bucket size of a rollup row
+type RollupResolution string
+
+const (
+	RollupHourly RollupResolution = "hour"
+	RollupDaily  RollupResolution = "day"
+)
+
+// Duration returns the bucket size for the resolution
+func (r RollupResolution) Duration() time.Duration {
+	if r == RollupDaily {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// MetricRollup stores aggregated raw metrics for one metric type, mount, host
+// and bucket
+type MetricRollup struct {
+	ID   uint       `json:"id" gorm:"primaryKey"`
+	Type MetricType `json:"type" gorm:"column:metric_type;not null;uniqueIndex:idx_rollup_bucket"`
+	// Mount is set for per-mount types such as DiskUsage, so each mount
+	// point is rolled up on its own
+	Mount string `json:"mount,omitempty" gorm:"not null;default:'';uniqueIndex:idx_rollup_bucket"`
+	// Host is the machine the rolled-up samples came from, so fleet
+	// aggregates still see each host past the raw retention
+	Host        string           `json:"host,omitempty" gorm:"not null;default:'';uniqueIndex:idx_rollup_bucket"`
+	Resolution  RollupResolution `json:"resolution" gorm:"not null;uniqueIndex:idx_rollup_bucket"`
+	BucketStart time.Time        `json:"bucket_start" gorm:"not null;uniqueIndex:idx_rollup_bucket"`
+	Average     float64          `json:"average"`
+	Min         float64          `json:"min"`
+	Max         float64          `json:"max"`
+	Count       int64            `json:"count"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// RetentionPolicy controls when raw metrics are compacted into rollups
+type RetentionPolicy struct {
+	// RawRetention is how long raw rows are kept before being rolled up
+	// hourly and deleted; zero disables compaction
+	RawRetention time.Duration
+	// HourlyRetention is how long hourly rollups are kept before being
+	// rolled up daily; zero keeps hourly rollups forever
+	HourlyRetention time.Duration
+}
+
+// historySample is a raw reading or rollup bucket in a common shape
+type historySample struct {
+	Timestamp time.Time
+	Sum       float64
+	Min       float64
+	Max       float64
+	Count     int64
+}
+
+// value returns the sample's starting value for the given aggregation
+func (s historySample) value(agg Aggregation) float64 {
+	switch agg {
+	case AggregationMin:
+		return s.Min
+	case AggregationMax:
+		return s.Max
+	default:
+		return s.Sum / float64(s.Count)
+	}
+}
+
+// rollupKey identifies a rollup bucket while accumulating
+type rollupKey struct {
+	Type  MetricType
+	Mount string
+	Host  string
+	Start int64
+}
+
+// rollupAccumulator accumulates samples into a rollup bucket
+type rollupAccumulator struct {
+	rollup MetricRollup
+	sum    float64
+}
+
+func (a *rollupAccumulator) add(sum, min, max float64, count int64) {
+	if a.rollup.Count == 0 || min < a.rollup.Min {
+		a.rollup.Min = min
+	}
+	if a.rollup.Count == 0 || max > a.rollup.Max {
+		a.rollup.Max = max
+	}
+	a.sum += sum
+	a.rollup.Count += count
+	a.rollup.Average = a.sum / float64(a.rollup.Count)
+}
+
+// SetRetentionPolicy sets the raw and hourly retention used by compaction
+func (c *Collector) SetRetentionPolicy(policy RetentionPolicy) {
+	c.retention = policy
+}
+
+// StartCompaction periodically compacts old metrics into rollups until ctx is done
+func (c *Collector) StartCompaction(ctx context.Context, interval time.Duration) {
+	if c.retention.RawRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting metric compaction every %v (raw retention: %v, hourly retention: %v)",
+		interval, c.retention.RawRetention, c.retention.HourlyRetention)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.CompactMetrics(); err != nil {
+				log.Printf("Error compacting metrics: %v", err)
+			}
+		}
+	}
+}
+
+// CompactMetrics rolls raw metrics older than the raw retention into hourly
+// rollups, and hourly rollups older than the hourly retention into daily ones,
+// deleting the compacted rows
+func (c *Collector) CompactMetrics() error {
+	if c.retention.RawRetention <= 0 {
+		return nil
+	}
+
+	now := c.clock.Now()
+
+	rawCount, err := c.compactRaw(now.Add(-c.retention.RawRetention).Truncate(time.Hour))
+	if err != nil {
+		return err
+	}
+
+	var hourlyCount int64
+	if c.retention.HourlyRetention > 0 {
+		hourlyCount, err = c.compactHourly(now.Add(-c.retention.HourlyRetention).Truncate(24 * time.Hour))
+		if err != nil {
+			return err
+		}
+	}
+
+	if rawCount > 0 || hourlyCount > 0 {
+		log.Printf("Compacted %d raw metrics and %d hourly rollups", rawCount, hourlyCount)
+	}
+
+	return nil
+}
+
+// compactRaw rolls raw metrics before cutoff into hourly rollups
+func (c *Collector) compactRaw(cutoff time.Time) (int64, error) {
+	var compacted int64
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		rows, err := tx.Model(&Metric{}).Where("timestamp < ?", cutoff).Rows()
+		if err != nil {
+			return fmt.Errorf("failed to read raw metrics: %w", err)
+		}
+
+		buckets := make(map[rollupKey]*rollupAccumulator)
+		for rows.Next() {
+			var metric Metric
+			if err := tx.ScanRows(rows, &metric); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan raw metric: %w", err)
+			}
+
+			start := metric.Timestamp.Truncate(time.Hour)
+			acc := accumulatorFor(buckets, metric.Type, metric.Mount, metric.Host, RollupHourly, start)
+			acc.add(metric.Value, metric.Value, metric.Value, 1)
+		}
+		rows.Close()
+
+		if err := mergeRollups(tx, buckets); err != nil {
+			return err
+		}
+
+		result := tx.Where("timestamp < ?", cutoff).Delete(&Metric{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete compacted metrics: %w", result.Error)
+		}
+		compacted = result.RowsAffected
+
+		return nil
+	})
+
+	return compacted, err
+}
+
+// compactHourly rolls hourly rollups before cutoff into daily rollups
+func (c *Collector) compactHourly(cutoff time.Time) (int64, error) {
+	var compacted int64
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		var hourly []MetricRollup
+		if err := tx.Where("resolution = ? AND bucket_start < ?", RollupHourly, cutoff).
+			Find(&hourly).Error; err != nil {
+			return fmt.Errorf("failed to read hourly rollups: %w", err)
+		}
+
+		buckets := make(map[rollupKey]*rollupAccumulator)
+		for _, rollup := range hourly {
+			start := rollup.BucketStart.Truncate(24 * time.Hour)
+			acc := accumulatorFor(buckets, rollup.Type, rollup.Mount, rollup.Host, RollupDaily, start)
+			acc.add(rollup.Average*float64(rollup.Count), rollup.Min, rollup.Max, rollup.Count)
+		}
+
+		if err := mergeRollups(tx, buckets); err != nil {
+			return err
+		}
+
+		result := tx.Where("resolution = ? AND bucket_start < ?", RollupHourly, cutoff).Delete(&MetricRollup{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete compacted rollups: %w", result.Error)
+		}
+		compacted = result.RowsAffected
+
+		return nil
+	})
+
+	return compacted, err
+}
+
+// accumulatorFor returns the accumulator for a bucket, creating it if needed
+func accumulatorFor(buckets map[rollupKey]*rollupAccumulator, metricType MetricType, mount, host string, resolution RollupResolution, start time.Time) *rollupAccumulator {
+	key := rollupKey{Type: metricType, Mount: mount, Host: host, Start: start.Unix()}
+	acc, ok := buckets[key]
+	if !ok {
+		acc = &rollupAccumulator{rollup: MetricRollup{
+			Type:        metricType,
+			Mount:       mount,
+			Host:        host,
+			Resolution:  resolution,
+			BucketStart: start,
+		}}
+		buckets[key] = acc
+	}
+	return acc
+}
+
+// mergeRollups saves accumulated buckets, combining them with any existing
+// rollup for the same bucket from an earlier compaction
+func mergeRollups(tx *gorm.DB, buckets map[rollupKey]*rollupAccumulator) error {
+	for _, acc := range buckets {
+		var existing MetricRollup
+		err := tx.Where("metric_type = ? AND mount = ? AND host = ? AND resolution = ? AND bucket_start = ?",
+			acc.rollup.Type, acc.rollup.Mount, acc.rollup.Host, acc.rollup.Resolution, acc.rollup.BucketStart).
+			First(&existing).Error
+
+		switch {
+		case err == nil:
+			acc.add(existing.Average*float64(existing.Count), existing.Min, existing.Max, existing.Count)
+			acc.rollup.ID = existing.ID
+			acc.rollup.CreatedAt = existing.CreatedAt
+			if err := tx.Save(&acc.rollup).Error; err != nil {
+				return fmt.Errorf("failed to update rollup: %w", err)
+			}
+		case err == gorm.ErrRecordNotFound:
+			if err := tx.Create(&acc.rollup).Error; err != nil {
+				return fmt.Errorf("failed to create rollup: %w", err)
+			}
+		default:
+			return fmt.Errorf("failed to read rollup: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// historySamples returns raw readings and rollup buckets for a metric type
+// since the given time, ordered oldest first, limited to one mount point when
+// mount is set. Raw rows are deleted when they are rolled up, so the two
+// sources never overlap. Rollups carry no labels, so with a non-empty
+// selector only matching raw readings are returned.
+func (c *Collector) historySamples(metricType MetricType, mount string, since time.Time, selector LabelSelector) ([]historySample, error) {
+	query := c.db.Where("metric_type = ? AND timestamp >= ?", metricType, since)
+	if mount != "" {
+		query = query.Where("mount = ?", mount)
+	}
+
+	var rows []Metric
+	if err := selector.apply(query).
+		Order("timestamp ASC").
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get metric history: %w", err)
+	}
+
+	var rollups []MetricRollup
+	if len(selector) == 0 {
+		query := c.db.Where("metric_type = ? AND bucket_start >= ?", metricType, since)
+		if mount != "" {
+			query = query.Where("mount = ?", mount)
+		}
+		if err := query.
+			Order("bucket_start ASC").
+			Find(&rollups).Error; err != nil {
+			return nil, fmt.Errorf("failed to get metric rollups: %w", err)
+		}
+	}
+
+	samples := make([]historySample, 0, len(rows)+len(rollups))
+	for _, rollup := range rollups {
+		samples = append(samples, historySample{
+			Timestamp: rollup.BucketStart,
+			Sum:       rollup.Average * float64(rollup.Count),
+			Min:       rollup.Min,
+			Max:       rollup.Max,
+			Count:     rollup.Count,
+		})
+	}
+	for _, row := range rows {
+		samples = append(samples, historySample{
+			Timestamp: row.Timestamp,
+			Sum:       row.Value,
+			Min:       row.Value,
+			Max:       row.Value,
+			Count:     1,
+		})
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Timestamp.Before(samples[j].Timestamp)
+	})
+
+	return samples, nil
+}
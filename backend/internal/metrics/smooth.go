@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseSmoothing parses an exponential moving average alpha, which must be in
+// (0, 1]. Smaller values smooth more; 1 leaves the series unchanged.
+func ParseSmoothing(value string) (float64, error) {
+	alpha, err := strconv.ParseFloat(value, 64)
+	if err != nil || alpha <= 0 || alpha > 1 {
+		return 0, fmt.Errorf("invalid smoothing factor %q (expected a number in (0, 1])", value)
+	}
+	return alpha, nil
+}
+
+// ema tracks an exponential moving average
+type ema struct {
+	alpha   float64
+	value   float64
+	started bool
+}
+
+// next folds sample into the average and returns the new average
+func (e *ema) next(sample float64) float64 {
+	if !e.started {
+		e.value, e.started = sample, true
+	} else {
+		e.value = e.alpha*sample + (1-e.alpha)*e.value
+	}
+	return e.value
+}
+
+// SmoothMetrics replaces metric values in place with their exponential moving
+// average. metrics must be ordered newest first, as history is returned; the
+// average runs from the oldest sample forward, separately per disk mount.
+func SmoothMetrics(metrics []Metric, alpha float64) {
+	series := make(map[string]*ema)
+	for i := len(metrics) - 1; i >= 0; i-- {
+		avg, ok := series[metrics[i].Mount]
+		if !ok {
+			avg = &ema{alpha: alpha}
+			series[metrics[i].Mount] = avg
+		}
+		metrics[i].Value = avg.next(metrics[i].Value)
+	}
+}
+
+// SmoothAggregated is SmoothMetrics for bucketed history
+func SmoothAggregated(points []AggregatedMetric, alpha float64) {
+	avg := &ema{alpha: alpha}
+	for i := len(points) - 1; i >= 0; i-- {
+		points[i].Value = avg.next(points[i].Value)
+	}
+}
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// Snapshot is everything known about the system right now. The host
+// readings are sampled together under one timestamp; Metrics adds the latest
+// collected value of every registered metric type.
+type Snapshot struct {
+	Timestamp   time.Time               `json:"timestamp"`
+	CPU         CPUSnapshot             `json:"cpu"`
+	Memory      MemorySnapshot          `json:"memory"`
+	Swap        SwapSnapshot            `json:"swap"`
+	Disk        map[string]DiskSnapshot `json:"disk"`
+	Network     NetworkSnapshot         `json:"network"`
+	LoadAverage *LoadAverage            `json:"load_average,omitempty"`
+	Uptime      uint64                  `json:"uptime_seconds"`
+
+	// Metrics holds the samples of each registered type's latest collection
+	// cycle, so types added to the registry appear without changes here
+	Metrics map[MetricType][]SnapshotSample `json:"metrics"`
+
+	// Errors names the parts that could not be sampled and why
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// CPUSnapshot is CPU usage in percent, overall and per core, measured over
+// the same window. Modes is set when the per-mode breakdown is collected.
+type CPUSnapshot struct {
+	Usage   float64            `json:"usage"`
+	PerCore []float64          `json:"per_core"`
+	Modes   map[string]float64 `json:"modes,omitempty"`
+}
+
+// MemorySnapshot is memory usage. UsedPercent matches memory_usage, so it is
+// relative to the container limit in cgroup mode; the byte counts are
+// host-wide.
+type MemorySnapshot struct {
+	UsedPercent float64 `json:"used_percent"`
+	Total       uint64  `json:"total_bytes"`
+	Used        uint64  `json:"used_bytes"`
+	Cached      uint64  `json:"cached_bytes"`
+	Buffers     uint64  `json:"buffers_bytes"`
+	Free        uint64  `json:"free_bytes"`
+	Available   uint64  `json:"available_bytes"`
+}
+
+// SwapSnapshot is swap usage
+type SwapSnapshot struct {
+	UsedPercent float64 `json:"used_percent"`
+	Total       uint64  `json:"total_bytes"`
+	Used        uint64  `json:"used_bytes"`
+	Free        uint64  `json:"free_bytes"`
+}
+
+// DiskSnapshot is the usage of one mount point
+type DiskSnapshot struct {
+	UsedPercent float64 `json:"used_percent"`
+	Total       uint64  `json:"total_bytes"`
+	Used        uint64  `json:"used_bytes"`
+	Free        uint64  `json:"free_bytes"`
+}
+
+// NetworkSnapshot is network throughput in bytes per second. Rates need two
+// counter readings, so they are the collection loop's latest.
+type NetworkSnapshot struct {
+	RxRate     float64                  `json:"rx_rate"`
+	TxRate     float64                  `json:"tx_rate"`
+	Interfaces map[string]InterfaceRate `json:"interfaces,omitempty"`
+}
+
+// LoadAverage is the 1, 5 and 15 minute load average
+type LoadAverage struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// SnapshotSample is one collected value of a registered metric type
+type SnapshotSample struct {
+	Value     float64   `json:"value"`
+	Unit      string    `json:"unit"`
+	Mount     string    `json:"mount,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Snapshot samples CPU, memory, swap, disks, load average and uptime
+// concurrently, blocking for the CPU sample interval, and adds the latest
+// network rates and collected metrics. A part that cannot be sampled is
+// reported in Errors and left empty; the rest of the snapshot is still
+// returned.
+func (c *Collector) Snapshot(ctx context.Context) *Snapshot {
+	snapshot := &Snapshot{
+		Timestamp: c.clock.Now(),
+		Disk:      make(map[string]DiskSnapshot),
+		Metrics:   make(map[MetricType][]SnapshotSample),
+	}
+
+	var mu sync.Mutex
+	fail := func(part string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if snapshot.Errors == nil {
+			snapshot.Errors = make(map[string]string)
+		}
+		snapshot.Errors[part] = err.Error()
+	}
+
+	parts := []func(){
+		func() {
+			usage, err := c.sampleCPU(ctx)
+			if err != nil {
+				fail("cpu", err)
+				return
+			}
+			snapshot.CPU.Usage = usage
+		},
+		func() {
+			perCore, err := cpu.PercentWithContext(ctx, c.cpuSampleInterval, true)
+			if err != nil {
+				fail("cpu_per_core", fmt.Errorf("failed to get per-core CPU usage: %w", err))
+				return
+			}
+			snapshot.CPU.PerCore = perCore
+		},
+		func() {
+			usedPercent, err := c.memoryPercent(ctx)
+			if err != nil {
+				fail("memory", err)
+				return
+			}
+			info, err := mem.VirtualMemoryWithContext(ctx)
+			if err != nil {
+				fail("memory", fmt.Errorf("failed to get memory usage: %w", err))
+				return
+			}
+			snapshot.Memory = MemorySnapshot{
+				UsedPercent: usedPercent,
+				Total:       info.Total,
+				Used:        info.Used,
+				Cached:      info.Cached,
+				Buffers:     info.Buffers,
+				Free:        info.Free,
+				Available:   info.Available,
+			}
+		},
+		func() {
+			info, err := mem.SwapMemoryWithContext(ctx)
+			if err != nil {
+				fail("swap", fmt.Errorf("failed to get swap usage: %w", err))
+				return
+			}
+			snapshot.Swap = SwapSnapshot{UsedPercent: info.UsedPercent, Total: info.Total, Used: info.Used, Free: info.Free}
+		},
+		func() {
+			for _, mount := range c.disk.mountList() {
+				usage, err := disk.UsageWithContext(ctx, mount)
+				if err != nil {
+					fail("disk:"+mount, fmt.Errorf("failed to get disk usage: %w", err))
+					continue
+				}
+				mu.Lock()
+				snapshot.Disk[mount] = DiskSnapshot{UsedPercent: usage.UsedPercent, Total: usage.Total, Used: usage.Used, Free: usage.Free}
+				mu.Unlock()
+			}
+		},
+		func() {
+			avg, err := load.AvgWithContext(ctx)
+			if err != nil {
+				fail("load_average", fmt.Errorf("failed to get load average: %w", err))
+				return
+			}
+			snapshot.LoadAverage = &LoadAverage{Load1: avg.Load1, Load5: avg.Load5, Load15: avg.Load15}
+		},
+		func() {
+			uptime, err := host.UptimeWithContext(ctx)
+			if err != nil {
+				fail("uptime", fmt.Errorf("failed to get uptime: %w", err))
+				return
+			}
+			snapshot.Uptime = uptime
+		},
+	}
+
+	var wg sync.WaitGroup
+	for _, part := range parts {
+		wg.Add(1)
+		go func(part func()) {
+			defer wg.Done()
+			part()
+		}(part)
+	}
+	wg.Wait()
+
+	if modes, err := c.GetCPUModes(); err == nil && modes != nil {
+		snapshot.CPU.Modes = modes.Modes
+	}
+
+	c.mu.RLock()
+	if c.lastMetrics != nil {
+		snapshot.Network.RxRate = c.lastMetrics.NetworkRx
+		snapshot.Network.TxRate = c.lastMetrics.NetworkTx
+	}
+	c.mu.RUnlock()
+	snapshot.Network.Interfaces = c.network.interfaceRates()
+
+	for _, info := range RegisteredTypes() {
+		for _, metric := range c.recent.latestCycle(info.Type, c.host) {
+			snapshot.Metrics[info.Type] = append(snapshot.Metrics[info.Type], SnapshotSample{
+				Value:     metric.Value,
+				Unit:      metric.Unit,
+				Mount:     metric.Mount,
+				Timestamp: metric.Timestamp,
+			})
+		}
+	}
+
+	return snapshot
+}
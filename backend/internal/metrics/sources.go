@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// DefaultSourceTimeout bounds how long one source may take within a collection cycle
+const DefaultSourceTimeout = 10 * time.Second
+
+// Sample is a single reading produced by a source
+type Sample struct {
+	Type  MetricType
+	Value float64
+	Unit  string
+	// Mount is set for per-mount samples such as DiskUsage
+	Mount string
+}
+
+// Source produces samples for one or more metric types. Sources run
+// concurrently each cycle and must honor ctx cancellation where they can.
+type Source interface {
+	Name() string
+	Collect(ctx context.Context) ([]Sample, error)
+}
+
+// sourceResult is the outcome of running one source in a cycle
+type sourceResult struct {
+	name     string
+	samples  []Sample
+	err      error
+	duration time.Duration
+}
+
+// RegisterSource adds a source to the collection cycle. Sources must be
+// registered before Start is called.
+func (c *Collector) RegisterSource(source Source) {
+	c.sources = append(c.sources, source)
+}
+
+// UnregisterSource removes the source with the given name, such as a
+// built-in one being replaced by a custom source, and reports whether there
+// was one. It must be called before Start.
+func (c *Collector) UnregisterSource(name string) bool {
+	for i, source := range c.sources {
+		if source.Name() == name {
+			c.sources = append(c.sources[:i], c.sources[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// SetSourceTimeout sets the per-source timeout within a collection cycle
+func (c *Collector) SetSourceTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultSourceTimeout
+	}
+	c.sourceTimeout = timeout
+}
+
+// runSources runs every registered source concurrently and returns their
+// results in registration order
+func (c *Collector) runSources(ctx context.Context) []sourceResult {
+	results := make([]sourceResult, len(c.sources))
+
+	var wg sync.WaitGroup
+	for i, source := range c.sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			results[i] = c.runSource(ctx, source)
+		}(i, source)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runSource runs one source with a timeout, converting panics into errors so
+// a misbehaving source cannot take down the collection loop. A source that
+// ignores its context is abandoned when the timeout expires.
+func (c *Collector) runSource(parent context.Context, source Source) sourceResult {
+	ctx, cancel := context.WithTimeout(parent, c.sourceTimeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan sourceResult, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- sourceResult{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		samples, err := source.Collect(ctx)
+		done <- sourceResult{samples: samples, err: err}
+	}()
+
+	var result sourceResult
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = sourceResult{err: fmt.Errorf("timed out after %v", c.sourceTimeout)}
+	}
+
+	result.name = source.Name()
+	result.duration = time.Since(start)
+	return result
+}
+
+// logSourceTimings logs how long each source took in a cycle
+func logSourceTimings(results []sourceResult) {
+	sorted := make([]sourceResult, len(results))
+	copy(sorted, results)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].duration > sorted[j].duration
+	})
+
+	timings := make([]string, len(sorted))
+	for i, result := range sorted {
+		timings[i] = fmt.Sprintf("%s=%v", result.name, result.duration.Round(time.Microsecond))
+	}
+	log.Printf("Collector timings: %s", strings.Join(timings, " "))
+}
+
+// cpuSource reports CPU usage since the previous cycle
+type cpuSource struct {
+	collector *Collector
+}
+
+func (s cpuSource) Name() string {
+	return "cpu"
+}
+
+func (s cpuSource) Collect(ctx context.Context) ([]Sample, error) {
+	usage, err := s.collector.collectCPU(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []Sample{{Type: CPUUsage, Value: usage, Unit: UnitPercent}}, nil
+}
+
+// memorySource reports used memory and the host memory breakdown
+type memorySource struct {
+	collector *Collector
+}
+
+func (s memorySource) Name() string {
+	return "memory"
+}
+
+func (s memorySource) Collect(ctx context.Context) ([]Sample, error) {
+	usage, err := s.collector.memoryPercent(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The breakdown is host-wide, even when usage is against a cgroup limit
+	info, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory breakdown: %w", err)
+	}
+	samples := []Sample{{Type: MemoryUsage, Value: usage, Unit: UnitPercent}}
+	return append(samples, memoryBreakdown(info)...), nil
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metricTypePattern is what configured metric type names may look like,
+// e.g. gpu_temperature
+var metricTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// TypeMetadata is configured metadata for a metric type. Empty fields keep
+// the built-in value; a nil Range keeps the built-in range.
+type TypeMetadata struct {
+	DisplayName string
+	Unit        string
+	Description string
+	Range       *ValueRange
+}
+
+// ParseTypeMetadata parses metadata specs keyed by metric type, such as
+// "display_name=GPU Temperature;unit=°C;range=0:120". The fields are
+// display_name, unit, description and range, given as min:max with either
+// end left empty for unbounded.
+func ParseTypeMetadata(specs map[string]string) (map[MetricType]TypeMetadata, error) {
+	metadata := make(map[MetricType]TypeMetadata, len(specs))
+	for name, spec := range specs {
+		if !metricTypePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid metric type %q (lowercase letters, digits and '_')", name)
+		}
+
+		var meta TypeMetadata
+		for _, field := range strings.Split(spec, ";") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid metadata %q for %s (expected key=value)", field, name)
+			}
+			value = strings.TrimSpace(value)
+
+			switch strings.TrimSpace(key) {
+			case "display_name":
+				meta.DisplayName = value
+			case "unit":
+				meta.Unit = value
+			case "description":
+				meta.Description = value
+			case "range":
+				valueRange, err := parseValueRange(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid range for %s: %w", name, err)
+				}
+				meta.Range = valueRange
+			default:
+				return nil, fmt.Errorf("unknown metadata field %q for %s (expected display_name, unit, description or range)", key, name)
+			}
+		}
+		metadata[MetricType(name)] = meta
+	}
+	return metadata, nil
+}
+
+// parseValueRange parses "min:max", where either end may be empty
+func parseValueRange(value string) (*ValueRange, error) {
+	minText, maxText, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, fmt.Errorf("%q is not min:max", value)
+	}
+
+	var valueRange ValueRange
+	for _, end := range []struct {
+		text  string
+		limit **float64
+	}{{minText, &valueRange.Min}, {maxText, &valueRange.Max}} {
+		text := strings.TrimSpace(end.text)
+		if text == "" {
+			continue
+		}
+		limit, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		*end.limit = bound(limit)
+	}
+	if valueRange.Min != nil && valueRange.Max != nil && *valueRange.Min > *valueRange.Max {
+		return nil, fmt.Errorf("min %g is above max %g", *valueRange.Min, *valueRange.Max)
+	}
+	return &valueRange, nil
+}
+
+// ApplyTypeMetadata merges configured metadata into the metric type
+// registry. Registered types take the display name, description and range
+// given; their unit cannot change, since stored values are in it. Other types
+// are registered, so they can be ingested. The registry is left unchanged if
+// any entry is invalid.
+func ApplyTypeMetadata(metadata map[MetricType]TypeMetadata) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	// Apply in a fixed order so the first error reported is stable
+	types := make([]MetricType, 0, len(metadata))
+	for metricType := range metadata {
+		types = append(types, metricType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	merged := make(map[MetricType]TypeInfo, len(metadata))
+	for _, metricType := range types {
+		meta := metadata[metricType]
+		info, registered := registry[metricType]
+		if registered && meta.Unit != "" && meta.Unit != info.Unit {
+			return fmt.Errorf("unit of metric type %s is %q and cannot be changed", metricType, info.Unit)
+		}
+		if !registered {
+			info = TypeInfo{
+				Type:        metricType,
+				DisplayName: string(metricType),
+				Unit:        meta.Unit,
+				Description: "Configured metric type",
+			}
+		}
+
+		if meta.DisplayName != "" {
+			info.DisplayName = meta.DisplayName
+		}
+		if meta.Description != "" {
+			info.Description = meta.Description
+		}
+		if meta.Range != nil {
+			info.Range = *meta.Range
+		}
+		merged[metricType] = info
+	}
+
+	for metricType, info := range merged {
+		registry[metricType] = info
+	}
+	return nil
+}
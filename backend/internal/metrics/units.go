@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Base units metric values are stored in
+const (
+	UnitPercent        = "%"
+	UnitBytes          = "B"
+	UnitBytesPerSecond = "B/s"
+	UnitSeconds        = "s"
+)
+
+// unitScale places a unit in a family of interchangeable units, with its size
+// relative to the family's base unit
+type unitScale struct {
+	base   string
+	factor float64
+}
+
+// unitTable lists every unit values can be converted to or from
+var unitTable = map[string]unitScale{
+	"%":     {UnitPercent, 1},
+	"ratio": {UnitPercent, 100},
+
+	"B":   {UnitBytes, 1},
+	"KB":  {UnitBytes, 1e3},
+	"MB":  {UnitBytes, 1e6},
+	"GB":  {UnitBytes, 1e9},
+	"TB":  {UnitBytes, 1e12},
+	"KiB": {UnitBytes, 1 << 10},
+	"MiB": {UnitBytes, 1 << 20},
+	"GiB": {UnitBytes, 1 << 30},
+	"TiB": {UnitBytes, 1 << 40},
+
+	"B/s":    {UnitBytesPerSecond, 1},
+	"KB/s":   {UnitBytesPerSecond, 1e3},
+	"MB/s":   {UnitBytesPerSecond, 1e6},
+	"GB/s":   {UnitBytesPerSecond, 1e9},
+	"KiB/s":  {UnitBytesPerSecond, 1 << 10},
+	"MiB/s":  {UnitBytesPerSecond, 1 << 20},
+	"GiB/s":  {UnitBytesPerSecond, 1 << 30},
+	"bit/s":  {UnitBytesPerSecond, 1.0 / 8},
+	"Mbit/s": {UnitBytesPerSecond, 1e6 / 8},
+	"Gbit/s": {UnitBytesPerSecond, 1e9 / 8},
+
+	"s":  {UnitSeconds, 1},
+	"ms": {UnitSeconds, 1e-3},
+	"us": {UnitSeconds, 1e-6},
+	"m":  {UnitSeconds, 60},
+	"h":  {UnitSeconds, 3600},
+}
+
+// UnitConverter converts values of one metric type from its base unit
+type UnitConverter struct {
+	Unit   string
+	factor float64
+}
+
+// NewUnitConverter returns a converter from metricType's base unit to unit.
+// It fails for unknown units, metric types without a registered base unit,
+// and units from a different family (e.g. MB for a percentage).
+func NewUnitConverter(metricType MetricType, unit string) (*UnitConverter, error) {
+	info, ok := LookupType(metricType)
+	if !ok {
+		return nil, fmt.Errorf("metric type %q has no known base unit", metricType)
+	}
+
+	target, ok := unitTable[unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", unit)
+	}
+
+	from, ok := unitTable[info.Unit]
+	if !ok || from.base != target.base {
+		return nil, fmt.Errorf("cannot convert %s from %s to %s", metricType, info.Unit, unit)
+	}
+
+	return &UnitConverter{Unit: unit, factor: from.factor / target.factor}, nil
+}
+
+// Convert converts a value in the base unit to the converter's unit
+func (u *UnitConverter) Convert(value float64) float64 {
+	return value * u.factor
+}
+
+// ConvertMetrics converts metric values in place and sets their unit
+func (u *UnitConverter) ConvertMetrics(metrics []Metric) {
+	for i := range metrics {
+		metrics[i].Value = u.Convert(metrics[i].Value)
+		metrics[i].Unit = u.Unit
+	}
+}
+
+// ConvertAggregated converts aggregated values in place and sets their unit
+func (u *UnitConverter) ConvertAggregated(points []AggregatedMetric) {
+	for i := range points {
+		points[i].Value = u.Convert(points[i].Value)
+		points[i].Unit = u.Unit
+	}
+}
+
+// byteSteps are the prefixes FormatValue humanizes byte values with
+var byteSteps = []string{"K", "M", "G", "T"}
+
+// FormatValue formats a value of metricType for people to read, in the
+// type's base unit: percentages with a % sign, bytes and byte rates scaled to
+// KB, MB, GB or TB, seconds with an s suffix and other configured units after
+// a space. Types without a unit, such as load averages, are formatted as bare
+// numbers.
+func FormatValue(metricType MetricType, value float64) string {
+	info, _ := LookupType(metricType)
+	switch info.Unit {
+	case UnitPercent:
+		return fmt.Sprintf("%.2f%%", value)
+	case UnitBytes, UnitBytesPerSecond:
+		return formatBytes(value, strings.TrimPrefix(info.Unit, UnitBytes))
+	case UnitSeconds:
+		return fmt.Sprintf("%.2fs", value)
+	case "":
+		return fmt.Sprintf("%.2f", value)
+	default:
+		return fmt.Sprintf("%.2f %s", value, info.Unit)
+	}
+}
+
+// formatBytes scales a byte value to the largest decimal prefix that keeps it
+// at or above 1, e.g. 1536000 as "1.54 MB", followed by suffix
+func formatBytes(value float64, suffix string) string {
+	if math.Abs(value) < 1e3 {
+		return fmt.Sprintf("%.0f B%s", value, suffix)
+	}
+
+	prefix := ""
+	for _, step := range byteSteps {
+		if math.Abs(value) < 1e3 {
+			break
+		}
+		value /= 1e3
+		prefix = step
+	}
+	return fmt.Sprintf("%.2f %sB%s", value, prefix, suffix)
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// OutOfRangePolicy decides what the collector does with a sample outside its
+// type's registered range
+type OutOfRangePolicy string
+
+const (
+	// OutOfRangeClamp stores the nearest valid value instead
+	OutOfRangeClamp OutOfRangePolicy = "clamp"
+	// OutOfRangeReject discards the sample
+	OutOfRangeReject OutOfRangePolicy = "reject"
+)
+
+// ParseOutOfRangePolicy parses "clamp" or "reject"; empty means clamp
+func ParseOutOfRangePolicy(value string) (OutOfRangePolicy, error) {
+	switch policy := OutOfRangePolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return OutOfRangeClamp, nil
+	case OutOfRangeClamp, OutOfRangeReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown out-of-range policy %q (expected clamp or reject)", value)
+	}
+}
+
+// SetOutOfRangePolicy sets how collected samples outside their type's range
+// are handled
+func (c *Collector) SetOutOfRangePolicy(policy OutOfRangePolicy) {
+	c.outOfRange = policy
+}
+
+// validateSample checks a collected sample against its type's range, logging
+// any bad value. It returns the value to store, clamped if the policy allows,
+// and false if the sample should be discarded. NaN can't be clamped and is
+// always discarded.
+func (c *Collector) validateSample(source string, sample Sample) (float64, bool) {
+	info, ok := LookupType(sample.Type)
+	if !ok {
+		return sample.Value, true
+	}
+	err := info.Range.Check(sample.Value)
+	if err == nil {
+		return sample.Value, true
+	}
+
+	if c.outOfRange == OutOfRangeReject || math.IsNaN(sample.Value) {
+		log.Printf("Collector %s returned a bad %s%s sample, discarded: %v", source, sample.Type, mountLabel(sample.Mount), err)
+		return 0, false
+	}
+
+	clamped := info.Range.Clamp(sample.Value)
+	log.Printf("Collector %s returned a bad %s%s sample, clamped to %g: %v", source, sample.Type, mountLabel(sample.Mount), clamped, err)
+	return clamped, true
+}
+
+// mountLabel formats a mount for log lines, or returns "" when there is none
+func mountLabel(mount string) string {
+	if mount == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", mount)
+}
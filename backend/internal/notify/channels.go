@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+)
+
+// ChannelsFromConfig builds the notification channels enabled in cfg
+func ChannelsFromConfig(cfg config.NotifyConfig, email *EmailNotifier) []Channel {
+	var channels []Channel
+
+	if cfg.SlackWebhookURL != "" {
+		channels = append(channels, NewSlackChannel(cfg.SlackWebhookURL))
+	}
+	if cfg.WebhookURL != "" {
+		channels = append(channels, NewWebhookChannel(cfg.WebhookURL))
+	}
+	if len(cfg.EmailTo) > 0 && email != nil && email.Enabled() {
+		channels = append(channels, NewEmailChannel(email, cfg.EmailTo))
+	}
+
+	return channels
+}
+
+// WebhookChannel posts events as JSON to a URL
+type WebhookChannel struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookChannel creates a channel posting events to url
+func NewWebhookChannel(url string) *WebhookChannel {
+	return &WebhookChannel{url: url, client: &http.Client{}}
+}
+
+// Name returns the channel name
+func (w *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+// Send posts the event as JSON
+func (w *WebhookChannel) Send(ctx context.Context, event Event) error {
+	return postJSON(ctx, w.client, w.url, event)
+}
+
+// SlackChannel posts events to a Slack incoming webhook
+type SlackChannel struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlackChannel creates a channel posting to a Slack incoming webhook URL
+func NewSlackChannel(url string) *SlackChannel {
+	return &SlackChannel{url: url, client: &http.Client{}}
+}
+
+// Name returns the channel name
+func (s *SlackChannel) Name() string {
+	return "slack"
+}
+
+// Send posts the event as a Slack message
+func (s *SlackChannel) Send(ctx context.Context, event Event) error {
+	text := fmt.Sprintf("*%s*\n%s", event.Subject(), event.Message)
+	if event.Kind == EventResolved {
+		text += fmt.Sprintf("\nResolved after %s", event.Duration())
+	}
+	payload := map[string]string{"text": text}
+	return postJSON(ctx, s.client, s.url, payload)
+}
+
+// EmailChannel emails events to a fixed list of recipients
+type EmailChannel struct {
+	notifier *EmailNotifier
+	to       []string
+}
+
+// NewEmailChannel creates a channel emailing events to the given recipients
+func NewEmailChannel(notifier *EmailNotifier, to []string) *EmailChannel {
+	return &EmailChannel{notifier: notifier, to: to}
+}
+
+// Name returns the channel name
+func (e *EmailChannel) Name() string {
+	return "email"
+}
+
+// Send emails the event. SMTP has no context support, so ctx is only
+// checked before sending.
+func (e *EmailChannel) Send(ctx context.Context, event Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	metricType := metrics.MetricType(event.MetricType)
+	body := fmt.Sprintf("%s\n\nMetric: %s\nSeverity: %s\nValue: %s\nThreshold: %s\nTriggered at: %s\n",
+		event.Message, event.MetricType, event.Severity,
+		metrics.FormatValue(metricType, event.Value), metrics.FormatValue(metricType, event.Threshold),
+		event.TriggeredAt.Format(time.RFC3339))
+	if event.Kind == EventResolved && event.ResolvedAt != nil {
+		body += fmt.Sprintf("Resolved at: %s\nActive for: %s\n", event.ResolvedAt.Format(time.RFC3339), event.Duration())
+	}
+
+	return e.notifier.SendEmail(e.to, event.Subject(), body)
+}
+
+// postJSON posts payload as JSON and treats non-2xx responses as errors
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint returned %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// sendTimeout bounds a single delivery to one channel
+const sendTimeout = 10 * time.Second
+
+// Event describes an alert notification. It mirrors the alert fields that
+// channels need so this package does not depend on the alerts package.
+type Event struct {
+	Kind        string    `json:"kind"`
+	AlertID     uint      `json:"alert_id"`
+	MetricType  string    `json:"metric_type"`
+	Mount       string    `json:"mount,omitempty"`
+	Severity    string    `json:"severity"`
+	Message     string    `json:"message"`
+	Value       float64   `json:"value"`
+	Threshold   float64   `json:"threshold"`
+	TriggeredAt time.Time `json:"triggered_at"`
+
+	// ResolvedAt and DurationSeconds, how long the alert was active, are set
+	// on resolved events
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds,omitempty"`
+}
+
+// Event kinds
+const (
+	EventTriggered = "triggered"
+	// EventResolved is sent when an alert is resolved, so integrations can
+	// close what they opened for the triggered event
+	EventResolved = "resolved"
+	// EventTest is a synthetic event sent to check a channel's configuration
+	EventTest = "test"
+)
+
+// Subject returns a one-line summary of the event
+func (e Event) Subject() string {
+	if e.Kind == EventTest {
+		return "[test] System Monitor test notification"
+	}
+	return fmt.Sprintf("[%s] %s alert %s", e.Severity, e.MetricType, e.Kind)
+}
+
+// Duration returns how long a resolved event's alert was active
+func (e Event) Duration() time.Duration {
+	return time.Duration(e.DurationSeconds) * time.Second
+}
+
+// Channel delivers events to one destination
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// Dispatcher fans events out to channels through a bounded worker pool and
+// tracks pending deliveries so they can be drained on shutdown. The
+// configured channels receive every event at or above the fallback
+// severity, and with subscribers set, events also go to the subscribed
+// users' channels. A routing table narrows which configured channels
+// receive each event.
+type Dispatcher struct {
+	channels []Channel
+	// quiet, if set, holds back low-severity events during quiet hours
+	quiet *QuietHours
+	// routing, if set, picks the configured channels per event
+	routing *RoutingTable
+
+	subscribers         Subscribers
+	email               *EmailNotifier
+	fallbackMinSeverity string
+
+	// Worker pool, started by the first Dispatch
+	workers   int
+	queueSize int
+	overflow  OverflowPolicy
+	queue     chan delivery
+	startOnce sync.Once
+
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	pending  int
+	draining bool
+
+	sent, failed, dropped uint64
+}
+
+// NewDispatcher creates a dispatcher sending to the given channels
+func NewDispatcher(channels ...Channel) *Dispatcher {
+	return &Dispatcher{channels: channels}
+}
+
+// Channels returns the names of the configured channels
+func (d *Dispatcher) Channels() []string {
+	names := make([]string, len(d.channels))
+	for i, channel := range d.channels {
+		names[i] = channel.Name()
+	}
+	return names
+}
+
+// SetQuietHours sets the quiet hours policy; nil notifies every severity at all times
+func (d *Dispatcher) SetQuietHours(quiet *QuietHours) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.quiet = quiet
+}
+
+// Dispatch queues event for the configured channels its route selects if it
+// meets the fallback severity, and for its subscribers' channels.
+// Subscriptions never take events away from the configured channels, so no
+// user can stop the operator's channels being paged.
+// Events dispatched after Drain has started, or held back by quiet hours, are
+// dropped. When the queue is full the delivery is dropped or Dispatch waits,
+// depending on the overflow policy.
+func (d *Dispatcher) Dispatch(event Event) {
+	d.startOnce.Do(d.startWorkers)
+
+	// Subscribers are looked up before locking so a slow query does not
+	// block deliveries finishing
+	d.mu.Lock()
+	subscribers, email := d.subscribers, d.email
+	d.mu.Unlock()
+	channels := d.recipientChannels(subscribers, email, event)
+
+	d.mu.Lock()
+	if d.draining {
+		d.mu.Unlock()
+		log.Printf("Notification dropped during shutdown: %s", event.Subject())
+		return
+	}
+
+	if d.quiet != nil && d.quiet.Holds(event, time.Now()) {
+		d.mu.Unlock()
+		log.Printf("Notification held during quiet hours: %s", event.Subject())
+		return
+	}
+
+	if MeetsSeverity(event.Severity, d.fallbackMinSeverity) {
+		// Copied so appending never writes into the configured channels
+		routed := d.routedChannels(event)
+		channels = append(append(make([]Channel, 0, len(routed)+len(channels)), routed...), channels...)
+	}
+	if len(channels) == 0 {
+		d.mu.Unlock()
+		log.Printf("Notification not sent, no channels or subscribers: %s", event.Subject())
+		return
+	}
+
+	d.pending += len(channels)
+	d.wg.Add(len(channels))
+	queue, overflow := d.queue, d.overflow
+	d.mu.Unlock()
+
+	// Enqueued without the lock, which workers need to finish deliveries
+	for _, channel := range channels {
+		d.enqueue(queue, overflow, delivery{channel: channel, event: event})
+	}
+}
+
+// send delivers event to one channel and marks it done
+func (d *Dispatcher) send(channel Channel, event Event) {
+	defer func() {
+		d.mu.Lock()
+		d.pending--
+		d.mu.Unlock()
+		d.wg.Done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	err := channel.Send(ctx, event)
+	if err != nil {
+		log.Printf("Failed to send %s notification: %v", channel.Name(), err)
+	}
+
+	d.mu.Lock()
+	if err != nil {
+		d.failed++
+	} else {
+		d.sent++
+	}
+	d.mu.Unlock()
+}
+
+// Drain stops accepting new events and waits for queued and in-flight deliveries until
+// ctx is done. It returns how many deliveries finished during the drain and
+// how many were still pending when ctx expired.
+func (d *Dispatcher) Drain(ctx context.Context) (drained, dropped int) {
+	d.mu.Lock()
+	d.draining = true
+	inFlight := d.pending
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return inFlight, 0
+	case <-ctx.Done():
+		d.mu.Lock()
+		remaining := d.pending
+		d.mu.Unlock()
+		return inFlight - remaining, remaining
+	}
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
+)
+
+// EmailNotifier sends plain-text email through an SMTP server
+type EmailNotifier struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// NewEmailNotifier creates an email notifier from SMTP configuration
+func NewEmailNotifier(cfg config.SMTPConfig) *EmailNotifier {
+	return &EmailNotifier{
+		host:     cfg.Host,
+		port:     cfg.Port,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     cfg.From,
+	}
+}
+
+// Enabled reports whether an SMTP server has been configured
+func (n *EmailNotifier) Enabled() bool {
+	return n.host != "" && n.from != ""
+}
+
+// SendEmail sends a plain-text message to the given recipients
+func (n *EmailNotifier) SendEmail(to []string, subject, body string) error {
+	if !n.Enabled() {
+		return fmt.Errorf("SMTP is not configured")
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no email recipients")
+	}
+
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + n.from,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	addr := fmt.Sprintf("%s:%d", n.host, n.port)
+	if err := smtp.SendMail(addr, auth, n.from, to, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
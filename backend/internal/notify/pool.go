@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Worker pool defaults
+const (
+	DefaultWorkers   = 4
+	DefaultQueueSize = 100
+)
+
+// OverflowPolicy decides what happens to a delivery when the queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowDrop logs and discards the delivery
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowBlock makes the caller wait for room in the queue
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// ParseOverflowPolicy parses "drop" or "block"; empty means drop
+func ParseOverflowPolicy(value string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return OverflowDrop, nil
+	case OverflowDrop, OverflowBlock:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q (expected drop or block)", value)
+	}
+}
+
+// QueueStats reports on the notification worker pool
+type QueueStats struct {
+	Workers   int            `json:"workers"`
+	QueueSize int            `json:"queue_size"`
+	Overflow  OverflowPolicy `json:"overflow"`
+	// QueueDepth is how many deliveries are waiting for a worker
+	QueueDepth int `json:"queue_depth"`
+	// Pending counts deliveries accepted but not finished, queued or sending
+	Pending int    `json:"pending"`
+	Sent    uint64 `json:"sent"`
+	Failed  uint64 `json:"failed"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// delivery is one event bound for one channel
+type delivery struct {
+	channel Channel
+	event   Event
+}
+
+// SetWorkerPool sets how many deliveries run at once, how many can wait in
+// the queue and what happens when it is full. Non-positive sizes use the
+// defaults. It has no effect once the first event has been dispatched.
+func (d *Dispatcher) SetWorkerPool(workers, queueSize int, overflow OverflowPolicy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.queue != nil {
+		log.Println("Notification worker pool already started; new settings ignored")
+		return
+	}
+	d.workers, d.queueSize, d.overflow = workers, queueSize, overflow
+}
+
+// poolSettings returns the pool settings with defaults applied; d.mu must be held
+func (d *Dispatcher) poolSettings() (workers, queueSize int, overflow OverflowPolicy) {
+	workers, queueSize, overflow = d.workers, d.queueSize, d.overflow
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	if overflow == "" {
+		overflow = OverflowDrop
+	}
+	return workers, queueSize, overflow
+}
+
+// startWorkers creates the queue and its workers. The workers run for the
+// life of the process.
+func (d *Dispatcher) startWorkers() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.workers, d.queueSize, d.overflow = d.poolSettings()
+	d.queue = make(chan delivery, d.queueSize)
+	for i := 0; i < d.workers; i++ {
+		go d.work(d.queue)
+	}
+}
+
+// work sends deliveries from queue one at a time
+func (d *Dispatcher) work(queue <-chan delivery) {
+	for item := range queue {
+		d.send(item.channel, item.event)
+	}
+}
+
+// enqueue hands item to the workers, dropping or waiting when the queue is
+// full. The delivery must already be counted as pending.
+func (d *Dispatcher) enqueue(queue chan<- delivery, overflow OverflowPolicy, item delivery) {
+	if overflow == OverflowBlock {
+		queue <- item
+		return
+	}
+
+	select {
+	case queue <- item:
+	default:
+		log.Printf("Notification queue full, %s notification dropped: %s", item.channel.Name(), item.event.Subject())
+		d.mu.Lock()
+		d.pending--
+		d.dropped++
+		d.mu.Unlock()
+		d.wg.Done()
+	}
+}
+
+// Stats reports the worker pool's configuration, queue depth and counters
+func (d *Dispatcher) Stats() QueueStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	workers, queueSize, overflow := d.poolSettings()
+	stats := QueueStats{
+		Workers:   workers,
+		QueueSize: queueSize,
+		Overflow:  overflow,
+		Pending:   d.pending,
+		Sent:      d.sent,
+		Failed:    d.failed,
+		Dropped:   d.dropped,
+	}
+	if d.queue != nil {
+		stats.QueueDepth = len(d.queue)
+	}
+	return stats
+}
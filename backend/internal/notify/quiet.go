@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultQuietMinSeverity is the lowest severity still notified during quiet hours
+const DefaultQuietMinSeverity = "critical"
+
+// quietWindow is a daily time range in minutes since midnight. A window whose
+// end is before its start wraps past midnight.
+type quietWindow struct {
+	start, end int
+}
+
+// contains reports whether minute of the day falls in the window
+func (w quietWindow) contains(minute int) bool {
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// QuietHours holds back notifications below a minimum severity during daily
+// quiet windows. Alerts are still recorded; only their notifications are skipped.
+type QuietHours struct {
+	windows     []quietWindow
+	location    *time.Location
+	minSeverity string
+}
+
+// ParseQuietHours parses comma-separated daily windows such as
+// "22:00-07:00,12:00-13:00" in timezone (an IANA name, default UTC). During a
+// window only alerts at or above minSeverity (default critical) are notified.
+// An empty spec disables quiet hours and returns nil.
+func ParseQuietHours(spec, timezone, minSeverity string) (*QuietHours, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours timezone %q: %w", timezone, err)
+	}
+
+	if minSeverity == "" {
+		minSeverity = DefaultQuietMinSeverity
+	}
+	minSeverity, err = ParseSeverity(minSeverity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours severity: %w", err)
+	}
+
+	quiet := &QuietHours{location: location, minSeverity: minSeverity}
+	for _, part := range strings.Split(spec, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid quiet hours window %q (expected HH:MM-HH:MM)", part)
+		}
+
+		start, err := parseClock(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours window %q: %w", part, err)
+		}
+		end, err := parseClock(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours window %q: %w", part, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("invalid quiet hours window %q: start and end are equal", part)
+		}
+
+		quiet.windows = append(quiet.windows, quietWindow{start: start, end: end})
+	}
+
+	return quiet, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Active reports whether at falls within a quiet window
+func (q *QuietHours) Active(at time.Time) bool {
+	local := at.In(q.location)
+	minute := local.Hour()*60 + local.Minute()
+	for _, window := range q.windows {
+		if window.contains(minute) {
+			return true
+		}
+	}
+	return false
+}
+
+// Holds reports whether event's notification should be held back at the given
+// time. Events with an unknown severity are always sent.
+func (q *QuietHours) Holds(event Event, at time.Time) bool {
+	if MeetsSeverity(event.Severity, q.minSeverity) {
+		return false
+	}
+	return q.Active(at)
+}
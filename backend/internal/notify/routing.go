@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Routing table keywords
+const (
+	// RouteAnySeverity matches every severity in a route selector
+	RouteAnySeverity = "*"
+	// RouteNone routes matching events to no configured channel
+	RouteNone = "none"
+)
+
+// Route sends events of a severity, and optionally of one metric type, to a
+// set of configured channels
+type Route struct {
+	Severity   string
+	MetricType string
+	Channels   []string
+}
+
+// specificity ranks routes so the most specific matching route wins: a
+// metric type counts for more than a severity
+func (r Route) specificity() int {
+	rank := 0
+	if r.MetricType != "" {
+		rank += 2
+	}
+	if r.Severity != RouteAnySeverity {
+		rank++
+	}
+	return rank
+}
+
+// matches reports whether the route applies to event
+func (r Route) matches(event Event) bool {
+	if r.Severity != RouteAnySeverity && !strings.EqualFold(r.Severity, event.Severity) {
+		return false
+	}
+	return r.MetricType == "" || r.MetricType == event.MetricType
+}
+
+// RoutingTable picks the configured channels an event is sent to by its
+// severity and metric type. Events no route matches go to every configured
+// channel.
+type RoutingTable struct {
+	routes []Route
+}
+
+// ParseRoutingTable parses comma-separated routes such as
+// "critical:webhook|slack,low:slack,high/disk_usage:email". Each route maps a
+// severity, or * for any, optionally followed by /metric_type, to
+// |-separated channel names or none. An empty spec disables routing and
+// returns nil.
+func ParseRoutingTable(spec string) (*RoutingTable, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	table := &RoutingTable{}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		selector, channels, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid route %q (expected severity[/metric_type]:channel|channel)", part)
+		}
+
+		var route Route
+		severity, metricType, _ := strings.Cut(strings.TrimSpace(selector), "/")
+		route.Severity = strings.ToLower(strings.TrimSpace(severity))
+		route.MetricType = strings.TrimSpace(metricType)
+		if route.Severity != RouteAnySeverity {
+			parsed, err := ParseSeverity(route.Severity)
+			if err != nil {
+				return nil, fmt.Errorf("invalid route %q: %w", part, err)
+			}
+			route.Severity = parsed
+		}
+		if strings.Contains(selector, "/") && route.MetricType == "" {
+			return nil, fmt.Errorf("invalid route %q: metric type is empty", part)
+		}
+
+		key := route.Severity + "/" + route.MetricType
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate route for %q", strings.TrimSpace(selector))
+		}
+		seen[key] = true
+
+		route.Channels = []string{}
+		if strings.TrimSpace(channels) != RouteNone {
+			for _, name := range strings.Split(channels, "|") {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name == "" {
+					return nil, fmt.Errorf("invalid route %q: empty channel name", part)
+				}
+				route.Channels = append(route.Channels, name)
+			}
+		}
+
+		table.routes = append(table.routes, route)
+	}
+
+	// Most specific first, so match can stop at the first hit
+	sort.SliceStable(table.routes, func(i, j int) bool {
+		return table.routes[i].specificity() > table.routes[j].specificity()
+	})
+	return table, nil
+}
+
+// match returns the most specific route for event
+func (t *RoutingTable) match(event Event) (Route, bool) {
+	for _, route := range t.routes {
+		if route.matches(event) {
+			return route, true
+		}
+	}
+	return Route{}, false
+}
+
+// SetRouting routes events that reach the configured channels by severity
+// and metric type; nil sends them to every configured channel. Every channel
+// the table names must be configured.
+func (d *Dispatcher) SetRouting(table *RoutingTable) error {
+	if table != nil {
+		configured := make(map[string]bool, len(d.channels))
+		for _, channel := range d.channels {
+			configured[channel.Name()] = true
+		}
+		for _, route := range table.routes {
+			for _, name := range route.Channels {
+				if !configured[name] {
+					return fmt.Errorf("%w: %q (configured: %s)", ErrUnknownChannel, name, strings.Join(d.Channels(), ", "))
+				}
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.routing = table
+	return nil
+}
+
+// routedChannels returns the configured channels event should go to.
+// Callers must hold d.mu.
+func (d *Dispatcher) routedChannels(event Event) []Channel {
+	if d.routing == nil {
+		return d.channels
+	}
+	route, ok := d.routing.match(event)
+	if !ok {
+		return d.channels
+	}
+
+	var channels []Channel
+	for _, channel := range d.channels {
+		for _, name := range route.Channels {
+			if channel.Name() == name {
+				channels = append(channels, channel)
+				break
+			}
+		}
+	}
+	return channels
+}
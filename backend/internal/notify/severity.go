@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// severityRank orders alert severities from least to most severe
+var severityRank = map[string]int{
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// ParseSeverity normalises an alert severity name, rejecting unknown names
+func ParseSeverity(severity string) (string, error) {
+	severity = strings.ToLower(strings.TrimSpace(severity))
+	if _, ok := severityRank[severity]; !ok {
+		return "", fmt.Errorf("invalid severity %q (expected low, medium, high or critical)", severity)
+	}
+	return severity, nil
+}
+
+// MeetsSeverity reports whether severity is at or above minSeverity. An empty
+// minSeverity accepts everything; an unknown severity meets any minimum so
+// events are never silently lost.
+func MeetsSeverity(severity, minSeverity string) bool {
+	if minSeverity == "" {
+		return true
+	}
+	rank, ok := severityRank[strings.ToLower(severity)]
+	return !ok || rank >= severityRank[strings.ToLower(minSeverity)]
+}
@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// Channel kinds a subscription can deliver to
+const (
+	ChannelEmail   = "email"
+	ChannelSlack   = "slack"
+	ChannelWebhook = "webhook"
+)
+
+// Recipient is one subscriber's delivery target: an email address for email,
+// or a webhook URL for slack and webhook
+type Recipient struct {
+	Channel string
+	Target  string
+}
+
+// Subscribers finds the recipients subscribed to an event
+type Subscribers interface {
+	Recipients(event Event) ([]Recipient, error)
+}
+
+// ValidateRecipient checks that channel is a known kind and target suits it.
+// Slack and webhook targets must be public: any user can subscribe, so a
+// target on loopback, link-local or private addresses would let them make
+// the server post to internal services.
+func ValidateRecipient(channel, target string) error {
+	switch channel {
+	case ChannelEmail:
+		if _, err := mail.ParseAddress(target); err != nil {
+			return fmt.Errorf("invalid email address %q", target)
+		}
+	case ChannelSlack, ChannelWebhook:
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid %s URL %q (expected http or https)", channel, target)
+		}
+		if err := checkPublicHost(parsed.Hostname()); err != nil {
+			return fmt.Errorf("invalid %s URL %q: %w", channel, target, err)
+		}
+	default:
+		return fmt.Errorf("unsupported channel %q (expected %s, %s or %s)", channel, ChannelEmail, ChannelSlack, ChannelWebhook)
+	}
+	return nil
+}
+
+// checkPublicHost rejects hosts naming this machine or a private network.
+// Host names that resolve to such addresses are refused when connecting.
+func checkPublicHost(host string) error {
+	lower := strings.ToLower(host)
+	if lower == "localhost" || strings.HasSuffix(lower, ".localhost") {
+		return fmt.Errorf("host %s is not public", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return fmt.Errorf("address %s is not public", host)
+	}
+	return nil
+}
+
+// publicIP reports whether ip is outside the loopback, private, link-local,
+// unspecified and multicast ranges
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// subscriberClient posts to subscription targets. It checks every address
+// it connects to, including after redirects, so a public host name that
+// resolves to a private address is refused too. Environment proxies are not
+// used, as the proxy would make the connection instead.
+var subscriberClient = &http.Client{Transport: &http.Transport{
+	DialContext: (&net.Dialer{Timeout: sendTimeout, Control: dialPublicOnly}).DialContext,
+}}
+
+// dialPublicOnly refuses connections to addresses that are not public
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("refusing to connect to %s: address is not public", host)
+	}
+	return nil
+}
+
+// SetSubscribers routes events to subscribed users' channels. Email
+// subscriptions are sent through email and skipped when SMTP is not configured.
+func (d *Dispatcher) SetSubscribers(subscribers Subscribers, email *EmailNotifier) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.subscribers = subscribers
+	d.email = email
+}
+
+// SetFallbackMinSeverity limits the configured channels to events at or above
+// minSeverity, whether or not a subscription matched. An empty value sends
+// every event to them.
+func (d *Dispatcher) SetFallbackMinSeverity(minSeverity string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.fallbackMinSeverity = minSeverity
+}
+
+// recipientChannels resolves the subscribers' channels for event, or nil when
+// no subscription matched. Duplicate recipients receive the event once.
+func (d *Dispatcher) recipientChannels(subscribers Subscribers, email *EmailNotifier, event Event) []Channel {
+	if subscribers == nil {
+		return nil
+	}
+
+	recipients, err := subscribers.Recipients(event)
+	if err != nil {
+		log.Printf("Failed to find notification subscribers: %v", err)
+		return nil
+	}
+
+	seen := make(map[Recipient]bool, len(recipients))
+	var channels []Channel
+	for _, recipient := range recipients {
+		if seen[recipient] {
+			continue
+		}
+		seen[recipient] = true
+
+		// Subscriptions stored before targets were checked are skipped
+		if err := ValidateRecipient(recipient.Channel, recipient.Target); err != nil {
+			log.Printf("Skipping subscription: %v", err)
+			continue
+		}
+
+		switch recipient.Channel {
+		case ChannelEmail:
+			if email == nil || !email.Enabled() {
+				log.Printf("Skipping email subscription for %s: SMTP is not configured", recipient.Target)
+				continue
+			}
+			channels = append(channels, NewEmailChannel(email, []string{recipient.Target}))
+		case ChannelSlack:
+			channels = append(channels, &SlackChannel{url: recipient.Target, client: subscriberClient})
+		case ChannelWebhook:
+			channels = append(channels, &WebhookChannel{url: recipient.Target, client: subscriberClient})
+		}
+	}
+	return channels
+}
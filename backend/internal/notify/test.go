@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AllChannels selects every configured channel in SendTest
+const AllChannels = "all"
+
+var (
+	ErrNoChannels     = errors.New("no notification channels are configured")
+	ErrUnknownChannel = errors.New("notification channel is not configured")
+)
+
+// TestResult is the outcome of sending a test notification to one channel
+type TestResult struct {
+	Channel string `json:"channel"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewTestEvent returns a synthetic event for checking channel configuration
+func NewTestEvent(now time.Time) Event {
+	return Event{
+		Kind:        EventTest,
+		MetricType:  EventTest,
+		Severity:    "low",
+		Message:     "This is a test notification from System Monitor. No action is needed.",
+		TriggeredAt: now,
+	}
+}
+
+// SendTest sends a test event to the configured channel called name, or to
+// every configured channel for AllChannels, and reports each outcome. It
+// sends directly and waits, bypassing the queue, quiet hours and
+// subscriptions, so the result reflects the channel's configuration alone.
+func (d *Dispatcher) SendTest(ctx context.Context, name string) ([]TestResult, error) {
+	if len(d.channels) == 0 {
+		return nil, ErrNoChannels
+	}
+
+	var channels []Channel
+	for _, channel := range d.channels {
+		if name == AllChannels || channel.Name() == name {
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("%w: %q (configured: %s)", ErrUnknownChannel, name, strings.Join(d.Channels(), ", "))
+	}
+
+	event := NewTestEvent(time.Now())
+	results := make([]TestResult, len(channels))
+	var wg sync.WaitGroup
+	for i, channel := range channels {
+		wg.Add(1)
+		go func(i int, channel Channel) {
+			defer wg.Done()
+
+			sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+			defer cancel()
+
+			results[i] = TestResult{Channel: channel.Name(), Success: true}
+			if err := channel.Send(sendCtx, event); err != nil {
+				results[i].Success = false
+				results[i].Error = err.Error()
+			}
+		}(i, channel)
+	}
+	wg.Wait()
+
+	return results, nil
+}
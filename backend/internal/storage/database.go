@@ -3,24 +3,40 @@ package storage
 import (
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/alerts"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/audit"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/auth"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/idempotency"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
 )
 
 // Database holds the database connection
 type Database struct {
 	DB *gorm.DB
+
+	// sqlite is true when connected to SQLite rather than PostgreSQL
+	sqlite bool
+
+	// migrateMu keeps migration runs from overlapping
+	migrateMu sync.Mutex
 }
 
-// NewDatabase creates a new database connection
+// NewDatabase creates a new database connection. DATABASE_URL may be a
+// PostgreSQL URL, ":memory:", or a SQLite file given as sqlite:///path/to/db,
+// file:path.db or a path ending in .db, .sqlite or .sqlite3. Relative SQLite
+// paths are placed in DATA_DIR when it is set.
 func NewDatabase(cfg *config.Config) (*Database, error) {
 	dsn := cfg.GetDatabaseDSN()
 
@@ -31,75 +47,208 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 	var db *gorm.DB
 	var err error
 
-	// Check if it's an in-memory SQLite database (for testing)
-	if dsn == ":memory:" {
-		db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
-		})
+	if path, ok := sqlitePath(dsn); ok {
+		path = resolveSQLitePath(path, cfg.Database.DataDir)
+		if !isInMemorySQLite(path) && !strings.HasPrefix(path, "file:") {
+			if dir := filepath.Dir(path); dir != "." {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					return nil, fmt.Errorf("failed to create SQLite directory: %w", err)
+				}
+			}
+		}
+
+		sqliteDSN, err := sqliteDSN(path, cfg.Database.SQLiteJournalMode, cfg.Database.SQLiteBusyTimeout)
 		if err != nil {
-			return nil, fmt.Errorf("failed to connect to SQLite database: %w", err)
+			return nil, err
 		}
-		log.Println("Successfully connected to in-memory SQLite database")
-	} else {
-		// Use PostgreSQL driver for DATABASE_URL
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+
+		db, err = gorm.Open(sqlite.Open(sqliteDSN), &gorm.Config{
 			Logger: logger.Default.LogMode(logger.Info),
 		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to connect to PostgreSQL database: %w", err)
+			return nil, fmt.Errorf("failed to connect to SQLite database: %w", err)
+		}
+
+		var journalMode string
+		if !isInMemorySQLite(path) {
+			if err := db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error; err != nil {
+				return nil, fmt.Errorf("failed to read SQLite journal mode: %w", err)
+			}
 		}
 
-		// Test the connection
+		// SQLite allows a single writer, and each connection to :memory:
+		// would otherwise get its own empty database. Under WAL, readers
+		// get their own connections so they do not wait on writes.
 		sqlDB, err := db.DB()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get database instance: %w", err)
 		}
+		if strings.EqualFold(journalMode, "wal") {
+			sqlDB.SetMaxOpenConns(sqliteWALMaxConns)
+		} else {
+			sqlDB.SetMaxOpenConns(1)
+		}
 
-		if err := sqlDB.Ping(); err != nil {
-			return nil, fmt.Errorf("failed to ping database: %w", err)
+		if journalMode != "" {
+			log.Printf("Successfully connected to SQLite database: %s (journal mode: %s)", path, journalMode)
+		} else {
+			log.Printf("Successfully connected to SQLite database: %s", path)
 		}
+		return &Database{DB: db, sqlite: true}, nil
+	}
+
+	// Use PostgreSQL driver for DATABASE_URL, connecting with the parsed
+	// config so options like sslmode and connect_timeout are applied
+	pgConfig, err := ParsePostgresDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err = gorm.Open(postgres.New(postgres.Config{
+		DSN:  dsn,
+		Conn: stdlib.OpenDB(*pgConfig),
+	}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL database: %w", err)
+	}
 
-		log.Println("Successfully connected to PostgreSQL database")
+	// Test the connection
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
 
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	log.Println("Successfully connected to PostgreSQL database")
+
 	return &Database{DB: db}, nil
 }
 
+// sqlitePath returns the SQLite path for dsn, or false if dsn is not a SQLite DSN
+func sqlitePath(dsn string) (string, bool) {
+	switch {
+	case dsn == ":memory:":
+		return dsn, true
+	case strings.HasPrefix(dsn, "sqlite://"):
+		return strings.TrimPrefix(dsn, "sqlite://"), true
+	case strings.HasPrefix(dsn, "file:"):
+		return dsn, true
+	}
+
+	ext := strings.ToLower(filepath.Ext(dsn))
+	if ext == ".db" || ext == ".sqlite" || ext == ".sqlite3" {
+		return dsn, true
+	}
+
+	return "", false
+}
+
+// IsSQLite reports whether the database is SQLite
+func (d *Database) IsSQLite() bool {
+	return d.sqlite
+}
+
+// MigrationReport describes what a migration run changed
+type MigrationReport struct {
+	// Rows whose missing metric_type was filled in, per table
+	MetricTypesFixed map[string]int64 `json:"metric_types_fixed"`
+	// EmailsNormalized counts user emails lowercased
+	EmailsNormalized int64 `json:"emails_normalized"`
+	// DroppedColumns lists obsolete columns removed, as table.column
+	DroppedColumns []string `json:"dropped_columns"`
+	// Warnings lists fixups that failed without failing the migration
+	Warnings []string `json:"warnings"`
+}
+
+// warn logs a fixup failure and records it in the report
+func (r *MigrationReport) warn(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Printf("Warning: %s", message)
+	r.Warnings = append(r.Warnings, message)
+}
+
 // AutoMigrate runs database migrations
 func (d *Database) AutoMigrate() error {
+	_, err := d.Migrate()
+	return err
+}
+
+// Migrate runs database migrations and the data fixups that follow them,
+// reporting what changed. It is safe to run again on a live database.
+func (d *Database) Migrate() (*MigrationReport, error) {
+	d.migrateMu.Lock()
+	defer d.migrateMu.Unlock()
+
 	log.Println("Running database migrations...")
+	report := &MigrationReport{
+		MetricTypesFixed: make(map[string]int64),
+		DroppedColumns:   []string{},
+		Warnings:         []string{},
+	}
+
+	// Rollup buckets gained key columns; AutoMigrate adds the columns but
+	// leaves an existing unique index as it was
+	d.dropStaleRollupIndex(report, "mount", "host")
 
 	// First, run the basic migrations
 	err := d.DB.AutoMigrate(
 		&auth.User{},
 		&auth.Session{},
+		&auth.APIKey{},
 		&metrics.Metric{},
 		&metrics.MetricThreshold{},
+		&metrics.MetricRollup{},
+		&metrics.Annotation{},
+		&metrics.CounterState{},
 		&alerts.Alert{},
+		&alerts.Incident{},
+		&alerts.MaintenanceWindow{},
+		&alerts.AlertSubscription{},
+		&audit.AuditLog{},
+		&idempotency.Record{},
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	// Fix any existing NULL values in metric_type columns
-	if err := d.fixMetricTypeColumns(); err != nil {
-		log.Printf("Warning: Failed to fix metric_type columns: %v", err)
+	// Enforce case-insensitive uniqueness for usernames and emails
+	if err := d.ensureUserIndexes(report); err != nil {
+		report.warn("Failed to create case-insensitive user indexes: %v", err)
+	}
+
+	// Index metric history in the order it is paged through
+	if err := d.DB.Exec(`CREATE INDEX IF NOT EXISTS idx_metrics_type_timestamp_id ON metrics (metric_type, timestamp, id)`).Error; err != nil {
+		report.warn("Failed to create metric history index: %v", err)
 	}
 
-	// Clear any cached query plans by closing and reopening the connection
-	if err := d.refreshConnection(); err != nil {
-		log.Printf("Warning: Failed to refresh database connection: %v", err)
+	// Fix any existing NULL values in metric_type columns
+	d.fixMetricTypeColumns(report)
+
+	// Clear any cached query plans by closing and reopening the connection.
+	// SQLite has no plan cache and must keep its single connection.
+	if !d.sqlite {
+		if err := d.refreshConnection(); err != nil {
+			report.warn("Failed to refresh database connection: %v", err)
+		}
 	}
 
 	log.Println("Database migrations completed successfully")
-	return nil
+	return report, nil
 }
 
-// fixMetricTypeColumns updates any NULL values in metric_type columns and drops old type columns
-func (d *Database) fixMetricTypeColumns() error {
-	// Fix metric_thresholds table
-	result := d.DB.Exec(`
+// metricTypeFixups fill in a missing metric_type, per table. Values are
+// guessed from the rest of the row, falling back to cpu_usage.
+var metricTypeFixups = []struct {
+	table string
+	sql   string
+}{
+	{"metric_thresholds", `
 		UPDATE metric_thresholds 
 		SET metric_type = CASE 
 			WHEN threshold = 80.0 THEN 'cpu_usage'
@@ -107,23 +256,13 @@ func (d *Database) fixMetricTypeColumns() error {
 			ELSE 'cpu_usage'
 		END 
 		WHERE metric_type IS NULL OR metric_type = ''
-	`)
-	if result.Error != nil {
-		log.Printf("Failed to fix metric_thresholds: %v", result.Error)
-	}
-
-	// Fix metrics table - set a default type for any NULL values
-	result = d.DB.Exec(`
+	`},
+	{"metrics", `
 		UPDATE metrics 
 		SET metric_type = 'cpu_usage' 
 		WHERE metric_type IS NULL OR metric_type = ''
-	`)
-	if result.Error != nil {
-		log.Printf("Failed to fix metrics: %v", result.Error)
-	}
-
-	// Fix alerts table
-	result = d.DB.Exec(`
+	`},
+	{"alerts", `
 		UPDATE alerts 
 		SET metric_type = CASE 
 			WHEN message LIKE '%CPU%' OR message LIKE '%cpu%' THEN 'cpu_usage'
@@ -131,32 +270,88 @@ func (d *Database) fixMetricTypeColumns() error {
 			ELSE 'cpu_usage'
 		END 
 		WHERE metric_type IS NULL OR metric_type = ''
-	`)
+	`},
+}
+
+// fixMetricTypeColumns updates any NULL values in metric_type columns and drops old type columns
+func (d *Database) fixMetricTypeColumns(report *MigrationReport) {
+	for _, fixup := range metricTypeFixups {
+		result := d.DB.Exec(fixup.sql)
+		if result.Error != nil {
+			report.warn("Failed to fix %s: %v", fixup.table, result.Error)
+		} else if result.RowsAffected > 0 {
+			report.MetricTypesFixed[fixup.table] = result.RowsAffected
+		}
+	}
+
+	// Drop old type columns if they exist. Only PostgreSQL deployments
+	// predate metric_type, and the check relies on information_schema.
+	if !d.sqlite {
+		d.dropOldTypeColumns(report)
+	}
+}
+
+// ensureUserIndexes normalizes stored emails and adds unique indexes on the
+// lowercased username and email. Existing case-variant duplicates must be
+// resolved manually before the indexes can be created.
+func (d *Database) ensureUserIndexes(report *MigrationReport) error {
+	result := d.DB.Exec(`UPDATE users SET email = LOWER(email) WHERE email <> LOWER(email)`)
 	if result.Error != nil {
-		log.Printf("Failed to fix alerts: %v", result.Error)
+		return fmt.Errorf("failed to normalize user emails: %w", result.Error)
 	}
+	report.EmailsNormalized = result.RowsAffected
 
-	// Drop old type columns if they exist
-	d.dropOldTypeColumns()
+	if err := d.DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username))`).Error; err != nil {
+		return fmt.Errorf("failed to create username index: %w", err)
+	}
+
+	if err := d.DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))`).Error; err != nil {
+		return fmt.Errorf("failed to create email index: %w", err)
+	}
 
 	return nil
 }
 
+// dropStaleRollupIndex drops the rollup bucket index when the rollup table
+// predates any of the given key columns, so AutoMigrate recreates it over
+// the full key
+func (d *Database) dropStaleRollupIndex(report *MigrationReport, columns ...string) {
+	migrator := d.DB.Migrator()
+	if !migrator.HasTable(&metrics.MetricRollup{}) {
+		return
+	}
+
+	for _, column := range columns {
+		if migrator.HasColumn(&metrics.MetricRollup{}, column) {
+			continue
+		}
+		if !migrator.HasIndex(&metrics.MetricRollup{}, "idx_rollup_bucket") {
+			return
+		}
+		if err := migrator.DropIndex(&metrics.MetricRollup{}, "idx_rollup_bucket"); err != nil {
+			report.warn("Failed to drop rollup bucket index: %v", err)
+			return
+		}
+		log.Printf("Dropped rollup bucket index to rebuild it with the %s column", column)
+		return
+	}
+}
+
 // dropOldTypeColumns removes the old type columns that conflict with metric_type
-func (d *Database) dropOldTypeColumns() {
+func (d *Database) dropOldTypeColumns(report *MigrationReport) {
 	// Drop problematic columns from metrics table
 	metricsColumns := []string{"type", "cpu_usage", "memory_usage"}
 	for _, column := range metricsColumns {
-		d.dropColumnIfExists("metrics", column)
+		d.dropColumnIfExists(report, "metrics", column)
 	}
 
 	// Drop type columns from other tables
-	d.dropColumnIfExists("alerts", "type")
-	d.dropColumnIfExists("metric_thresholds", "type")
+	d.dropColumnIfExists(report, "alerts", "type")
+	d.dropColumnIfExists(report, "metric_thresholds", "type")
 }
 
 // dropColumnIfExists drops a column if it exists
-func (d *Database) dropColumnIfExists(table, column string) {
+func (d *Database) dropColumnIfExists(report *MigrationReport, table, column string) {
 	var count int64
 	result := d.DB.Raw(`
 		SELECT COUNT(*) 
@@ -165,7 +360,7 @@ func (d *Database) dropColumnIfExists(table, column string) {
 	`, table, column).Scan(&count)
 
 	if result.Error != nil {
-		log.Printf("Failed to check for %s column in %s: %v", column, table, result.Error)
+		report.warn("Failed to check for %s column in %s: %v", column, table, result.Error)
 		return
 	}
 
@@ -173,9 +368,10 @@ func (d *Database) dropColumnIfExists(table, column string) {
 		dropSQL := fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s", table, column)
 		result = d.DB.Exec(dropSQL)
 		if result.Error != nil {
-			log.Printf("Failed to drop %s column from %s: %v", column, table, result.Error)
+			report.warn("Failed to drop %s column from %s: %v", column, table, result.Error)
 		} else {
 			log.Printf("Dropped old %s column from %s table", column, table)
+			report.DroppedColumns = append(report.DroppedColumns, table+"."+column)
 		}
 	}
 }
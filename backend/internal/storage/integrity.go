@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/alerts"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+)
+
+// maxIssueExamples bounds the offending values listed per issue
+const maxIssueExamples = 5
+
+// IntegrityIssue is one kind of invalid row found in one table
+type IntegrityIssue struct {
+	Table   string `json:"table"`
+	Problem string `json:"problem"`
+	Count   int64  `json:"count"`
+	// Fixed counts rows repaired; problems that can't be fixed safely are
+	// only reported
+	Fixed int64 `json:"fixed"`
+	// Examples lists some of the offending values
+	Examples []string `json:"examples,omitempty"`
+}
+
+// IntegrityReport describes the invalid rows an integrity check found
+type IntegrityReport struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// Fix is whether fixable problems were repaired
+	Fix    bool             `json:"fix"`
+	Issues []IntegrityIssue `json:"issues"`
+	// Warnings lists checks that could not run
+	Warnings []string `json:"warnings"`
+}
+
+// warn logs a failed check and records it in the report
+func (r *IntegrityReport) warn(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Printf("Warning: %s", message)
+	r.Warnings = append(r.Warnings, message)
+}
+
+// add records an issue found count times
+func (r *IntegrityReport) add(issue IntegrityIssue) {
+	if issue.Count > 0 {
+		r.Issues = append(r.Issues, issue)
+	}
+}
+
+// VerifyDataIntegrity looks for rows the dashboard can't make sense of:
+// missing or unknown metric types, thresholds outside their type's range or
+// with an unknown direction, alerts pointing at deleted incidents and
+// subscriptions of deleted users. With fix, missing metric types are filled
+// in as the startup migration does, dangling incident references are cleared
+// and orphaned subscriptions are deleted. Unknown types and bad thresholds
+// are only reported.
+func (d *Database) VerifyDataIntegrity(fix bool) (*IntegrityReport, error) {
+	d.migrateMu.Lock()
+	defer d.migrateMu.Unlock()
+
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying database: %w", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("database unavailable: %w", err)
+	}
+
+	report := &IntegrityReport{
+		CheckedAt: time.Now(),
+		Fix:       fix,
+		Issues:    []IntegrityIssue{},
+		Warnings:  []string{},
+	}
+
+	d.checkMissingMetricTypes(report, fix)
+
+	var registered []string
+	for _, info := range metrics.RegisteredTypes() {
+		registered = append(registered, string(info.Type))
+	}
+	alertTypes := append([]string{string(alerts.StalenessAlertType), string(alerts.FlappingAlertType)}, registered...)
+	d.checkUnknownMetricTypes(report, "metrics", registered)
+	d.checkUnknownMetricTypes(report, "metric_thresholds", registered)
+	d.checkUnknownMetricTypes(report, "alerts", alertTypes)
+	d.checkUnknownMetricTypes(report, "alert_subscriptions", registered)
+
+	d.checkThresholds(report)
+	d.checkOrphans(report, fix, "alerts", "incident references a deleted incident",
+		"incident_id IS NOT NULL AND incident_id NOT IN (SELECT id FROM incidents)",
+		"UPDATE alerts SET incident_id = NULL WHERE incident_id IS NOT NULL AND incident_id NOT IN (SELECT id FROM incidents)")
+	d.checkOrphans(report, fix, "alert_subscriptions", "subscription belongs to a deleted user",
+		"user_id NOT IN (SELECT id FROM users)",
+		"DELETE FROM alert_subscriptions WHERE user_id NOT IN (SELECT id FROM users)")
+
+	return report, nil
+}
+
+// checkMissingMetricTypes counts rows with no metric_type and, with fix,
+// fills them in
+func (d *Database) checkMissingMetricTypes(report *IntegrityReport, fix bool) {
+	for _, fixup := range metricTypeFixups {
+		issue := IntegrityIssue{Table: fixup.table, Problem: "metric_type is missing"}
+		if err := d.DB.Table(fixup.table).
+			Where("metric_type IS NULL OR metric_type = ''").
+			Count(&issue.Count).Error; err != nil {
+			report.warn("Failed to check %s for missing metric types: %v", fixup.table, err)
+			continue
+		}
+
+		if fix && issue.Count > 0 {
+			result := d.DB.Exec(fixup.sql)
+			if result.Error != nil {
+				report.warn("Failed to fix %s: %v", fixup.table, result.Error)
+			} else {
+				issue.Fixed = result.RowsAffected
+			}
+		}
+		report.add(issue)
+	}
+}
+
+// checkUnknownMetricTypes counts rows whose metric_type is set but not one of valid
+func (d *Database) checkUnknownMetricTypes(report *IntegrityReport, table string, valid []string) {
+	var rows []struct {
+		MetricType string
+		Count      int64
+	}
+	if err := d.DB.Table(table).
+		Select("metric_type, COUNT(*) AS count").
+		Where("metric_type <> '' AND metric_type NOT IN ?", valid).
+		Group("metric_type").
+		Order("metric_type").
+		Scan(&rows).Error; err != nil {
+		report.warn("Failed to check %s for unknown metric types: %v", table, err)
+		return
+	}
+
+	issue := IntegrityIssue{Table: table, Problem: "metric_type is not a known type"}
+	for _, row := range rows {
+		issue.Count += row.Count
+		if len(issue.Examples) < maxIssueExamples {
+			issue.Examples = append(issue.Examples, row.MetricType)
+		}
+	}
+	report.add(issue)
+}
+
+// checkThresholds counts thresholds outside their metric type's valid range
+// or with an unknown direction
+func (d *Database) checkThresholds(report *IntegrityReport) {
+	var thresholds []metrics.MetricThreshold
+	if err := d.DB.Find(&thresholds).Error; err != nil {
+		report.warn("Failed to check metric_thresholds: %v", err)
+		return
+	}
+
+	outOfRange := IntegrityIssue{Table: "metric_thresholds", Problem: "threshold is outside the metric type's valid range"}
+	badDirection := IntegrityIssue{Table: "metric_thresholds", Problem: "direction is not above or below"}
+	for _, threshold := range thresholds {
+		if info, ok := metrics.LookupType(threshold.Type); ok && info.Range.Check(threshold.Threshold) != nil {
+			outOfRange.Count++
+			if len(outOfRange.Examples) < maxIssueExamples {
+				outOfRange.Examples = append(outOfRange.Examples, fmt.Sprintf("%s=%g", threshold.Type, threshold.Threshold))
+			}
+		}
+		if threshold.Direction != metrics.DirectionAbove && threshold.Direction != metrics.DirectionBelow {
+			badDirection.Count++
+			if len(badDirection.Examples) < maxIssueExamples {
+				badDirection.Examples = append(badDirection.Examples, fmt.Sprintf("%s=%s", threshold.Type, threshold.Direction))
+			}
+		}
+	}
+	report.add(outOfRange)
+	report.add(badDirection)
+}
+
+// checkOrphans counts rows of table matching where and, with fix, runs fixSQL
+func (d *Database) checkOrphans(report *IntegrityReport, fix bool, table, problem, where, fixSQL string) {
+	issue := IntegrityIssue{Table: table, Problem: problem}
+	if err := d.DB.Table(table).Where(where).Count(&issue.Count).Error; err != nil {
+		report.warn("Failed to check %s: %v", table, err)
+		return
+	}
+
+	if fix && issue.Count > 0 {
+		result := d.DB.Exec(fixSQL)
+		if result.Error != nil {
+			report.warn("Failed to fix %s: %v", table, result.Error)
+		} else {
+			issue.Fixed = result.RowsAffected
+		}
+	}
+	report.add(issue)
+}
+
+// StartIntegrityCheck runs VerifyDataIntegrity every interval until ctx is
+// done, logging what it finds. A non-positive interval disables the check.
+func (d *Database) StartIntegrityCheck(ctx context.Context, interval time.Duration, fix bool) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Checking data integrity every %v", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := d.VerifyDataIntegrity(fix)
+			if err != nil {
+				log.Printf("Data integrity check failed: %v", err)
+				continue
+			}
+			for _, issue := range report.Issues {
+				log.Printf("Data integrity: %d rows in %s: %s (%d fixed)", issue.Count, issue.Table, issue.Problem, issue.Fixed)
+			}
+		}
+	}
+}
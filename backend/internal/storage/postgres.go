@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// supportedSSLModes are the sslmode values libpq and pgx accept
+var supportedSSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// ParsePostgresDSN validates and parses a PostgreSQL connection string, either
+// a postgres:// URL or key=value pairs. Options such as sslmode and
+// connect_timeout are carried into the returned config, which NewDatabase
+// connects with, so they always take effect.
+func ParsePostgresDSN(dsn string) (*pgx.ConnConfig, error) {
+	options, err := postgresOptions(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode, ok := options["sslmode"]; ok && !isSupportedSSLMode(mode) {
+		return nil, fmt.Errorf("unsupported sslmode %q (expected one of %s)", mode, strings.Join(supportedSSLModes, ", "))
+	}
+	if timeout, ok := options["connect_timeout"]; ok {
+		if seconds, err := strconv.Atoi(timeout); err != nil || seconds < 0 {
+			return nil, fmt.Errorf("invalid connect_timeout %q (expected a non-negative number of seconds)", timeout)
+		}
+	}
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PostgreSQL DATABASE_URL: %w", err)
+	}
+
+	return connConfig, nil
+}
+
+// postgresOptions extracts the connection options from a URL's query string
+// or from key=value pairs
+func postgresOptions(dsn string) (map[string]string, error) {
+	options := make(map[string]string)
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		parsed, err := url.Parse(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PostgreSQL DATABASE_URL: %w", err)
+		}
+		for key, values := range parsed.Query() {
+			if len(values) > 0 {
+				options[key] = values[len(values)-1]
+			}
+		}
+		return options, nil
+	}
+
+	for _, field := range strings.Fields(dsn) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		options[key] = strings.Trim(value, "'")
+	}
+
+	return options, nil
+}
+
+// isSupportedSSLMode reports whether mode is a known sslmode value
+func isSupportedSSLMode(mode string) bool {
+	for _, supported := range supportedSSLModes {
+		if mode == supported {
+			return true
+		}
+	}
+	return false
+}
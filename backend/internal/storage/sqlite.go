@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sqliteWALMaxConns lets readers use their own connections under WAL, where
+// they no longer block the collector's writes
+const sqliteWALMaxConns = 4
+
+// sqliteJournalModes are the journal modes SQLite accepts
+var sqliteJournalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+
+// isInMemorySQLite reports whether path is an in-memory SQLite database
+func isInMemorySQLite(path string) bool {
+	return strings.Contains(path, ":memory:") || strings.Contains(path, "mode=memory")
+}
+
+// resolveSQLitePath places a relative SQLite file path inside dataDir.
+// In-memory databases, file: URIs and absolute paths are returned unchanged.
+func resolveSQLitePath(path, dataDir string) string {
+	if dataDir == "" || isInMemorySQLite(path) || strings.HasPrefix(path, "file:") || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dataDir, path)
+}
+
+// sqliteDSN adds the journal mode and busy timeout to a file-based SQLite
+// path as driver options, so every pooled connection uses them. In-memory
+// databases are returned unchanged.
+func sqliteDSN(path, journalMode string, busyTimeout time.Duration) (string, error) {
+	if isInMemorySQLite(path) {
+		return path, nil
+	}
+
+	mode := strings.ToUpper(journalMode)
+	valid := false
+	for _, supported := range sqliteJournalModes {
+		if mode == supported {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", fmt.Errorf("unsupported SQLite journal mode %q (expected one of %s)", journalMode, strings.Join(sqliteJournalModes, ", "))
+	}
+
+	options := fmt.Sprintf("_journal_mode=%s&_busy_timeout=%d", mode, busyTimeout.Milliseconds())
+	if mode == "WAL" {
+		// Take the write lock when a transaction begins, so concurrent
+		// writers wait out the busy timeout instead of failing to upgrade
+		options += "&_txlock=immediate"
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + options, nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/clock"
 	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -14,11 +15,19 @@ func InitConfig(c *config.Config) {
 	cfg = c
 }
 
+// clk tells the time tokens are issued and checked against
+var clk clock.Clock = clock.Real{}
+
+// SetClock sets the clock used for token expiry, so tests can control time
+func SetClock(c clock.Clock) {
+	clk = c
+}
+
 func GenerateToken(userId uint, username string) (string, string, error) {
 	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"userId":   userId,
 		"username": username,
-		"exp":      time.Now().Add(35 * time.Minute).Unix(),
+		"exp":      clk.Now().Add(35 * time.Minute).Unix(),
 	}).SignedString([]byte(cfg.Auth.JWTSecret))
 
 	if err != nil {
@@ -28,7 +37,7 @@ func GenerateToken(userId uint, username string) (string, string, error) {
 	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"userId":   userId,
 		"username": username,
-		"exp":      time.Now().Add(7 * 24 * time.Hour).Unix(),
+		"exp":      clk.Now().Add(7 * 24 * time.Hour).Unix(),
 	}).SignedString([]byte(cfg.Auth.JWTSecret))
 
 	if err != nil {
@@ -44,7 +53,7 @@ func ValidateToken(tokenString string) (jwt.MapClaims, error) {
 			return nil, errors.New("unexpected signing method")
 		}
 		return []byte(cfg.Auth.JWTSecret), nil
-	})
+	}, jwt.WithTimeFunc(clk.Now))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,468 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/alerts"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/clock"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/notify"
+)
+
+// setupAlertsDB migrates the alert tables on top of setupTestDB and adds a
+// below-direction memory threshold at 20%
+func setupAlertsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&alerts.Alert{}, &alerts.Incident{}, &alerts.MaintenanceWindow{}))
+	require.NoError(t, db.Create(&metrics.MetricThreshold{
+		Type:      metrics.MemoryUsage,
+		Threshold: 20,
+		Direction: metrics.DirectionBelow,
+		Enabled:   true,
+	}).Error)
+
+	return db
+}
+
+func TestCheckThresholdsAlertsBelowThreshold(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+
+	var active []alerts.Alert
+	require.NoError(t, db.Where("status = ?", alerts.AlertActive).Find(&active).Error)
+	require.Len(t, active, 1)
+	assert.Equal(t, metrics.MemoryUsage, active[0].Type)
+	assert.Equal(t, metrics.DirectionBelow, active[0].Direction)
+	// 17 is 15% short of 20
+	assert.Equal(t, alerts.SeverityMedium, active[0].Severity)
+	assert.Contains(t, active[0].Message, "Low memory usage")
+
+	// Rising back above the threshold resolves the alert
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 60, Timestamp: time.Now()}))
+
+	var count int64
+	require.NoError(t, db.Model(&alerts.Alert{}).Where("status = ?", alerts.AlertActive).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestCheckThresholdsIgnoresHighValuesForBelowThreshold(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 95, Timestamp: time.Now()}))
+
+	var count int64
+	require.NoError(t, db.Model(&alerts.Alert{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestCurrentBreachesReportsBelowThreshold(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	breaches, err := service.CurrentBreaches(&metrics.SystemMetrics{MemoryUsage: 9})
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	assert.Equal(t, metrics.DirectionBelow, breaches[0].Direction)
+	// 9 is 55% short of 20
+	assert.Equal(t, alerts.SeverityCritical, breaches[0].Severity)
+}
+
+func TestUpdateThresholdDirectionResolvesActiveAlerts(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 10, Timestamp: time.Now()}))
+
+	above := string(metrics.DirectionAbove)
+	update, err := service.UpdateThreshold(metrics.MemoryUsage, &alerts.UpdateThresholdRequest{Direction: &above})
+	require.NoError(t, err)
+	assert.Equal(t, metrics.DirectionAbove, update.Threshold.Direction)
+	assert.Equal(t, int64(1), update.ResolvedAlerts)
+
+	bogus := "sideways"
+	_, err = service.UpdateThreshold(metrics.MemoryUsage, &alerts.UpdateThresholdRequest{Direction: &bogus})
+	assert.Error(t, err)
+}
+
+func TestCheckThresholdsRaisesFlappingAlert(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+	service.SetFlapDetection(time.Hour, 3)
+
+	// Two trigger/resolve cycles are four state changes
+	for _, usage := range []float64{17, 60, 17, 60} {
+		require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: usage, Timestamp: time.Now()}))
+	}
+
+	var flapping []alerts.Alert
+	require.NoError(t, db.Where("metric_type = ?", alerts.FlappingAlertType).Find(&flapping).Error)
+	require.Len(t, flapping, 1)
+	assert.Equal(t, metrics.MemoryUsage, flapping[0].FlapType)
+	assert.Equal(t, alerts.AlertActive, flapping[0].Status)
+	assert.Equal(t, float64(4), flapping[0].Value)
+	assert.Contains(t, flapping[0].Message, "4 state changes in 1h0m0s (4.0 per hour)")
+
+	// Further flaps don't raise a second flapping alert
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+	var count int64
+	require.NoError(t, db.Model(&alerts.Alert{}).Where("metric_type = ?", alerts.FlappingAlertType).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestPurgeResolvedAlertsKeepsActiveAndRecent(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	now := time.Now()
+	old := now.Add(-100 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	for _, alert := range []alerts.Alert{
+		{Type: metrics.CPUUsage, Message: "old resolved", Severity: alerts.SeverityHigh, Status: alerts.AlertResolved, TriggeredAt: old, ResolvedAt: &old},
+		{Type: metrics.CPUUsage, Message: "recent resolved", Severity: alerts.SeverityHigh, Status: alerts.AlertResolved, TriggeredAt: old, ResolvedAt: &recent},
+		{Type: metrics.CPUUsage, Message: "old active", Severity: alerts.SeverityHigh, Status: alerts.AlertActive, TriggeredAt: old},
+		{Type: metrics.CPUUsage, Message: "old suppressed", Severity: alerts.SeverityHigh, Status: alerts.AlertSuppressed, TriggeredAt: old},
+	} {
+		require.NoError(t, db.Create(&alert).Error)
+	}
+
+	deleted, err := service.PurgeResolvedAlerts(90 * 24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	var remaining []string
+	require.NoError(t, db.Model(&alerts.Alert{}).Order("id").Pluck("message", &remaining).Error)
+	assert.Equal(t, []string{"recent resolved", "old active", "old suppressed"}, remaining)
+}
+
+func TestAlertMessagesFormatValuesInMetricUnit(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+
+	var alert alerts.Alert
+	require.NoError(t, db.Where("status = ?", alerts.AlertActive).First(&alert).Error)
+	assert.Equal(t, "Low memory usage detected: 17.00% (threshold: 20.00%)", alert.Message)
+
+	// Templates get the same formatting
+	templates, err := alerts.ParseMessageTemplates("", map[string]string{
+		string(metrics.MemoryUsage): "memory at {{.FormattedValue}}, limit {{.FormattedThreshold}}",
+	})
+	require.NoError(t, err)
+	service.SetMessageTemplates(templates)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 60, Timestamp: time.Now()}))
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 12.5, Timestamp: time.Now()}))
+
+	var templated alerts.Alert
+	require.NoError(t, db.Where("status = ?", alerts.AlertActive).First(&templated).Error)
+	assert.Equal(t, "memory at 12.50%, limit 20.00%", templated.Message)
+}
+
+func TestCheckThresholdsIgnoresFloatingPointNoise(t *testing.T) {
+	db := setupAlertsDB(t)
+	require.NoError(t, db.Create(&metrics.MetricThreshold{Type: metrics.CPUUsage, Threshold: 80, Enabled: true}).Error)
+	service := alerts.NewService(db)
+
+	activeCount := func() int64 {
+		var count int64
+		require.NoError(t, db.Model(&alerts.Alert{}).Where("status = ?", alerts.AlertActive).Count(&count).Error)
+		return count
+	}
+
+	// Within the default epsilon of either threshold
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{CPUUsage: 80.0000001, MemoryUsage: 19.9999999, Timestamp: time.Now()}))
+	assert.Zero(t, activeCount())
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{CPUUsage: 80.02, MemoryUsage: 50, Timestamp: time.Now()}))
+	assert.Equal(t, int64(1), activeCount())
+
+	// Exact comparison breaches on the noise
+	service.SetComparisonEpsilon(0)
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{CPUUsage: 80.0000001, MemoryUsage: 19.9999999, Timestamp: time.Now()}))
+	assert.Equal(t, int64(2), activeCount())
+}
+
+func TestGetActiveAlertCountsBySeverityCountsOnlyActive(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	now := time.Now()
+	for _, alert := range []alerts.Alert{
+		{Type: metrics.CPUUsage, Message: "active high", Severity: alerts.SeverityHigh, Status: alerts.AlertActive, TriggeredAt: now},
+		{Type: metrics.DiskUsage, Message: "active high", Severity: alerts.SeverityHigh, Status: alerts.AlertActive, TriggeredAt: now},
+		{Type: metrics.MemoryUsage, Message: "active medium", Severity: alerts.SeverityMedium, Status: alerts.AlertActive, TriggeredAt: now},
+		{Type: metrics.CPUUsage, Message: "resolved critical", Severity: alerts.SeverityCritical, Status: alerts.AlertResolved, TriggeredAt: now, ResolvedAt: &now},
+		{Type: metrics.CPUUsage, Message: "suppressed low", Severity: alerts.SeverityLow, Status: alerts.AlertSuppressed, TriggeredAt: now},
+	} {
+		require.NoError(t, db.Create(&alert).Error)
+	}
+
+	counts, err := service.GetActiveAlertCountsBySeverity()
+	require.NoError(t, err)
+	assert.Equal(t, map[alerts.AlertSeverity]int64{
+		alerts.SeverityLow:      0,
+		alerts.SeverityMedium:   1,
+		alerts.SeverityHigh:     2,
+		alerts.SeverityCritical: 0,
+	}, counts)
+}
+
+func TestCheckThresholdsSkippedWhileCollectionPaused(t *testing.T) {
+	db := setupAlertsDB(t)
+	collector := metrics.NewCollector(db, time.Minute)
+	service := alerts.NewService(db)
+	service.SetCollectionPaused(collector.Paused)
+
+	require.True(t, collector.Pause())
+	assert.False(t, collector.Pause(), "pausing twice changes nothing")
+	stats := collector.Stats()
+	assert.True(t, stats.Paused)
+	assert.NotNil(t, stats.PausedAt)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+	var count int64
+	require.NoError(t, db.Model(&alerts.Alert{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	require.True(t, collector.Resume())
+	assert.False(t, collector.Stats().Paused)
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+	require.NoError(t, db.Model(&alerts.Alert{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+// recordingNotifier collects dispatched events
+type recordingNotifier struct {
+	events []notify.Event
+}
+
+func (r *recordingNotifier) Dispatch(event notify.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestResolvedAlertsAreNotifiedWithDuration(t *testing.T) {
+	db := setupAlertsDB(t)
+	notifier := &recordingNotifier{}
+	service := alerts.NewService(db)
+	service.SetNotifier(notifier)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+	require.Len(t, notifier.events, 1)
+	assert.Equal(t, notify.EventTriggered, notifier.events[0].Kind)
+	assert.Nil(t, notifier.events[0].ResolvedAt)
+
+	// Backdate the alert so it has been active for 10 minutes
+	require.NoError(t, db.Model(&alerts.Alert{}).Where("id = ?", notifier.events[0].AlertID).
+		Update("triggered_at", time.Now().Add(-10*time.Minute)).Error)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 60, Timestamp: time.Now()}))
+	require.Len(t, notifier.events, 2)
+	resolved := notifier.events[1]
+	assert.Equal(t, notify.EventResolved, resolved.Kind)
+	assert.Equal(t, notifier.events[0].AlertID, resolved.AlertID)
+	require.NotNil(t, resolved.ResolvedAt)
+	assert.InDelta(t, 600, resolved.DurationSeconds, 2)
+	assert.Equal(t, "[medium] memory_usage alert resolved", resolved.Subject())
+
+	// Manual resolution notifies too, once
+	alert, err := service.CreateAlert(&alerts.CreateAlertRequest{Type: metrics.CPUUsage, Value: 95, Threshold: 80})
+	require.NoError(t, err)
+	require.NoError(t, service.ResolveAlert(alert.ID))
+	assert.Error(t, service.ResolveAlert(alert.ID))
+	last := notifier.events[len(notifier.events)-1]
+	assert.Equal(t, notify.EventResolved, last.Kind)
+	assert.Equal(t, alert.ID, last.AlertID)
+	assert.Len(t, notifier.events, 4)
+}
+
+func TestResolveMarginKeepsAlertActiveInDeadband(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	margin := 5.0
+	update, err := service.UpdateThreshold(metrics.MemoryUsage, &alerts.UpdateThresholdRequest{ResolveMargin: &margin})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, update.Threshold.ResolveMargin)
+
+	negative := -1.0
+	_, err = service.UpdateThreshold(metrics.MemoryUsage, &alerts.UpdateThresholdRequest{ResolveMargin: &negative})
+	assert.Error(t, err)
+
+	countActive := func() int64 {
+		var count int64
+		require.NoError(t, db.Model(&alerts.Alert{}).Where("status = ?", alerts.AlertActive).Count(&count).Error)
+		return count
+	}
+
+	// The below-direction threshold is 20, so the alert resolves only at 25
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+	assert.Equal(t, int64(1), countActive())
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 22, Timestamp: time.Now()}))
+	assert.Equal(t, int64(1), countActive(), "within the deadband the alert stays active")
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 26, Timestamp: time.Now()}))
+	assert.Zero(t, countActive())
+}
+
+func TestRealertCooldownHoldsBackNewAlert(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+	service.SetRealertCooldown(time.Hour)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 60, Timestamp: time.Now()}))
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+
+	var count int64
+	require.NoError(t, db.Model(&alerts.Alert{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count, "no new alert within the cooldown")
+
+	// Once the cooldown has passed, the breach alerts again
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now().Add(2 * time.Hour)}))
+	require.NoError(t, db.Model(&alerts.Alert{}).Where("status = ?", alerts.AlertActive).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestCheckThresholdsAlertsOnLowAvailableMemory(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&alerts.Alert{}, &alerts.Incident{}, &alerts.MaintenanceWindow{}))
+	require.NoError(t, metrics.NewCollector(db, time.Minute).InitializeThresholds())
+	service := alerts.NewService(db)
+
+	var threshold metrics.MetricThreshold
+	require.NoError(t, db.Where("metric_type = ?", metrics.MemoryAvailable).First(&threshold).Error)
+	assert.Equal(t, metrics.DirectionBelow, threshold.Direction)
+	assert.Equal(t, 10.0, threshold.Threshold)
+
+	available := 4.0
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 70, MemoryAvailable: &available, Timestamp: time.Now()}))
+
+	var active []alerts.Alert
+	require.NoError(t, db.Where("status = ?", alerts.AlertActive).Find(&active).Error)
+	require.Len(t, active, 1)
+	assert.Equal(t, metrics.MemoryAvailable, active[0].Type)
+	assert.Equal(t, alerts.SeverityCritical, active[0].Severity)
+	assert.Contains(t, active[0].Message, "Low available memory")
+
+	// A platform that does not report available memory leaves the alert alone
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 70, Timestamp: time.Now()}))
+	var count int64
+	require.NoError(t, db.Model(&alerts.Alert{}).Where("status = ?", alerts.AlertActive).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	available = 35
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 70, MemoryAvailable: &available, Timestamp: time.Now()}))
+	require.NoError(t, db.Model(&alerts.Alert{}).Where("status = ?", alerts.AlertActive).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestAlertTimingFollowsInjectedClock(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+	clk := clock.NewMock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	service.SetClock(clk)
+	service.SetRealertCooldown(time.Hour)
+
+	check := func(memoryUsage float64) {
+		t.Helper()
+		require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: memoryUsage, Timestamp: clk.Now()}))
+	}
+
+	check(17)
+	clk.Advance(10 * time.Minute)
+	check(60)
+
+	list, err := service.GetAlerts(alerts.AlertResolved, 10, alerts.DefaultAlertSort)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.NotNil(t, list[0].ResolvedAt)
+	assert.True(t, list[0].ResolvedAt.Equal(clk.Now()))
+	assert.Equal(t, int64(600), list[0].DurationSeconds)
+
+	// Within the cooldown the breach is held back; after it, it alerts
+	clk.Advance(30 * time.Minute)
+	check(17)
+	var count int64
+	require.NoError(t, db.Model(&alerts.Alert{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	clk.Advance(31 * time.Minute)
+	check(17)
+	require.NoError(t, db.Model(&alerts.Alert{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	// The resolved alert is purged once it is older than the retention
+	deleted, err := service.PurgeResolvedAlerts(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	clk.Advance(24 * time.Hour)
+	deleted, err = service.PurgeResolvedAlerts(24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestCheckThresholdsSkipsMissingMetrics(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{MemoryUsage: 17, Timestamp: time.Now()}))
+
+	// A failed memory source leaves the value at zero, which neither
+	// resolves the alert nor counts as a reading
+	require.NoError(t, service.CheckThresholds(&metrics.SystemMetrics{
+		Missing:   []metrics.MetricType{metrics.MemoryUsage},
+		Timestamp: time.Now(),
+	}))
+	var active []alerts.Alert
+	require.NoError(t, db.Where("status = ?", alerts.AlertActive).Find(&active).Error)
+	require.Len(t, active, 1)
+	assert.Equal(t, 17.0, active[0].Value)
+
+	breaches, err := service.CurrentBreaches(&metrics.SystemMetrics{Missing: []metrics.MetricType{metrics.MemoryUsage}, Timestamp: time.Now()})
+	require.NoError(t, err)
+	assert.Empty(t, breaches)
+}
+
+func TestCheckLogErrorRateKeepsOneAlertPerSource(t *testing.T) {
+	db := setupAlertsDB(t)
+	service := alerts.NewService(db)
+
+	first, err := service.CheckLogErrorRate("/var/log/app.log", 0.3, 0.1)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Equal(t, "/var/log/app.log", first.Source)
+
+	// Repeated analyses over the ratio return the active alert
+	again, err := service.CheckLogErrorRate("/var/log/app.log", 0.4, 0.1)
+	require.NoError(t, err)
+	require.NotNil(t, again)
+	assert.Equal(t, first.ID, again.ID)
+
+	other, err := service.CheckLogErrorRate("/var/log/worker.log", 0.2, 0.1)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	assert.NotEqual(t, first.ID, other.ID)
+
+	// Back under the ratio resolves only that file's alert
+	resolved, err := service.CheckLogErrorRate("/var/log/app.log", 0.05, 0.1)
+	require.NoError(t, err)
+	assert.Nil(t, resolved)
+
+	var active []alerts.Alert
+	require.NoError(t, db.Where("metric_type = ? AND status = ?", metrics.LogErrorRate, alerts.AlertActive).Find(&active).Error)
+	require.Len(t, active, 1)
+	assert.Equal(t, "/var/log/worker.log", active[0].Source)
+
+	var total int64
+	require.NoError(t, db.Model(&alerts.Alert{}).Where("metric_type = ?", metrics.LogErrorRate).Count(&total).Error)
+	assert.Equal(t, int64(2), total)
+}
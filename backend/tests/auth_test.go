@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/api"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/auth"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/clock"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/utils"
+)
+
+func TestRegisterRejectsMixedCaseDuplicates(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&auth.User{}))
+	service := auth.NewService(db)
+
+	user, err := service.Register(&auth.RegisterRequest{
+		Username: "Alice",
+		Email:    "Alice@Example.com",
+		Password: "secret123",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", user.Username, "display username is preserved")
+	assert.Equal(t, "alice@example.com", user.Email, "email is stored normalized")
+
+	_, err = service.Register(&auth.RegisterRequest{
+		Username: "alice",
+		Email:    "someone@example.com",
+		Password: "secret123",
+	})
+	assert.Error(t, err, "username differing only in case is a duplicate")
+
+	_, err = service.Register(&auth.RegisterRequest{
+		Username: "bob",
+		Email:    "ALICE@example.COM",
+		Password: "secret123",
+	})
+	assert.Error(t, err, "email differing only in case is a duplicate")
+}
+
+func TestImportUsersValidatesBatchAndSkipsExisting(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&auth.User{}))
+	service := auth.NewService(db)
+	require.NoError(t, service.SetBcryptCost(bcrypt.MinCost))
+
+	_, err := service.Register(&auth.RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "secret123"})
+	require.NoError(t, err)
+
+	// Duplicates within the batch and bad entries reject the whole batch
+	results, err := service.ImportUsers([]auth.ImportUserRequest{
+		{Username: "bob", Email: "bob@example.com", Password: "secret123"},
+		{Username: "BOB", Email: "other@example.com", Password: "secret123"},
+		{Username: "carol", Email: "not-an-email", Password: "secret123"},
+		{Username: "dave", Email: "dave@example.com", Password: "secret123", Role: "owner"},
+	})
+	require.ErrorIs(t, err, auth.ErrImportInvalid)
+	require.Len(t, results, 4)
+	assert.Empty(t, results[0].Status)
+	assert.Equal(t, auth.ImportInvalid, results[1].Status)
+	assert.Contains(t, results[1].Error, "repeats entry 0")
+	assert.Equal(t, auth.ImportInvalid, results[2].Status)
+	assert.Equal(t, auth.ImportInvalid, results[3].Status)
+	var count int64
+	require.NoError(t, db.Model(&auth.User{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count, "nothing is created")
+
+	results, err = service.ImportUsers([]auth.ImportUserRequest{
+		{Username: "bob", Email: "Bob@Example.com", Password: "secret123", Role: auth.RoleAdmin},
+		{Username: "Alice", Email: "alice2@example.com", Password: "secret123"},
+		{Username: "carol", Email: "carol@example.com", Password: "secret123"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, auth.ImportCreated, results[0].Status)
+	assert.Equal(t, auth.RoleAdmin, results[0].User.Role)
+	assert.Equal(t, "bob@example.com", results[0].User.Email)
+	assert.Equal(t, auth.ImportSkipped, results[1].Status)
+	assert.Equal(t, auth.ImportCreated, results[2].Status)
+	assert.Equal(t, auth.RoleUser, results[2].User.Role)
+
+	// Imported passwords are stored hashed
+	var carol auth.User
+	require.NoError(t, db.Where("username = ?", "carol").First(&carol).Error)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(carol.Password), []byte("secret123")))
+}
+
+func TestAuthMiddlewareInjectsUserID(t *testing.T) {
+	utils.InitConfig(&config.Config{Auth: config.AuthConfig{JWTSecret: "test-secret"}})
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&auth.User{}))
+	service := auth.NewService(db)
+
+	user, err := service.Register(&auth.RegisterRequest{
+		Username: "carol",
+		Email:    "carol@example.com",
+		Password: "secret123",
+	})
+	require.NoError(t, err)
+
+	login, err := service.Login(&auth.LoginRequest{Username: "carol", Password: "secret123"})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/whoami", api.AuthMiddleware(service), func(c *gin.Context) {
+		userID, ok := api.UserIDFromContext(c)
+		require.True(t, ok)
+		username, _ := api.UsernameFromContext(c)
+		role, _ := api.RoleFromContext(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID, "username": username, "role": role})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		UserID   uint   `json:"user_id"`
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, user.ID, response.UserID)
+	assert.Equal(t, "carol", response.Username)
+	assert.Equal(t, string(auth.RoleUser), response.Role)
+}
+
+func TestTokensExpireByInjectedClock(t *testing.T) {
+	utils.InitConfig(&config.Config{Auth: config.AuthConfig{JWTSecret: "test-secret"}})
+	clk := clock.NewMock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	utils.SetClock(clk)
+	t.Cleanup(func() { utils.SetClock(clock.Real{}) })
+
+	access, refresh, err := utils.GenerateToken(7, "dave")
+	require.NoError(t, err)
+
+	clk.Advance(30 * time.Minute)
+	userID, err := utils.GetUserIDFromToken(access)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), userID)
+
+	// The access token lasts 35 minutes; the refresh token a week
+	clk.Advance(10 * time.Minute)
+	_, err = utils.ValidateToken(access)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	renewed, err := utils.RefreshToken(refresh)
+	require.NoError(t, err)
+	_, err = utils.ValidateToken(renewed)
+	assert.NoError(t, err)
+
+	clk.Advance(7 * 24 * time.Hour)
+	_, err = utils.RefreshToken(refresh)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
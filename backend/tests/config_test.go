@@ -0,0 +1,21 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/config"
+)
+
+func TestComparisonEpsilonZeroComparesExactly(t *testing.T) {
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, 0.01, cfg.Alerts.ComparisonEpsilon)
+
+	t.Setenv("ALERT_COMPARISON_EPSILON", "0")
+	cfg, err = config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, cfg.Alerts.ComparisonEpsilon)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/logging"
+)
+
+func TestParseLogFormatDefaultsByMode(t *testing.T) {
+	format, err := logging.ParseFormat("", true)
+	require.NoError(t, err)
+	assert.Equal(t, logging.FormatText, format)
+
+	format, err = logging.ParseFormat("", false)
+	require.NoError(t, err)
+	assert.Equal(t, logging.FormatJSON, format)
+
+	format, err = logging.ParseFormat("TEXT", false)
+	require.NoError(t, err)
+	assert.Equal(t, logging.FormatText, format)
+
+	_, err = logging.ParseFormat("xml", false)
+	assert.Error(t, err)
+}
+
+func TestJSONLogFormatKeyFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(logging.NewHandler(&buf, logging.FormatJSON, logging.ColorAlways))
+	logger.With("host", "web-1").Warn("Collector slow", "collector", "disk", "took_ms", 1250)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.NotEmpty(t, record["time"])
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "Collector slow", record["msg"])
+	assert.Equal(t, "web-1", record["host"])
+	assert.Equal(t, "disk", record["collector"])
+	assert.Equal(t, float64(1250), record["took_ms"])
+	assert.NotContains(t, buf.String(), "\x1b[", "JSON output is never colored")
+}
+
+func TestTextLogFormatKeyFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(logging.NewHandler(&buf, logging.FormatText, logging.ColorNever))
+	logger.With("host", "web-1").WithGroup("request").Info("HTTP request", "path", "/api/v1/alerts?status=active", "agent", "curl 8.0", "status", 200)
+
+	line := buf.String()
+	require.True(t, strings.HasSuffix(line, "\n"))
+	fields := strings.Fields(line)
+	require.GreaterOrEqual(t, len(fields), 3)
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}$`, fields[0])
+	assert.Equal(t, "INFO", fields[2])
+	assert.Contains(t, line, " INFO  HTTP request ")
+	assert.Contains(t, line, " host=web-1")
+	assert.Contains(t, line, ` request.path="/api/v1/alerts?status=active"`)
+	assert.Contains(t, line, ` request.agent="curl 8.0"`)
+	assert.Contains(t, line, " request.status=200")
+	assert.NotContains(t, line, "\x1b[")
+
+	// Writing to a buffer is not a terminal, so auto leaves color off
+	buf.Reset()
+	slog.New(logging.NewHandler(&buf, logging.FormatText, logging.ColorAuto)).Error("boom")
+	assert.NotContains(t, buf.String(), "\x1b[")
+
+	buf.Reset()
+	slog.New(logging.NewHandler(&buf, logging.FormatText, logging.ColorAlways)).Error("boom")
+	assert.Contains(t, buf.String(), "\x1b[31mERROR\x1b[0m boom")
+}
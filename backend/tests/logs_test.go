@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/logs"
+)
+
+// writeTestLog writes lines to a temporary log file and returns its path
+func writeTestLog(t *testing.T, lines []string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.log")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+	return path
+}
+
+func TestParseLogFileParallelMatchesSequential(t *testing.T) {
+	levels := []string{"INFO", "WARN", "ERROR", "DEBUG"}
+	lines := make([]string, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		level := levels[i%len(levels)]
+		lines = append(lines, fmt.Sprintf("2024-01-15 10:30:%02d [%s] event %d happened", i%60, level, i%7))
+		if i%97 == 0 {
+			lines = append(lines, "", "unstructured noise line")
+		}
+	}
+	path := writeTestLog(t, lines)
+
+	analyzer := logs.NewLogAnalyzer()
+	sequential, err := analyzer.ParseLogFile(path)
+	require.NoError(t, err)
+
+	for _, workers := range []int{0, 1, 3, 8, 10000} {
+		parallel, err := analyzer.ParseLogFileParallel(path, workers)
+		require.NoError(t, err)
+
+		assert.Equal(t, sequential.TotalEntries, parallel.TotalEntries, "workers=%d", workers)
+		assert.Equal(t, sequential.LevelCounts, parallel.LevelCounts, "workers=%d", workers)
+		assert.Equal(t, sequential.TopErrors, parallel.TopErrors, "workers=%d", workers)
+		assert.Equal(t, sequential.UnmatchedLines, parallel.UnmatchedLines, "workers=%d", workers)
+	}
+}
+
+func TestParseLogFileJoinsStackTraces(t *testing.T) {
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines,
+			"2024-01-15 10:30:00 [INFO] request served",
+			"2024-01-15 10:30:01 [ERROR] request failed",
+			"java.lang.IllegalStateException: boom",
+			"\tat com.example.Handler.handle(Handler.java:42)",
+			"\tat com.example.Server.run(Server.java:10)",
+			"Caused by: java.io.IOException: disk full",
+			"\t... 2 more",
+			"2024-01-15 10:30:02 [ERROR] worker crashed",
+			"Traceback (most recent call last):",
+			`  File "worker.py", line 3, in <module>`,
+			"ValueError: bad input",
+		)
+	}
+	path := writeTestLog(t, lines)
+
+	analyzer := logs.NewLogAnalyzer()
+	stats, err := analyzer.ParseLogFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 600, stats.TotalEntries)
+	assert.Equal(t, 400, stats.LevelCounts[logs.ERROR])
+	assert.Zero(t, stats.UnmatchedLines)
+	require.Len(t, stats.TopErrors, 2)
+	assert.Equal(t, 200, stats.TopErrors[0].Count)
+	assert.True(t, strings.HasPrefix(stats.TopErrors[0].Message, "request failed\njava.lang.IllegalStateException: boom\nat com.example.Handler"))
+
+	// Chunks never split a stack trace
+	for _, workers := range []int{3, 8, 64} {
+		parallel, err := analyzer.ParseLogFileParallel(path, workers)
+		require.NoError(t, err)
+		assert.Equal(t, stats.LevelCounts, parallel.LevelCounts, "workers=%d", workers)
+		assert.Equal(t, stats.TopErrors, parallel.TopErrors, "workers=%d", workers)
+		assert.Zero(t, parallel.UnmatchedLines, "workers=%d", workers)
+	}
+
+	// Without multi-line handling every trace line is unmatched
+	disabled, err := logs.ParseContinuationPattern(logs.ContinuationNone)
+	require.NoError(t, err)
+	stats, err = analyzer.WithContinuationPattern(disabled).ParseLogFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 200*8, stats.UnmatchedLines)
+}
+
+func TestParseLogFileFiltersLevels(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines,
+			"2024-01-15 10:30:00 [INFO] request served",
+			"2024-01-15 10:30:01 [WARN] disk usage above 80%",
+			"2024-01-15 10:30:02 [ERROR] request failed",
+			"\tat com.example.Handler.handle(Handler.java:42)",
+			"2024-01-15 10:30:03 [WARN] slow query",
+		)
+		if i%2 == 0 {
+			lines = append(lines, "2024-01-15 10:30:04 [WARN] slow query")
+		}
+	}
+	path := writeTestLog(t, lines)
+
+	levels, err := logs.ParseLevels("warn")
+	require.NoError(t, err)
+	analyzer := logs.NewLogAnalyzer().WithLevels(levels)
+	stats, err := analyzer.ParseLogFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 125, stats.TotalEntries)
+	assert.Equal(t, map[logs.LogLevel]int{logs.WARN: 125}, stats.LevelCounts)
+	assert.Zero(t, stats.ErrorRate())
+	assert.Empty(t, stats.TopErrors)
+	// Skipped entries' stack frames are neither counted nor unmatched
+	assert.Zero(t, stats.UnmatchedLines)
+	assert.Equal(t, []logs.ErrorFrequency{
+		{Message: "slow query", Count: 75},
+		{Message: "disk usage above 80%", Count: 50},
+	}, stats.TopMessages[logs.WARN])
+
+	parallel, err := analyzer.ParseLogFileParallel(path, 4)
+	require.NoError(t, err)
+	assert.Equal(t, stats.LevelCounts, parallel.LevelCounts)
+	assert.Equal(t, stats.TopMessages, parallel.TopMessages)
+
+	// The default analyzes every level and lists only top errors
+	all, err := logs.NewLogAnalyzer().ParseLogFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 225, all.TotalEntries)
+	assert.Nil(t, all.TopMessages)
+
+	_, err = logs.ParseLevels("WARN,TRACE")
+	assert.Error(t, err)
+}
@@ -0,0 +1,588 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/clock"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/metrics"
+)
+
+// setupTestDB opens an in-memory SQLite database with the metrics tables migrated
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	// Every connection to :memory: is a separate database, so pin the pool to one
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, db.AutoMigrate(&metrics.Metric{}, &metrics.MetricThreshold{}))
+
+	return db
+}
+
+// TestCollectorConcurrentAccess hammers GetCurrentMetrics while the collection
+// loop is running. Run with `make test-race` to detect unsynchronized access.
+func TestCollectorConcurrentAccess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping collector loop test in short mode")
+	}
+
+	db := setupTestDB(t)
+	collector := metrics.NewCollector(db, 200*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		collector.Start(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				current, err := collector.GetCurrentMetrics()
+				if assert.NoError(t, err) {
+					assert.NotNil(t, current)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	cancel()
+	<-done
+}
+
+func TestMetricHistoryFiltersByLabels(t *testing.T) {
+	db := setupTestDB(t)
+	collector := metrics.NewCollector(db, time.Minute)
+
+	now := time.Now()
+	for i, labels := range []metrics.Labels{
+		{"env": "prod", "service": "api"},
+		{"env": "production", "service": "api"},
+		{"env": "prod", "service": "a_i"},
+		nil,
+	} {
+		require.NoError(t, db.Create(&metrics.Metric{
+			Type:      metrics.CPUUsage,
+			Value:     float64(i),
+			Unit:      metrics.UnitPercent,
+			Labels:    labels,
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+		}).Error)
+	}
+
+	values := func(matches ...string) []float64 {
+		selector, err := metrics.ParseLabelSelector(matches)
+		require.NoError(t, err)
+		history, err := collector.GetMetricHistory(metrics.CPUUsage, 0, selector)
+		require.NoError(t, err)
+
+		found := []float64{}
+		for _, metric := range history {
+			found = append(found, metric.Value)
+		}
+		return found
+	}
+
+	assert.Equal(t, []float64{3, 2, 1, 0}, values())
+	assert.Equal(t, []float64{2, 0}, values("env:prod"))
+	assert.Equal(t, []float64{0}, values("env:prod", "service:api"))
+	// LIKE wildcards in values are matched literally
+	assert.Equal(t, []float64{2}, values("service:a_i"))
+	assert.Empty(t, values("service:a%"))
+
+	history, err := collector.GetMetricHistory(metrics.CPUUsage, 1, nil)
+	require.NoError(t, err)
+	assert.Nil(t, history[0].Labels)
+
+	_, err = metrics.ParseLabelSelector([]string{"env"})
+	assert.Error(t, err)
+}
+
+func TestMetricHistoryCursorPagination(t *testing.T) {
+	db := setupTestDB(t)
+	collector := metrics.NewCollector(db, time.Minute)
+
+	// Two metrics share each timestamp, so pages must break ties by id
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 6; i++ {
+		require.NoError(t, db.Create(&metrics.Metric{
+			Type:      metrics.CPUUsage,
+			Value:     float64(i),
+			Unit:      metrics.UnitPercent,
+			Timestamp: base.Add(time.Duration(i/2) * time.Second),
+		}).Error)
+	}
+
+	var seen []float64
+	page := metrics.HistoryPage{}
+	for {
+		history, next, err := collector.GetMetricHistoryPage(metrics.CPUUsage, 4, nil, page)
+		require.NoError(t, err)
+		for _, metric := range history {
+			seen = append(seen, metric.Value)
+		}
+		if next == nil {
+			break
+		}
+
+		// A newer metric written between pages does not shift the next one
+		require.NoError(t, db.Create(&metrics.Metric{Type: metrics.CPUUsage, Value: 99, Unit: metrics.UnitPercent, Timestamp: time.Now()}).Error)
+
+		cursor, err := metrics.ParseHistoryCursor(next.Encode())
+		require.NoError(t, err)
+		page = metrics.HistoryPage{Cursor: cursor}
+	}
+	assert.Equal(t, []float64{5, 4, 3, 2, 1, 0}, seen)
+
+	_, err := metrics.ParseHistoryCursor("not-a-cursor")
+	assert.ErrorIs(t, err, metrics.ErrInvalidCursor)
+}
+
+func TestFormatValueUsesMetricUnit(t *testing.T) {
+	cases := []struct {
+		metricType metrics.MetricType
+		value      float64
+		want       string
+	}{
+		{metrics.CPUUsage, 91.5, "91.50%"},
+		{metrics.DiskUsage, 80, "80.00%"},
+		{metrics.CPUIowait, 0.25, "0.25%"},
+		{metrics.NetworkRxRate, 512, "512 B/s"},
+		{metrics.NetworkRxRate, 1536, "1.54 KB/s"},
+		{metrics.NetworkTxRate, 12_300_000, "12.30 MB/s"},
+		{metrics.NetworkTxRate, 2.5e9, "2.50 GB/s"},
+		// Load averages have no registered unit
+		{metrics.MetricType("load_average"), 1.5, "1.50"},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, metrics.FormatValue(tc.metricType, tc.value), "%s %v", tc.metricType, tc.value)
+	}
+}
+
+func TestApplyTypeMetadataRegistersConfiguredTypes(t *testing.T) {
+	metadata, err := metrics.ParseTypeMetadata(map[string]string{
+		"gpu_temperature": "display_name=GPU Temperature; unit=°C; range=0:120",
+		"cpu_usage":       "display_name=Processor",
+	})
+	require.NoError(t, err)
+	require.NoError(t, metrics.ApplyTypeMetadata(metadata))
+	t.Cleanup(func() {
+		require.NoError(t, metrics.ApplyTypeMetadata(map[metrics.MetricType]metrics.TypeMetadata{
+			metrics.CPUUsage: {DisplayName: "CPU Usage"},
+		}))
+	})
+
+	info, ok := metrics.LookupType("gpu_temperature")
+	require.True(t, ok, "configured types are registered")
+	assert.Equal(t, "GPU Temperature", info.DisplayName)
+	assert.Equal(t, "°C", info.Unit)
+	assert.Error(t, info.Range.Check(130))
+	assert.Equal(t, "71.50 °C", metrics.FormatValue("gpu_temperature", 71.5))
+
+	// Built-in types keep what the config does not override
+	cpu, _ := metrics.LookupType(metrics.CPUUsage)
+	assert.Equal(t, "Processor", cpu.DisplayName)
+	assert.Equal(t, metrics.UnitPercent, cpu.Unit)
+	assert.NoError(t, cpu.Range.Check(100))
+	assert.Error(t, cpu.Range.Check(101))
+
+	// The unit of a registered type cannot change, and nothing is applied
+	err = metrics.ApplyTypeMetadata(map[metrics.MetricType]metrics.TypeMetadata{
+		metrics.MemoryUsage: {DisplayName: "RAM"},
+		metrics.CPUUsage:    {Unit: "ratio"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "Memory Usage", metrics.DisplayName(metrics.MemoryUsage))
+
+	for _, spec := range []string{"unit", "colour=red", "range=10:1", "range=a:"} {
+		_, err := metrics.ParseTypeMetadata(map[string]string{"gpu_temperature": spec})
+		assert.Error(t, err, spec)
+	}
+	_, err = metrics.ParseTypeMetadata(map[string]string{"GPU-Temp": "unit=C"})
+	assert.Error(t, err)
+}
+
+func TestGetMetricGapsFindsMissingStretches(t *testing.T) {
+	db := setupTestDB(t)
+	collector := metrics.NewCollector(db, time.Minute)
+
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Samples every minute, except for a 10 minute outage after 12:03 and
+	// nothing after 12:20
+	for _, minute := range []int{0, 1, 2, 3, 13, 14, 15, 16, 17, 18, 19, 20} {
+		at := from.Add(time.Duration(minute) * time.Minute)
+		require.NoError(t, db.Create(&metrics.Metric{Type: metrics.CPUUsage, Value: 10, Unit: metrics.UnitPercent, Timestamp: at}).Error)
+		// A second mount at the same instant is not a separate sample
+		require.NoError(t, db.Create(&metrics.Metric{Type: metrics.CPUUsage, Value: 20, Unit: metrics.UnitPercent, Timestamp: at}).Error)
+	}
+
+	gaps, err := collector.GetMetricGaps(metrics.CPUUsage, from, from.Add(30*time.Minute), time.Minute)
+	require.NoError(t, err)
+	require.Len(t, gaps, 2)
+	assert.True(t, gaps[0].Start.Equal(from.Add(3*time.Minute)))
+	assert.True(t, gaps[0].End.Equal(from.Add(13*time.Minute)))
+	assert.Equal(t, "10m0s", gaps[0].Duration)
+	assert.True(t, gaps[1].Start.Equal(from.Add(20*time.Minute)))
+	assert.True(t, gaps[1].End.Equal(from.Add(30*time.Minute)))
+
+	// A range without samples is a single gap
+	gaps, err = collector.GetMetricGaps(metrics.MemoryUsage, from, from.Add(30*time.Minute), time.Minute)
+	require.NoError(t, err)
+	require.Len(t, gaps, 1)
+	assert.Equal(t, "30m0s", gaps[0].Duration)
+
+	_, err = collector.GetMetricGaps(metrics.CPUUsage, from, from.Add(30*time.Minute), 0)
+	assert.Error(t, err)
+}
+
+func TestGetLatestMetricReturnsNewestStoredSample(t *testing.T) {
+	db := setupTestDB(t)
+	collector := metrics.NewCollector(db, time.Minute)
+
+	_, err := collector.GetLatestMetric(metrics.CPUUsage)
+	assert.ErrorIs(t, err, metrics.ErrNoMetricData)
+
+	now := time.Now()
+	for i, value := range []float64{30, 50, 40} {
+		at := now.Add(time.Duration(i-2) * time.Minute)
+		require.NoError(t, db.Create(&metrics.Metric{Type: metrics.CPUUsage, Value: value, Unit: metrics.UnitPercent, Timestamp: at}).Error)
+	}
+	require.NoError(t, db.Create(&metrics.Metric{Type: metrics.MemoryUsage, Value: 70, Unit: metrics.UnitPercent, Timestamp: now.Add(time.Minute)}).Error)
+
+	latest, err := collector.GetLatestMetric(metrics.CPUUsage)
+	require.NoError(t, err)
+	assert.Equal(t, metrics.CPUUsage, latest.Type)
+	assert.Equal(t, 40.0, latest.Value)
+}
+
+func TestAnnotationsFilterByRangeAndTag(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&metrics.Annotation{}))
+	collector := metrics.NewCollector(db, time.Minute)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	create := func(offset time.Duration, text string, tags ...string) {
+		at := base.Add(offset)
+		_, err := collector.CreateAnnotation(&metrics.CreateAnnotationRequest{Timestamp: &at, Text: text, Tags: tags}, 1)
+		require.NoError(t, err)
+	}
+	create(0, "deploy api v1", "deploy", "api")
+	create(time.Hour, "deploy web", "deploy", "web")
+	create(2*time.Hour, "db failover", "incident")
+	// "deploy" must match whole tags only
+	create(3*time.Hour, "config push", "deployment")
+
+	annotations, err := collector.GetAnnotations(metrics.AnnotationFilter{From: base, To: base.Add(3 * time.Hour), Tag: "deploy"})
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	assert.Equal(t, "deploy api v1", annotations[0].Text)
+	assert.Equal(t, metrics.AnnotationTags{"deploy", "api"}, annotations[0].Tags)
+	assert.Equal(t, "deploy web", annotations[1].Text)
+
+	annotations, err = collector.GetAnnotations(metrics.AnnotationFilter{From: base.Add(30 * time.Minute), To: base.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	assert.Equal(t, "deploy web", annotations[0].Text)
+	assert.Equal(t, "db failover", annotations[1].Text)
+
+	_, err = collector.CreateAnnotation(&metrics.CreateAnnotationRequest{Text: "bad", Tags: []string{"has space"}}, 1)
+	assert.Error(t, err)
+}
+
+// flakyCPUSource reports CPU usage on its first cycle and panics after that
+type flakyCPUSource struct {
+	calls atomic.Int32
+}
+
+func (s *flakyCPUSource) Name() string {
+	return "cpu"
+}
+
+func (s *flakyCPUSource) Collect(ctx context.Context) ([]metrics.Sample, error) {
+	if s.calls.Add(1) > 1 {
+		panic("sensor gone")
+	}
+	return []metrics.Sample{{Type: metrics.CPUUsage, Value: 95, Unit: metrics.UnitPercent}}, nil
+}
+
+func TestFailingSourceLeavesItsMetricsMissing(t *testing.T) {
+	db := setupTestDB(t)
+	collector := metrics.NewCollector(db, 50*time.Millisecond)
+	require.True(t, collector.UnregisterSource("cpu"))
+	collector.RegisterSource(&flakyCPUSource{})
+
+	cycles := make(chan metrics.SystemMetrics, 16)
+	collector.SetCollectHook(func(current *metrics.SystemMetrics) {
+		select {
+		case cycles <- *current:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		collector.Start(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	next := func() metrics.SystemMetrics {
+		t.Helper()
+		select {
+		case current := <-cycles:
+			return current
+		case <-time.After(2 * time.Second):
+			t.Fatal("no collection cycle completed")
+			return metrics.SystemMetrics{}
+		}
+	}
+
+	first := next()
+	assert.True(t, first.Reported(metrics.CPUUsage))
+	assert.Equal(t, 95.0, first.CPUUsage)
+
+	// The panicking source does not stop the others, and its last reading
+	// is not carried into later cycles
+	second := next()
+	assert.False(t, second.Reported(metrics.CPUUsage))
+	assert.Zero(t, second.CPUUsage)
+	assert.True(t, second.Reported(metrics.MemoryUsage))
+	assert.Positive(t, second.MemoryUsage)
+
+	current, err := collector.GetCurrentMetrics()
+	require.NoError(t, err)
+	assert.Contains(t, current.Missing, metrics.CPUUsage)
+
+	var cpuRows int64
+	require.NoError(t, db.Model(&metrics.Metric{}).Where("metric_type = ?", metrics.CPUUsage).Count(&cpuRows).Error)
+	assert.Equal(t, int64(1), cpuRows)
+}
+
+func TestCompactionKeepsMountsApart(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&metrics.MetricRollup{}))
+	collector := metrics.NewCollector(db, time.Minute)
+	clk := clock.NewMock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	collector.SetClock(clk)
+	collector.SetRetentionPolicy(metrics.RetentionPolicy{RawRetention: time.Hour})
+
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	for i, mount := range []string{"/", "/", "/data", "/data"} {
+		value := map[string]float64{"/": 20, "/data": 80}[mount] + float64(i%2)*10
+		require.NoError(t, db.Create(&metrics.Metric{Type: metrics.DiskUsage, Value: value, Unit: metrics.UnitPercent,
+			Mount: mount, Timestamp: start.Add(time.Duration(i) * time.Minute)}).Error)
+	}
+	require.NoError(t, collector.CompactMetrics())
+
+	var rollups []metrics.MetricRollup
+	require.NoError(t, db.Order("mount ASC").Find(&rollups).Error)
+	require.Len(t, rollups, 2)
+	assert.Equal(t, "/", rollups[0].Mount)
+	assert.Equal(t, 25.0, rollups[0].Average)
+	assert.Equal(t, "/data", rollups[1].Mount)
+	assert.Equal(t, 85.0, rollups[1].Average)
+
+	history, err := collector.GetMetricHistoryAggregated(metrics.DiskUsage, "/data", time.Hour, metrics.AggregationMax, 24, nil)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 90.0, history[0].Value)
+	assert.Equal(t, int64(2), history[0].Count)
+
+	_, err = collector.GetMetricHistoryAggregated(metrics.DiskUsage, "", time.Hour, metrics.AggregationAvg, 24, nil)
+	assert.ErrorIs(t, err, metrics.ErrMountRequired)
+}
+
+func TestFleetAggregateIncludesRollupsPerHost(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&metrics.MetricRollup{}))
+	collector := metrics.NewCollector(db, time.Minute)
+	clk := clock.NewMock(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))
+	collector.SetClock(clk)
+	collector.SetRetentionPolicy(metrics.RetentionPolicy{RawRetention: time.Hour})
+
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	for i, host := range []string{"web-1", "web-1", "web-1", "web-2"} {
+		require.NoError(t, db.Create(&metrics.Metric{Type: metrics.CPUUsage, Value: float64(10 * (i + 1)), Unit: metrics.UnitPercent,
+			Host: host, Timestamp: start.Add(time.Duration(i) * time.Minute)}).Error)
+	}
+	require.NoError(t, collector.CompactMetrics())
+
+	var rollups int64
+	require.NoError(t, db.Model(&metrics.MetricRollup{}).Count(&rollups).Error)
+	assert.Equal(t, int64(2), rollups)
+
+	fleet, err := collector.GetFleetAggregate(metrics.CPUUsage, metrics.AggregationAvg, start, start.Add(3*time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, fleet.Points, 1)
+	assert.Equal(t, 2, fleet.Points[0].Hosts)
+	// web-1 averages 20 and web-2 40, each host counting equally
+	assert.Equal(t, 30.0, fleet.Points[0].Value)
+
+	fleet, err = collector.GetFleetAggregate(metrics.CPUUsage, metrics.AggregationMax, start, start.Add(3*time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, fleet.Points, 1)
+	assert.Equal(t, 40.0, fleet.Points[0].Value)
+	assert.Equal(t, "web-2", fleet.Points[0].Host)
+}
+
+// setupCompaction returns a collector keeping raw metrics for 2 hours and
+// hourly rollups for a day, on a mock clock at noon on 2024-01-03
+func setupCompaction(t *testing.T) (*gorm.DB, *metrics.Collector, *clock.Mock) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&metrics.MetricRollup{}))
+	collector := metrics.NewCollector(db, time.Minute)
+	clk := clock.NewMock(time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC))
+	collector.SetClock(clk)
+	collector.SetRetentionPolicy(metrics.RetentionPolicy{RawRetention: 2 * time.Hour, HourlyRetention: 24 * time.Hour})
+
+	return db, collector, clk
+}
+
+// createCPUMetric stores a raw CPU reading
+func createCPUMetric(t *testing.T, db *gorm.DB, value float64, at time.Time) {
+	t.Helper()
+	require.NoError(t, db.Create(&metrics.Metric{Type: metrics.CPUUsage, Value: value, Unit: metrics.UnitPercent, Timestamp: at}).Error)
+}
+
+func TestCompactMetricsRollsRawIntoHourly(t *testing.T) {
+	db, collector, _ := setupCompaction(t)
+
+	hour := time.Date(2024, 1, 3, 8, 0, 0, 0, time.UTC)
+	createCPUMetric(t, db, 10, hour.Add(10*time.Minute))
+	createCPUMetric(t, db, 30, hour.Add(20*time.Minute))
+	createCPUMetric(t, db, 20, hour.Add(50*time.Minute))
+	// Within the raw retention, so kept as is
+	createCPUMetric(t, db, 90, hour.Add(3*time.Hour))
+
+	require.NoError(t, collector.CompactMetrics())
+
+	var rollups []metrics.MetricRollup
+	require.NoError(t, db.Find(&rollups).Error)
+	require.Len(t, rollups, 1)
+	assert.Equal(t, metrics.RollupHourly, rollups[0].Resolution)
+	assert.True(t, rollups[0].BucketStart.Equal(hour))
+	assert.Equal(t, 20.0, rollups[0].Average)
+	assert.Equal(t, 10.0, rollups[0].Min)
+	assert.Equal(t, 30.0, rollups[0].Max)
+	assert.Equal(t, int64(3), rollups[0].Count)
+
+	var raw int64
+	require.NoError(t, db.Model(&metrics.Metric{}).Count(&raw).Error)
+	assert.Equal(t, int64(1), raw)
+
+	// A late sample for the same hour merges into the existing rollup
+	createCPUMetric(t, db, 60, hour.Add(40*time.Minute))
+	require.NoError(t, collector.CompactMetrics())
+
+	rollups = nil
+	require.NoError(t, db.Find(&rollups).Error)
+	require.Len(t, rollups, 1)
+	assert.Equal(t, 30.0, rollups[0].Average)
+	assert.Equal(t, 10.0, rollups[0].Min)
+	assert.Equal(t, 60.0, rollups[0].Max)
+	assert.Equal(t, int64(4), rollups[0].Count)
+}
+
+func TestCompactMetricsRollsHourlyIntoDaily(t *testing.T) {
+	db, collector, clk := setupCompaction(t)
+
+	day := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	createCPUMetric(t, db, 10, day.Add(8*time.Hour))
+	createCPUMetric(t, db, 30, day.Add(8*time.Hour+30*time.Minute))
+	createCPUMetric(t, db, 50, day.Add(9*time.Hour))
+	require.NoError(t, collector.CompactMetrics())
+
+	var hourly int64
+	require.NoError(t, db.Model(&metrics.MetricRollup{}).Where("resolution = ?", metrics.RollupHourly).Count(&hourly).Error)
+	assert.Equal(t, int64(2), hourly)
+
+	// Two days later the hourly rollups are past the hourly retention
+	clk.Advance(48 * time.Hour)
+	require.NoError(t, collector.CompactMetrics())
+
+	var rollups []metrics.MetricRollup
+	require.NoError(t, db.Find(&rollups).Error)
+	require.Len(t, rollups, 1)
+	assert.Equal(t, metrics.RollupDaily, rollups[0].Resolution)
+	assert.True(t, rollups[0].BucketStart.Equal(day))
+	assert.Equal(t, 30.0, rollups[0].Average)
+	assert.Equal(t, 10.0, rollups[0].Min)
+	assert.Equal(t, 50.0, rollups[0].Max)
+	assert.Equal(t, int64(3), rollups[0].Count)
+}
+
+func TestHistoryCombinesRawAndRollups(t *testing.T) {
+	db, collector, clk := setupCompaction(t)
+
+	day := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	createCPUMetric(t, db, 10, day.Add(8*time.Hour))
+	createCPUMetric(t, db, 20, day.Add(9*time.Hour))
+	createCPUMetric(t, db, 30, day.Add(11*time.Hour))
+	require.NoError(t, collector.CompactMetrics())
+
+	// Rolled up: the two morning samples; still raw: the one at 11:00
+	var raw int64
+	require.NoError(t, db.Model(&metrics.Metric{}).Count(&raw).Error)
+	require.Equal(t, int64(1), raw)
+
+	summary, err := collector.GetMetricSummaryRange(metrics.CPUUsage, day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), summary.Count)
+	assert.Equal(t, 20.0, summary.Average)
+	assert.Equal(t, 10.0, summary.Min)
+	assert.Equal(t, 30.0, summary.Max)
+
+	history, err := collector.GetMetricHistoryAggregated(metrics.CPUUsage, "", time.Hour, metrics.AggregationAvg, 24, nil)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.True(t, history[0].Timestamp.Equal(day.Add(11*time.Hour)))
+	assert.Equal(t, 30.0, history[0].Value)
+	assert.True(t, history[2].Timestamp.Equal(day.Add(8*time.Hour)))
+	assert.Equal(t, 10.0, history[2].Value)
+
+	var total int64
+	for _, point := range history {
+		total += point.Count
+	}
+	assert.Equal(t, int64(3), total)
+
+	// Comparing with the day before sees the rolled-up samples in the
+	// current window only
+	clk.Set(day.Add(24 * time.Hour))
+	comparison, err := collector.CompareWindows(metrics.CPUUsage, 24*time.Hour, metrics.DefaultRegressionThreshold)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), comparison.Current.Count)
+	assert.Equal(t, int64(0), comparison.Previous.Count)
+}
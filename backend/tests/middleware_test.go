@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/api"
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/idempotency"
+)
+
+func setupBodyLimitRouter(maxBytes int64) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	var received int
+	router.POST("/upload", api.BodyLimitMiddleware(maxBytes), func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		received = len(body)
+		c.JSON(http.StatusOK, gin.H{"received": received})
+	})
+
+	return router, &received
+}
+
+func TestBodyLimitAcceptsBodyWithinLimit(t *testing.T) {
+	router, received := setupBodyLimitRouter(1024)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/upload", strings.NewReader(strings.Repeat("a", 1024)))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1024, *received)
+}
+
+func TestBodyLimitRejectsDeclaredLength(t *testing.T) {
+	router, received := setupBodyLimitRouter(1024)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/upload", bytes.NewReader(make([]byte, 4096)))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "exceeds the 1024 byte limit")
+	assert.Zero(t, *received, "handler must not run")
+}
+
+// endlessReader yields bytes forever, standing in for a huge chunked upload
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+func TestBodyLimitRejectsUnboundedBody(t *testing.T) {
+	router, received := setupBodyLimitRouter(1 << 20)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/upload", endlessReader{})
+	req.ContentLength = -1 // unknown length, as with chunked encoding
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Zero(t, *received, "handler must not run")
+}
+
+func TestIdempotencyKeyReleasedWhenHandlerPanics(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&idempotency.Record{}))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	calls := 0
+	router.POST("/alerts", api.IdempotencyMiddleware(idempotency.NewStore(db, time.Hour)), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusCreated, gin.H{"message": "Alert created"})
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/alerts", strings.NewReader(`{"type":"cpu_usage"}`))
+		req.Header.Set(api.HeaderIdempotencyKey, "retry-me")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.PanicsWithValue(t, "boom", func() { send() })
+
+	// The retry runs instead of being refused as in progress
+	w := send()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyAbandonedReservationIsReused(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&idempotency.Record{}))
+	store := idempotency.NewStore(db, time.Hour)
+
+	record, err := store.Begin("1:POST /alerts", "key", "hash")
+	require.NoError(t, err)
+	require.Nil(t, record)
+
+	_, err = store.Begin("1:POST /alerts", "key", "hash")
+	assert.ErrorIs(t, err, idempotency.ErrInProgress)
+
+	// A reservation older than the in-flight timeout was abandoned
+	require.NoError(t, db.Model(&idempotency.Record{}).Where("key = ?", "key").
+		Update("created_at", time.Now().Add(-idempotency.InFlightTimeout-time.Minute)).Error)
+	record, err = store.Begin("1:POST /alerts", "key", "hash")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+}
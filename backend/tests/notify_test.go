@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amarjeet-choudhary666/CodeXray/backend/internal/notify"
+)
+
+// recordingChannel passes each event it is sent to events
+type recordingChannel struct {
+	name   string
+	events chan notify.Event
+}
+
+func (c *recordingChannel) Name() string {
+	return c.name
+}
+
+func (c *recordingChannel) Send(ctx context.Context, event notify.Event) error {
+	c.events <- event
+	return nil
+}
+
+// staticSubscribers subscribes the same recipients to every event
+type staticSubscribers []notify.Recipient
+
+func (s staticSubscribers) Recipients(event notify.Event) ([]notify.Recipient, error) {
+	return s, nil
+}
+
+func TestSubscriptionsDoNotSilenceConfiguredChannels(t *testing.T) {
+	configured := &recordingChannel{name: "webhook", events: make(chan notify.Event, 1)}
+	dispatcher := notify.NewDispatcher(configured)
+	dispatcher.SetFallbackMinSeverity("high")
+	// A subscription to every metric type at any severity
+	dispatcher.SetSubscribers(staticSubscribers{{Channel: notify.ChannelWebhook, Target: "http://subscriber.invalid/hook"}}, nil)
+
+	dispatcher.Dispatch(notify.Event{Kind: notify.EventTriggered, MetricType: "cpu_usage", Severity: "critical", Message: "High CPU usage"})
+
+	select {
+	case event := <-configured.events:
+		require.Equal(t, "critical", event.Severity)
+	case <-time.After(5 * time.Second):
+		t.Fatal("configured channel did not receive the critical event")
+	}
+
+	// Below the fallback severity only subscribers are notified
+	dispatcher.Dispatch(notify.Event{Kind: notify.EventTriggered, MetricType: "cpu_usage", Severity: "low", Message: "Slightly high CPU usage"})
+	select {
+	case event := <-configured.events:
+		t.Fatalf("configured channel received %s event below the fallback severity", event.Severity)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestValidateRecipientRejectsInternalTargets(t *testing.T) {
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"https://192.168.1.10/hook",
+		"http://[::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		assert.Error(t, notify.ValidateRecipient(notify.ChannelWebhook, target), target)
+		assert.Error(t, notify.ValidateRecipient(notify.ChannelSlack, target), target)
+	}
+	assert.NoError(t, notify.ValidateRecipient(notify.ChannelSlack, "https://hooks.slack.com/services/T000/B000/XXXX"))
+	assert.NoError(t, notify.ValidateRecipient(notify.ChannelWebhook, "https://alerts.example.com/hook"))
+}
+
+func TestSubscriptionsAreNotSentToInternalTargets(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	dispatcher := notify.NewDispatcher()
+	dispatcher.SetSubscribers(staticSubscribers{{Channel: notify.ChannelWebhook, Target: server.URL + "/hook"}}, nil)
+	dispatcher.Dispatch(notify.Event{Kind: notify.EventTriggered, MetricType: "cpu_usage", Severity: "critical"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dispatcher.Drain(ctx)
+	assert.Zero(t, hits.Load())
+}